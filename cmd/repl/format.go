@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mini-rdbms/db/engine"
+	"text/tabwriter"
+)
+
+// validFormat reports whether name is a result format printResult knows.
+func validFormat(name string) bool {
+	switch name {
+	case "table", "csv", "json":
+		return true
+	}
+	return false
+}
+
+// printResult renders a result in the given format. Statements without
+// rows print their message instead.
+func printResult(w io.Writer, res *engine.ResultSet, format string) error {
+	if res.Message != "" {
+		_, err := fmt.Fprintln(w, res.Message)
+		return err
+	}
+	if len(res.Columns) == 0 {
+		return nil
+	}
+
+	switch format {
+	case "csv":
+		return res.WriteCSV(w)
+	case "json":
+		return res.WriteJSON(w)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.Debug)
+	// Header
+	for i, col := range res.Columns {
+		fmt.Fprintf(tw, "%s", col)
+		if i < len(res.Columns)-1 {
+			fmt.Fprint(tw, "\t")
+		}
+	}
+	fmt.Fprintln(tw)
+
+	// Rows
+	for _, row := range res.Rows {
+		for i, val := range row.Values {
+			fmt.Fprintf(tw, "%v", val.String())
+			if i < len(row.Values)-1 {
+				fmt.Fprint(tw, "\t")
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
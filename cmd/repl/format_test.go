@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"mini-rdbms/db/engine"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestPrintResultFormats(t *testing.T) {
+	res := &engine.ResultSet{
+		Columns:     []string{"id", "name"},
+		ColumnTypes: []types.DataType{types.TypeInt, types.TypeText},
+		Rows: []storage.Row{
+			{Values: []types.Value{types.NewInt(1), types.NewText("Alice")}},
+			{Values: []types.Value{types.NewInt(2), types.NewNull(types.TypeText)}},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"table", "id  |name\n1   |Alice\n2   |NULL\n"},
+		{"csv", "id,name\n1,Alice\n2,NULL\n"},
+		{"json", `[{"id":1,"name":"Alice"},{"id":2,"name":null}]` + "\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := printResult(&buf, res, tt.format); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestPrintResultMessage(t *testing.T) {
+	for _, format := range []string{"table", "csv", "json"} {
+		var buf bytes.Buffer
+		if err := printResult(&buf, &engine.ResultSet{Message: "1 row inserted"}, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if buf.String() != "1 row inserted\n" {
+			t.Errorf("%s: got %q", format, buf.String())
+		}
+	}
+}
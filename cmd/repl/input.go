@@ -0,0 +1,43 @@
+package main
+
+import "strings"
+
+// statementBuffer accumulates input lines until a statement is terminated
+// by a semicolon. Semicolons inside 'quoted strings' and -- comments do not
+// end a statement.
+type statementBuffer struct {
+	text    strings.Builder
+	inQuote bool // an unterminated ' carries over to the next line
+}
+
+// Empty reports whether no partial statement is pending.
+func (b *statementBuffer) Empty() bool {
+	return strings.TrimSpace(b.text.String()) == ""
+}
+
+// Add appends a line and returns every statement it completes, without the
+// terminating semicolons. Text after the last semicolon stays buffered.
+func (b *statementBuffer) Add(line string) []string {
+	var stmts []string
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == '\'':
+			b.inQuote = !b.inQuote
+		case !b.inQuote && ch == '-' && i+1 < len(line) && line[i+1] == '-':
+			// Rest of the line is a comment
+			b.text.WriteString(line[i:])
+			i = len(line)
+			continue
+		case !b.inQuote && ch == ';':
+			if stmt := strings.TrimSpace(b.text.String()); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			b.text.Reset()
+			continue
+		}
+		b.text.WriteByte(ch)
+	}
+	b.text.WriteByte('\n')
+	return stmts
+}
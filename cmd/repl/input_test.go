@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatementBufferAcrossLines(t *testing.T) {
+	var buf statementBuffer
+	lines := []string{
+		"SELECT *",
+		"FROM users",
+		"WHERE name = 'semi;colon' -- trailing; comment",
+		"AND id = 1;",
+	}
+	var got []string
+	for i, line := range lines {
+		stmts := buf.Add(line)
+		if i < len(lines)-1 {
+			if len(stmts) != 0 {
+				t.Fatalf("Line %d completed a statement early: %q", i+1, stmts)
+			}
+			if buf.Empty() {
+				t.Fatalf("Expected a pending statement after line %d", i+1)
+			}
+		}
+		got = append(got, stmts...)
+	}
+	want := []string{"SELECT *\nFROM users\nWHERE name = 'semi;colon' -- trailing; comment\nAND id = 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !buf.Empty() {
+		t.Errorf("Expected the buffer to be empty after the statement ended")
+	}
+
+	// Several statements on one line, with a quote spanning lines.
+	got = buf.Add("INSERT INTO t VALUES (1, 'a'); INSERT INTO t VALUES (2, 'multi")
+	if !reflect.DeepEqual(got, []string{"INSERT INTO t VALUES (1, 'a')"}) {
+		t.Errorf("Unexpected statements: %q", got)
+	}
+	got = buf.Add("line;');")
+	if !reflect.DeepEqual(got, []string{"INSERT INTO t VALUES (2, 'multi\nline;')"}) {
+		t.Errorf("Unexpected statements: %q", got)
+	}
+}
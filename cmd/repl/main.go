@@ -3,78 +3,104 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"mini-rdbms/db/engine"
+	"mini-rdbms/db/storage"
 	"os"
 	"strings"
-	"text/tabwriter"
+	"time"
 )
 
 func main() {
-	db := engine.NewEngine()
+	dataDir := flag.String("data", storage.DefaultDataDir, "directory for table files")
+	var cfg settings
+	flag.DurationVar(&cfg.timeout, "timeout", 0, "per-query timeout, e.g. 5s (0 disables)")
+	flag.StringVar(&cfg.format, "format", "table", "result format: table, csv or json")
+	flag.Parse()
+	if !validFormat(cfg.format) {
+		fmt.Fprintf(os.Stderr, "unknown format: %s\n", cfg.format)
+		os.Exit(2)
+	}
+
+	db := engine.NewEngineWithConfig(engine.Config{DataDir: *dataDir})
 
 	// Ensure data directory exists if not done
 	// Logic handled in Load/Save usually
 
 	scanner := bufio.NewScanner(os.Stdin)
 	fmt.Println("Minimal RDBMS REPL")
-	fmt.Println("Type 'exit' or 'quit' to close.")
+	fmt.Println("End statements with ';'. Type 'exit' or 'quit' to close.")
+	fmt.Println("Meta-commands: .tables, .schema <table>,")
+	fmt.Println(".format table|csv|json, .timeout <duration>, .export <table> <file>.")
 
+	var buf statementBuffer
 	for {
-		fmt.Print("db> ")
+		if buf.Empty() {
+			fmt.Print("db> ")
+		} else {
+			fmt.Print("...> ")
+		}
 		if !scanner.Scan() {
 			break
 		}
-		input := scanner.Text()
-		input = strings.TrimSpace(input)
+		input := strings.TrimSpace(scanner.Text())
 
-		if input == "" {
-			continue
-		}
-		if strings.EqualFold(input, "exit") || strings.EqualFold(input, "quit") {
-			break
+		// exit, quit and meta-commands are only recognized between statements
+		if buf.Empty() {
+			if input == "" || strings.HasPrefix(input, "--") {
+				continue
+			}
+			if strings.EqualFold(input, "exit") || strings.EqualFold(input, "quit") {
+				break
+			}
+			if out, ok := metaCommand(db, input, &cfg); ok {
+				fmt.Println(out)
+				continue
+			}
 		}
 
-		// Handle input ending with semicolon?
-		input = strings.TrimSuffix(input, ";")
-
-		res, err := db.Execute(context.Background(), input)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			continue
+		for _, stmt := range buf.Add(input) {
+			res, err := execute(db, stmt, cfg.timeout)
+			if err == nil {
+				err = printResult(os.Stdout, res, cfg.format)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
 		}
+	}
 
-		printResult(res)
+	if err := db.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving tables: %v\n", err)
+		os.Exit(1)
 	}
 }
 
-func printResult(res *engine.ResultSet) {
-	if res.Message != "" {
-		fmt.Println(res.Message)
-		return
+// execute runs one statement, cancelling it once timeout elapses (if set).
+func execute(db *engine.Engine, sql string, timeout time.Duration) (*engine.ResultSet, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
+	return db.Execute(ctx, sql)
+}
 
-	if len(res.Columns) > 0 {
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.Debug)
-		// Header
-		for i, col := range res.Columns {
-			fmt.Fprintf(w, "%s", col)
-			if i < len(res.Columns)-1 {
-				fmt.Fprint(w, "\t")
-			}
-		}
-		fmt.Fprintln(w)
-
-		// Rows
-		for _, row := range res.Rows {
-			for i, val := range row.Values {
-				fmt.Fprintf(w, "%v", val.String())
-				if i < len(row.Values)-1 {
-					fmt.Fprint(w, "\t")
-				}
-			}
-			fmt.Fprintln(w)
-		}
-		w.Flush()
+// exportTable writes every row of table to a CSV file at path.
+func exportTable(db *engine.Engine, table, path string, timeout time.Duration) error {
+	res, err := execute(db, "SELECT * FROM "+table, timeout)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := res.WriteCSV(f); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
 }
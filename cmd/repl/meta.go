@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"mini-rdbms/db/engine"
+	"strings"
+	"time"
+)
+
+// settings are the REPL options that meta-commands can change.
+type settings struct {
+	timeout time.Duration // per-query timeout; 0 disables
+	format  string        // result format: table, csv or json
+}
+
+// metaCommand runs a dot-command such as .tables and returns the text to
+// print. handled is false if input is not a meta-command, in which case it
+// should be executed as SQL.
+func metaCommand(db *engine.Engine, input string, cfg *settings) (out string, handled bool) {
+	args := strings.Fields(input)
+	if len(args) == 0 || !strings.HasPrefix(args[0], ".") {
+		return "", false
+	}
+
+	switch args[0] {
+	case ".tables":
+		names, err := db.TableNames()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), true
+		}
+		if len(names) == 0 {
+			return "No tables", true
+		}
+		return strings.Join(names, "\n"), true
+
+	case ".schema":
+		if len(args) != 2 {
+			return "Usage: .schema <table>", true
+		}
+		def, err := db.TableDef(args[1])
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), true
+		}
+		return def.DDL(), true
+
+	case ".format":
+		if len(args) != 2 || !validFormat(args[1]) {
+			return "Usage: .format table|csv|json", true
+		}
+		cfg.format = args[1]
+		return "Output format set to " + args[1], true
+
+	case ".timeout":
+		var d time.Duration
+		var err error
+		if len(args) == 2 {
+			d, err = time.ParseDuration(args[1])
+		}
+		if len(args) != 2 || err != nil || d < 0 {
+			return "Usage: .timeout <duration>, e.g. .timeout 5s (0 disables)", true
+		}
+		cfg.timeout = d
+		return fmt.Sprintf("Query timeout set to %v", d), true
+
+	case ".export":
+		if len(args) != 3 {
+			return "Usage: .export <table> <file>", true
+		}
+		if err := exportTable(db, args[1], args[2], cfg.timeout); err != nil {
+			return fmt.Sprintf("Error: %v", err), true
+		}
+		return fmt.Sprintf("Exported %s to %s", args[1], args[2]), true
+	}
+	return fmt.Sprintf("Unknown command: %s", args[0]), true
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"mini-rdbms/db/engine"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetaCommands(t *testing.T) {
+	dir := t.TempDir()
+	db := engine.NewEngineWithConfig(engine.Config{DataDir: dir})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY AUTO_INCREMENT, email TEXT UNIQUE, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT CHECK (amount > 0), code VARCHAR(8) COLLATE NOCASE, " +
+			"updated TEXT ON UPDATE CURRENT_TIMESTAMP, INDEX (user_id), FOREIGN KEY (user_id) REFERENCES users(id)) INSERTION_ORDER",
+	} {
+		if _, err := db.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// A fresh engine has nothing loaded but still sees the saved tables.
+	db = engine.NewEngineWithConfig(engine.Config{DataDir: dir})
+	cfg := settings{format: "table"}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{".tables", "orders\nusers"},
+		{".schema users", "CREATE TABLE users (id INT PRIMARY KEY AUTO_INCREMENT, email TEXT UNIQUE, name TEXT)"},
+		{".schema orders", "CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT CHECK (amount > 0), code VARCHAR(8) COLLATE NOCASE, " +
+			"updated TEXT ON UPDATE CURRENT_TIMESTAMP, INDEX (user_id), FOREIGN KEY (user_id) REFERENCES users(id)) INSERTION_ORDER"},
+		{".schema", "Usage: .schema <table>"},
+		{".timeout 2s", "Query timeout set to 2s"},
+		{".format json", "Output format set to json"},
+		{".format xml", "Usage: .format table|csv|json"},
+		{".bogus", "Unknown command: .bogus"},
+	}
+	for _, tt := range tests {
+		out, ok := metaCommand(db, tt.input, &cfg)
+		if !ok {
+			t.Errorf("%s: not handled as a meta-command", tt.input)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.input, out, tt.want)
+		}
+	}
+	if cfg.timeout != 2*time.Second {
+		t.Errorf("Expected .timeout to set 2s, got %v", cfg.timeout)
+	}
+	if cfg.format != "json" {
+		t.Errorf("Expected .format to set json, got %q", cfg.format)
+	}
+
+	if out, _ := metaCommand(db, ".schema missing", &cfg); !strings.HasPrefix(out, "Error:") {
+		t.Errorf("Expected an error for an unknown table, got %q", out)
+	}
+	if _, ok := metaCommand(db, "SELECT * FROM users", &cfg); ok {
+		t.Errorf("Expected SQL to fall through to Execute")
+	}
+}
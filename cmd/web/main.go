@@ -3,16 +3,48 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"mini-rdbms/db/engine"
+	"mini-rdbms/db/parser"
 	"mini-rdbms/db/schema"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 var db *engine.Engine
 
+// allowWrite lets POST /query run statements other than SELECT.
+var allowWrite bool
+
+// queryTimeout bounds how long a single request may spend in the engine.
+// Zero disables the deadline.
+var queryTimeout time.Duration
+
+// timeoutMiddleware gives each request a context that expires after
+// queryTimeout, so a runaway scan or join is cancelled.
+func timeoutMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if queryTimeout <= 0 {
+			next(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
 // CORS middleware to allow GitHub Pages to call this API
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -32,14 +64,40 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 }
 
 func main() {
-	db = engine.NewEngine()
+	dataDir := flag.String("data", storage.DefaultDataDir, "directory for table files")
+	flag.BoolVar(&allowWrite, "allow-write", false, "allow non-SELECT statements on /query")
+	flag.DurationVar(&queryTimeout, "query-timeout", 5*time.Second, "per-request query timeout (0 disables)")
+	rateLimit := flag.Float64("rate-limit", 10, "API requests per second allowed per client IP (0 disables)")
+	resultCache := flag.Int("result-cache", 0, "number of SELECT results to cache (0 disables)")
+	maxRows := flag.Int("max-rows", 10000, "largest number of rows a SELECT may return (0 disables)")
+	storageFormat := flag.String("storage-format", "json", "table file format: json or gob")
+	intOverflow := flag.String("int-overflow", "error", "INT arithmetic overflow: error or promote (to NUMERIC)")
+	flag.Parse()
+
+	format, err := storage.ParseFormat(*storageFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	overflow, err := engine.ParseOverflowMode(*intOverflow)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *rateLimit > 0 {
+		limiter = newRateLimiter(*rateLimit, max(1, int(*rateLimit)))
+		go limiter.cleanupEvery(time.Minute)
+	}
+
+	db = engine.NewEngineWithConfig(engine.Config{DataDir: *dataDir, ResultCacheSize: *resultCache, MaxResultRows: *maxRows, StorageFormat: format, IntOverflow: overflow})
 
 	// Setup Schema and Seed Data
 	setupSchema()
 	seedData()
 
-	http.HandleFunc("/users", corsMiddleware(handleUsers))
-	http.HandleFunc("/orders", corsMiddleware(handleOrders))
+	http.HandleFunc("/users", corsMiddleware(rateLimitMiddleware(timeoutMiddleware(handleUsers))))
+	http.HandleFunc("/orders", corsMiddleware(rateLimitMiddleware(timeoutMiddleware(handleOrders))))
+	http.HandleFunc("/schema", corsMiddleware(rateLimitMiddleware(timeoutMiddleware(handleSchema))))
+	http.HandleFunc("/query", corsMiddleware(rateLimitMiddleware(timeoutMiddleware(handleQuery))))
 	http.HandleFunc("/", handleHome)
 
 	// Use PORT from environment (Railway) or default to 8080
@@ -48,8 +106,25 @@ func main() {
 		port = "8080"
 	}
 
+	// Stop on SIGINT/SIGTERM: finish in-flight requests, then save tables
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + port}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
 	fmt.Printf("Server running on :%s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		log.Fatalf("Failed to save tables: %v", err)
+	}
+	log.Println("Server stopped")
 }
 
 func handleHome(w http.ResponseWriter, r *http.Request) {
@@ -67,7 +142,7 @@ func setupSchema() {
 
 	// Programmatically add FK constraint: orders.user_id -> users.id
 	// Since we don't parse FK syntax yet, we add it directly to the table definition
-	if ordersTable, ok := db.Tables["orders"]; ok {
+	if ordersTable, ok := db.Table("orders"); ok {
 		ordersTable.Def.ForeignKeys = []schema.ForeignKeyDef{
 			{
 				Column:    "user_id",
@@ -90,26 +165,34 @@ func seedData() {
 	log.Println("Seeding sample data...")
 
 	// Sample Users
-	users := []string{
-		"INSERT INTO users VALUES (1, 'Brian Kinyua', 'kinyua@example.com')",
-		"INSERT INTO users VALUES (2, 'Jane Kamau', 'jane.k@pesapal.co.ke')",
-		"INSERT INTO users VALUES (3, 'David Omari', 'omari@nairobi.go.ke')",
-	}
-
-	for _, sql := range users {
-		db.Execute(ctx, sql)
+	users := [][]types.Value{
+		{types.NewInt(1), types.NewText("Brian Kinyua"), types.NewText("kinyua@example.com")},
+		{types.NewInt(2), types.NewText("Jane Kamau"), types.NewText("jane.k@pesapal.co.ke")},
+		{types.NewInt(3), types.NewText("David Omari"), types.NewText("omari@nairobi.go.ke")},
 	}
 
 	// Sample Orders
-	orders := []string{
-		"INSERT INTO orders VALUES (5001, 1, 250, 'Large Samosa Platters')",
-		"INSERT INTO orders VALUES (5002, 2, 45, 'Chapati Madondo')",
-		"INSERT INTO orders VALUES (5003, 2, 120, 'Grilled Sukuma & Ugali')",
-		"INSERT INTO orders VALUES (5004, 3, 3500, 'PesaPal API Credits')",
+	orders := [][]types.Value{
+		{types.NewInt(5001), types.NewInt(1), types.NewInt(250), types.NewText("Large Samosa Platters")},
+		{types.NewInt(5002), types.NewInt(2), types.NewInt(45), types.NewText("Chapati Madondo")},
+		{types.NewInt(5003), types.NewInt(2), types.NewInt(120), types.NewText("Grilled Sukuma & Ugali")},
+		{types.NewInt(5004), types.NewInt(3), types.NewInt(3500), types.NewText("PesaPal API Credits")},
 	}
 
-	for _, sql := range orders {
-		db.Execute(ctx, sql)
+	for _, batch := range []struct {
+		table string
+		rows  [][]types.Value
+	}{{"users", users}, {"orders", orders}} {
+		res, err := db.InsertMany(batch.table, batch.rows)
+		if err != nil {
+			log.Printf("Seeding %s: %v", batch.table, err)
+			continue
+		}
+		for i, err := range res.Errors {
+			if err != nil {
+				log.Printf("Seeding %s row %d: %v", batch.table, i+1, err)
+			}
+		}
 	}
 
 	log.Println("Seeding complete.")
@@ -129,49 +212,63 @@ func handleUsers(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		sql := fmt.Sprintf("INSERT INTO users VALUES (%d, '%s', '%s')", u.ID, u.Name, u.Email)
-		res, err := db.Execute(r.Context(), sql)
+		res, err := db.Insert("users", map[string]types.Value{
+			"id":    types.NewInt(u.ID),
+			"name":  types.NewText(u.Name),
+			"email": types.NewText(u.Email),
+		})
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
-		json.NewEncoder(w).Encode(map[string]string{"message": res.Message})
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": res.Message, "id": res.LastInsertID.Val})
 
 	} else if r.Method == http.MethodGet {
 		// List Users
 		// Optional ?id=X
-		id := r.URL.Query().Get("id")
-		var sql string
-		if id != "" {
-			sql = fmt.Sprintf("SELECT * FROM users WHERE id = %s", id)
+		var res *engine.ResultSet
+		var err error
+		if idParam := r.URL.Query().Get("id"); idParam != "" {
+			id, convErr := strconv.Atoi(idParam)
+			if convErr != nil {
+				http.Error(w, "invalid id", 400)
+				return
+			}
+			res, err = db.ExecutePrepared(r.Context(), "SELECT * FROM users WHERE id = ?", types.NewInt(id))
 		} else {
-			sql = "SELECT * FROM users"
+			res, err = db.Execute(r.Context(), "SELECT * FROM users")
 		}
-
-		res, err := db.Execute(r.Context(), sql)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
 
-		// Convert structure for JSON
-		resp := make([]map[string]interface{}, 0)
-		for _, row := range res.Rows {
-			item := make(map[string]interface{})
-			for i, col := range res.Columns {
-				// Simplified type handling
-				v := row.Values[i]
-				if v.Type == "INT" {
-					val, _ := v.AsInt()
-					item[col] = val
-				} else {
-					val, _ := v.AsText()
-					item[col] = val
-				}
-			}
-			resp = append(resp, item)
+		if err := res.WriteJSON(w); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	} else if r.Method == http.MethodPut {
+		// Update User
+		// JSON: { "id": 1, "name": "Alice", "email": "a@b.com" }; omitted fields are left alone
+		var u struct {
+			ID    int     `json:"id"`
+			Name  *string `json:"name"`
+			Email *string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
 		}
-		json.NewEncoder(w).Encode(resp)
+		set := map[string]types.Value{}
+		if u.Name != nil {
+			set["name"] = types.NewText(*u.Name)
+		}
+		if u.Email != nil {
+			set["email"] = types.NewText(*u.Email)
+		}
+		updateByID(w, r, "users", u.ID, set)
+	} else if r.Method == http.MethodDelete {
+		// Delete User: ?id=X
+		deleteByID(w, r, "users")
 	}
 }
 
@@ -188,12 +285,17 @@ func handleOrders(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), 400)
 			return
 		}
-		sql := fmt.Sprintf("INSERT INTO orders VALUES (%d, %d, %d, '%s')", o.ID, o.UserID, o.Amount, o.Description)
-		if _, err := db.Execute(r.Context(), sql); err != nil {
+		res, err := db.Insert("orders", map[string]types.Value{
+			"id":          types.NewInt(o.ID),
+			"user_id":     types.NewInt(o.UserID),
+			"amount":      types.NewInt(o.Amount),
+			"description": types.NewText(o.Description),
+		})
+		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
-		json.NewEncoder(w).Encode(map[string]string{"result": "ok"})
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": "ok", "id": res.LastInsertID.Val})
 	} else if r.Method == http.MethodGet {
 		// Join Example: ?details=true for joining with users
 		details := r.URL.Query().Get("details")
@@ -211,16 +313,219 @@ func handleOrders(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Convert structure for JSON (Duplicate logic, simplified)
-		resp := make([]map[string]interface{}, 0)
-		for _, row := range res.Rows {
-			item := make(map[string]interface{})
-			for i, col := range res.Columns {
-				v := row.Values[i]
-				item[col] = v.Val // interface{} is unsafe for JSON? usually ok
-			}
-			resp = append(resp, item)
+		if err := res.WriteJSON(w); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	} else if r.Method == http.MethodPut {
+		// Update Order; omitted fields are left alone
+		var o struct {
+			ID          int     `json:"id"`
+			UserID      *int    `json:"user_id"`
+			Amount      *int    `json:"amount"`
+			Description *string `json:"description"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		set := map[string]types.Value{}
+		if o.UserID != nil {
+			set["user_id"] = types.NewInt(*o.UserID)
+		}
+		if o.Amount != nil {
+			set["amount"] = types.NewInt(*o.Amount)
+		}
+		if o.Description != nil {
+			set["description"] = types.NewText(*o.Description)
+		}
+		updateByID(w, r, "orders", o.ID, set)
+	} else if r.Method == http.MethodDelete {
+		// Delete Order: ?id=X
+		deleteByID(w, r, "orders")
+	}
+}
+
+// updateByID runs a parameterized UPDATE of the row with the given id.
+// Column names come from the handler, never from the request.
+func updateByID(w http.ResponseWriter, r *http.Request, table string, id int, set map[string]types.Value) {
+	if len(set) == 0 {
+		http.Error(w, "no fields to update", 400)
+		return
+	}
+	cols := make([]string, 0, len(set))
+	for col := range set {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	assignments := make([]string, len(cols))
+	args := make([]types.Value, 0, len(cols)+1)
+	for i, col := range cols {
+		assignments[i] = col + " = ?"
+		args = append(args, set[col])
+	}
+	args = append(args, types.NewInt(id))
+
+	sql := "UPDATE " + table + " SET " + strings.Join(assignments, ", ") + " WHERE id = ?"
+	res, err := db.ExecutePrepared(r.Context(), sql, args...)
+	writeAffected(w, res, err)
+}
+
+// deleteByID runs a parameterized DELETE of the row named by ?id=.
+func deleteByID(w http.ResponseWriter, r *http.Request, table string) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid id", 400)
+		return
+	}
+	res, err := db.ExecutePrepared(r.Context(), "DELETE FROM "+table+" WHERE id = ?", types.NewInt(id))
+	writeAffected(w, res, err)
+}
+
+// writeAffected reports a write's outcome: 500 on error, 404 if no row
+// matched, otherwise 200 with the affected-row count.
+func writeAffected(w http.ResponseWriter, res *engine.ResultSet, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": res.Message, "affected": res.RowsAffected})
+}
+
+// Schema description types for the /schema endpoint.
+type columnInfo struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	IsPrimary bool   `json:"primary_key"`
+	IsUnique  bool   `json:"unique"`
+}
+
+type foreignKeyInfo struct {
+	Column    string `json:"column"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
+}
+
+type tableInfo struct {
+	Name        string           `json:"name"`
+	Columns     []columnInfo     `json:"columns"`
+	ForeignKeys []foreignKeyInfo `json:"foreign_keys"`
+}
+
+// handleSchema describes every loaded table so the frontend can build forms generically.
+func handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	names, err := db.TableNames()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]tableInfo, 0, len(names))
+	for _, name := range names {
+		def, err := db.TableDef(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		info := tableInfo{
+			Name:        def.Name,
+			Columns:     make([]columnInfo, 0, len(def.Columns)),
+			ForeignKeys: make([]foreignKeyInfo, 0, len(def.ForeignKeys)),
 		}
-		json.NewEncoder(w).Encode(resp)
+		for _, col := range def.Columns {
+			info.Columns = append(info.Columns, columnInfo{
+				Name:      col.Name,
+				Type:      string(col.Type),
+				IsPrimary: col.IsPrimary,
+				IsUnique:  col.IsUnique,
+			})
+		}
+		for _, fk := range def.ForeignKeys {
+			info.ForeignKeys = append(info.ForeignKeys, foreignKeyInfo{
+				Column:    fk.Column,
+				RefTable:  fk.RefTable,
+				RefColumn: fk.RefColumn,
+			})
+		}
+		resp = append(resp, info)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type queryRequest struct {
+	SQL string `json:"sql"`
+}
+
+type queryResponse struct {
+	Columns []string        `json:"columns"`
+	Types   []string        `json:"types"`
+	Rows    [][]interface{} `json:"rows"`
+	Message string          `json:"message,omitempty"`
+}
+
+// handleQuery runs an arbitrary statement from {"sql": "..."}. Rows come back
+// as arrays so joined columns with the same name do not collide. Only SELECT
+// is accepted unless the server was started with -allow-write.
+func handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+
+	stmt, err := parser.NewParser(parser.NewTokenizer(req.SQL)).ParseStatement()
+	if err != nil && !errors.Is(err, parser.ErrEmptyStatement) {
+		http.Error(w, "parse error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// EXPLAIN only reads, even for UPDATE and DELETE
+	_, isSelect := stmt.(*parser.SelectStmt)
+	_, isExplain := stmt.(*parser.ExplainStmt)
+	_, isShow := stmt.(*parser.ShowStatusStmt)
+	_, isStats := stmt.(*parser.ShowStatsStmt)
+	if stmt != nil && !isSelect && !isExplain && !isShow && !isStats && !allowWrite {
+		http.Error(w, "only SELECT, EXPLAIN, SHOW STATUS and SHOW STATS are allowed; start the server with -allow-write to permit writes", http.StatusForbidden)
+		return
+	}
+
+	res, err := db.Execute(r.Context(), req.SQL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := queryResponse{
+		Columns: res.Columns,
+		Types:   make([]string, len(res.ColumnTypes)),
+		Rows:    make([][]interface{}, 0, len(res.Rows)),
+		Message: res.Message,
+	}
+	if resp.Columns == nil {
+		resp.Columns = []string{}
+	}
+	for i, t := range res.ColumnTypes {
+		resp.Types[i] = string(t)
+	}
+	for _, row := range res.Rows {
+		resp.Rows = append(resp.Rows, res.RowValues(row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
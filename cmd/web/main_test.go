@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mini-rdbms/db/engine"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// setupTestDB points the package-level engine at a temp dir and seeds it.
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	db = engine.NewEngineWithConfig(engine.Config{DataDir: t.TempDir()})
+	setupSchema()
+	seedData()
+}
+
+func TestHandleSchema(t *testing.T) {
+	setupTestDB(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/schema", nil)
+	rec := httptest.NewRecorder()
+	handleSchema(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var tables []tableInfo
+	if err := json.NewDecoder(rec.Body).Decode(&tables); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+
+	byName := make(map[string]tableInfo)
+	for _, ti := range tables {
+		byName[ti.Name] = ti
+	}
+
+	users, ok := byName["users"]
+	if !ok {
+		t.Fatalf("users table missing from schema")
+	}
+	wantUsers := []columnInfo{
+		{Name: "id", Type: "INT", IsPrimary: true},
+		{Name: "name", Type: "TEXT", IsUnique: true},
+		{Name: "email", Type: "TEXT"},
+	}
+	if len(users.Columns) != len(wantUsers) {
+		t.Fatalf("Expected %d users columns, got %d", len(wantUsers), len(users.Columns))
+	}
+	for i, want := range wantUsers {
+		if users.Columns[i] != want {
+			t.Errorf("users column %d: expected %+v, got %+v", i, want, users.Columns[i])
+		}
+	}
+
+	orders, ok := byName["orders"]
+	if !ok {
+		t.Fatalf("orders table missing from schema")
+	}
+	if len(orders.Columns) != 4 {
+		t.Errorf("Expected 4 orders columns, got %d", len(orders.Columns))
+	}
+	wantFK := foreignKeyInfo{Column: "user_id", RefTable: "users", RefColumn: "id"}
+	if len(orders.ForeignKeys) != 1 || orders.ForeignKeys[0] != wantFK {
+		t.Errorf("Expected orders FK %+v, got %+v", wantFK, orders.ForeignKeys)
+	}
+}
+
+func TestHandleUsersByID(t *testing.T) {
+	setupTestDB(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/users?id=2", nil)
+	rec := httptest.NewRecorder()
+	handleUsers(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	// Keys come out in column order, not sorted
+	if body := rec.Body.String(); !strings.HasPrefix(body, `[{"id":2,"name":"Jane Kamau"`) {
+		t.Errorf("Expected columns in table order, got %s", body)
+	}
+	var users []map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&users); err != nil {
+		t.Fatalf("Failed to decode users: %v", err)
+	}
+	if len(users) != 1 || users[0]["name"] != "Jane Kamau" {
+		t.Errorf("Expected Jane Kamau, got %v", users)
+	}
+
+	// A non-numeric id is rejected instead of being spliced into SQL.
+	req = httptest.NewRequest(http.MethodGet, "/users?id=1%20OR%20id%20%3E%200", nil)
+	rec = httptest.NewRecorder()
+	handleUsers(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+func postQuery(t *testing.T, sql string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(queryRequest{SQL: sql})
+	req := httptest.NewRequest(http.MethodPost, "/query", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleQuery(rec, req)
+	return rec
+}
+
+func TestHandleQuery(t *testing.T) {
+	setupTestDB(t)
+
+	rec := postQuery(t, "SELECT users.id, orders.id, amount FROM orders JOIN users ON orders.user_id = users.id WHERE amount > 200")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp queryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Columns) != 3 || resp.Columns[0] != "users.id" || resp.Types[2] != "INT" {
+		t.Errorf("Unexpected columns %v / types %v", resp.Columns, resp.Types)
+	}
+	// JSON numbers decode as float64
+	want := [][]interface{}{{1.0, 5001.0, 250.0}, {3.0, 5004.0, 3500.0}}
+	if len(resp.Rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %d", len(want), len(resp.Rows))
+	}
+	for i, w := range want {
+		for j, v := range w {
+			if resp.Rows[i][j] != v {
+				t.Errorf("Row %d col %d: expected %v, got %v", i, j, v, resp.Rows[i][j])
+			}
+		}
+	}
+
+	// Writes are refused unless enabled.
+	if rec := postQuery(t, "DELETE FROM orders"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for DELETE, got %d", rec.Code)
+	}
+	if rec := postQuery(t, "SELEC nonsense"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for bad SQL, got %d", rec.Code)
+	}
+
+	allowWrite = true
+	defer func() { allowWrite = false }()
+	rec = postQuery(t, "DELETE FROM orders WHERE id = 5002")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 with writes allowed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Message != "Deleted 1 rows" {
+		t.Errorf("Unexpected message: %s", resp.Message)
+	}
+}
+
+func TestHandleUsersUpdateAndDelete(t *testing.T) {
+	setupTestDB(t)
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handleUsers(rec, req)
+		return rec
+	}
+	affected := func(rec *httptest.ResponseRecorder) float64 {
+		var resp map[string]interface{}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		n, _ := resp["affected"].(float64)
+		return n
+	}
+
+	rec := send(http.MethodPut, "/users", `{"id": 2, "email": "jane@o'brien.example"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := affected(rec); n != 1 {
+		t.Errorf("Expected 1 affected row, got %v", n)
+	}
+	row, _ := db.Tables["users"].GetRow(2)
+	if row.Values[1].Val != "Jane Kamau" || row.Values[2].Val != "jane@o'brien.example" {
+		t.Errorf("Unexpected row after update: %v", row.Values)
+	}
+
+	if rec := send(http.MethodPut, "/users", `{"id": 99, "name": "Nobody"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown user, got %d", rec.Code)
+	}
+	if rec := send(http.MethodPut, "/users", `{"id": 2}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for empty update, got %d", rec.Code)
+	}
+
+	rec = send(http.MethodDelete, "/users?id=3", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := affected(rec); n != 1 {
+		t.Errorf("Expected 1 affected row, got %v", n)
+	}
+	if _, ok := db.Tables["users"].GetRow(3); ok {
+		t.Errorf("Expected user 3 to be deleted")
+	}
+	if rec := send(http.MethodDelete, "/users?id=3", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 on second delete, got %d", rec.Code)
+	}
+	if rec := send(http.MethodDelete, "/users?id=x", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid id, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limiter throttles API requests per client IP. Nil disables rate limiting.
+var limiter *rateLimiter
+
+// rateLimiter is a token bucket per client: each client may burst up to
+// burst requests, then regains rate requests per second.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	clients map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time // when tokens was last refilled
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		clients: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from key's bucket, reporting false if it is empty.
+func (l *rateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.clients[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// cleanup forgets clients whose buckets have refilled completely, since a
+// fresh bucket would behave the same. This keeps memory bounded by the
+// number of recently active clients.
+func (l *rateLimiter) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	now := l.now()
+	for key, b := range l.clients {
+		if now.Sub(b.last) >= full {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// cleanupEvery runs cleanup on a ticker until the process exits.
+func (l *rateLimiter) cleanupEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		l.cleanup()
+	}
+}
+
+// rateLimitMiddleware answers 429 Too Many Requests once a client IP has
+// used up its tokens.
+func rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if limiter != nil && !limiter.allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientIP returns the host part of the request's remote address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
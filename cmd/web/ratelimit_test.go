@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	setupTestDB(t)
+	clock := time.Unix(0, 0)
+	limiter = newRateLimiter(2, 3)
+	limiter.now = func() time.Time { return clock }
+	defer func() { limiter = nil }()
+
+	handler := rateLimitMiddleware(handleSchema)
+	get := func(remote string) int {
+		req := httptest.NewRequest(http.MethodGet, "/schema", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	// The burst of 3 passes, the 4th rapid request is refused
+	for i := 1; i <= 3; i++ {
+		if code := get("10.0.0.1:5000"); code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, code)
+		}
+	}
+	if code := get("10.0.0.1:5001"); code != http.StatusTooManyRequests {
+		t.Fatalf("Request 4: expected 429, got %d", code)
+	}
+
+	// Other clients have their own bucket
+	if code := get("10.0.0.2:5000"); code != http.StatusOK {
+		t.Errorf("Other client: expected 200, got %d", code)
+	}
+
+	// Half a second at 2/s refills one token
+	clock = clock.Add(500 * time.Millisecond)
+	if code := get("10.0.0.1:5000"); code != http.StatusOK {
+		t.Errorf("After refill: expected 200, got %d", code)
+	}
+	if code := get("10.0.0.1:5000"); code != http.StatusTooManyRequests {
+		t.Errorf("After refill: expected 429 once the token is spent, got %d", code)
+	}
+
+	// Buckets that have refilled completely are dropped
+	clock = clock.Add(2 * time.Second)
+	limiter.cleanup()
+	if n := len(limiter.clients); n != 0 {
+		t.Errorf("Expected idle clients to be cleaned up, %d remain", n)
+	}
+}
@@ -0,0 +1,480 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+)
+
+// aggregateFunction is a built-in function that folds every input row into
+// one value.
+type aggregateFunction struct {
+	// resultType checks the argument types and returns the result type.
+	// A * argument is passed as an empty DataType.
+	resultType func(args []types.DataType) (types.DataType, error)
+	// newState starts a fresh accumulator for one pass over the input. coll
+	// is the collation of the argument, for functions that order values.
+	newState func(coll types.Collation) aggregateState
+	// promoted, if set, takes the function's place under OverflowPromote.
+	promoted *aggregateFunction
+}
+
+// aggregateState accumulates the argument of one aggregate call, row by row.
+type aggregateState interface {
+	add(v types.Value) error
+	result() types.Value
+}
+
+// aggregateFunctions is keyed by upper-case function name.
+var aggregateFunctions = map[string]aggregateFunction{
+	"COUNT": {resultType: countType, newState: func(types.Collation) aggregateState { return &countState{} }},
+	"SUM": {resultType: sumType, newState: func(types.Collation) aggregateState { return &sumState{} },
+		promoted: &aggregateFunction{resultType: numericSumType, newState: func(types.Collation) aggregateState { return &numericSumState{total: new(big.Int)} }}},
+	"MIN": {resultType: minMaxType("MIN"), newState: func(coll types.Collation) aggregateState { return &minMaxState{want: -1, coll: coll} }},
+	"MAX": {resultType: minMaxType("MAX"), newState: func(coll types.Collation) aggregateState { return &minMaxState{want: 1, coll: coll} }},
+}
+
+func countType(args []types.DataType) (types.DataType, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("COUNT takes exactly one argument")
+	}
+	return types.TypeInt, nil
+}
+
+// countState counts non-NULL arguments; COUNT(*) feeds it a non-NULL value
+// for every row.
+type countState struct{ n int }
+
+func (s *countState) add(v types.Value) error {
+	if !v.IsNull() {
+		s.n++
+	}
+	return nil
+}
+
+func (s *countState) result() types.Value { return types.NewInt(s.n) }
+
+func sumType(args []types.DataType) (types.DataType, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("SUM takes exactly one argument")
+	}
+	if args[0] != "" && args[0] != types.TypeInt {
+		return "", fmt.Errorf("SUM requires an INT argument, got %s", args[0])
+	}
+	return types.TypeInt, nil
+}
+
+// sumState adds non-NULL arguments. The sum of no values is NULL, and a
+// total that does not fit in an int is an error rather than wrapping.
+type sumState struct {
+	total int
+	seen  bool
+}
+
+func (s *sumState) add(v types.Value) error {
+	if v.IsNull() {
+		return nil
+	}
+	n, err := v.AsInt()
+	if err != nil {
+		return err
+	}
+	total, ok := addInt(s.total, n)
+	if !ok {
+		return errIntOverflow
+	}
+	s.total, s.seen = total, true
+	return nil
+}
+
+func (s *sumState) result() types.Value {
+	if !s.seen {
+		return types.NewNull(types.TypeInt)
+	}
+	return types.NewInt(s.total)
+}
+
+// minMaxType returns the result type check for MIN or MAX, whose result
+// has the type of its argument.
+func minMaxType(name string) func(args []types.DataType) (types.DataType, error) {
+	return func(args []types.DataType) (types.DataType, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("%s takes exactly one argument", name)
+		}
+		return args[0], nil
+	}
+}
+
+// minMaxState keeps the smallest (want -1) or largest (want 1) non-NULL
+// argument. TEXT is compared under the argument's collation, as WHERE and
+// SHOW STATS compare it. The result over no values is NULL.
+type minMaxState struct {
+	want int
+	coll types.Collation
+	best types.Value
+	seen bool
+}
+
+func (s *minMaxState) add(v types.Value) error {
+	if v.IsNull() {
+		return nil
+	}
+	if !s.seen {
+		s.best, s.seen = v, true
+		return nil
+	}
+	cmp, err := v.CompareCollated(s.best, s.coll)
+	if err != nil {
+		return err
+	}
+	if cmp == s.want {
+		s.best = v
+	}
+	return nil
+}
+
+func (s *minMaxState) result() types.Value {
+	if !s.seen {
+		return types.NewNull("")
+	}
+	return s.best
+}
+
+func numericSumType(args []types.DataType) (types.DataType, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("SUM takes exactly one argument")
+	}
+	if args[0] != "" && args[0] != types.TypeInt && args[0] != types.TypeNumeric {
+		return "", fmt.Errorf("SUM requires an INT argument, got %s", args[0])
+	}
+	return types.TypeNumeric, nil
+}
+
+// numericSumState is sumState under OverflowPromote: the total is NUMERIC
+// and cannot overflow.
+type numericSumState struct {
+	total *big.Int
+	seen  bool
+}
+
+func (s *numericSumState) add(v types.Value) error {
+	if v.IsNull() {
+		return nil
+	}
+	n, err := v.AsNumeric()
+	if err != nil {
+		return err
+	}
+	s.total.Add(s.total, n)
+	s.seen = true
+	return nil
+}
+
+func (s *numericSumState) result() types.Value {
+	if !s.seen {
+		return types.NewNull(types.TypeNumeric)
+	}
+	return types.NewNumeric(s.total)
+}
+
+// distinctState passes each distinct non-NULL value to inner once, for
+// COUNT(DISTINCT col). Values equal under the argument's collation count
+// once.
+type distinctState struct {
+	inner aggregateState
+	coll  types.Collation
+	seen  map[string]bool
+}
+
+func (s *distinctState) add(v types.Value) error {
+	key := v.Fold(s.coll).Hash()
+	if v.IsNull() || s.seen[key] {
+		return nil
+	}
+	s.seen[key] = true
+	return s.inner.add(v)
+}
+
+func (s *distinctState) result() types.Value { return s.inner.result() }
+
+// aggregateFunction returns the implementation of the aggregate fn calls
+// under v's overflow setting.
+func (v evaluator) aggregateFunction(fn *parser.FunctionCall) aggregateFunction {
+	f := aggregateFunctions[fn.Name]
+	if v.promote && f.promoted != nil {
+		return *f.promoted
+	}
+	return f
+}
+
+// newAggregateState starts an accumulator for one aggregate call over rows
+// of def.
+func (v evaluator) newAggregateState(fn *parser.FunctionCall, def schema.TableDef) aggregateState {
+	coll := argCollation(fn, def)
+	state := v.aggregateFunction(fn).newState(coll)
+	if fn.Distinct {
+		return &distinctState{inner: state, coll: coll, seen: make(map[string]bool)}
+	}
+	return state
+}
+
+// argCollation returns the collation of fn's argument: its column's for a
+// plain column, binary for anything computed.
+func argCollation(fn *parser.FunctionCall, def schema.TableDef) types.Collation {
+	if len(fn.Args) != 1 {
+		return types.CollationBinary
+	}
+	ref, ok := fn.Args[0].(*parser.ColumnRef)
+	if !ok {
+		return types.CollationBinary
+	}
+	idx, err := def.ResolveColumn(ref.Table, ref.Name)
+	if err != nil {
+		return types.CollationBinary
+	}
+	return def.Columns[idx].Collation
+}
+
+// isAggregate reports whether expr is a call to an aggregate function.
+func isAggregate(expr parser.Expression) bool {
+	fn, ok := expr.(*parser.FunctionCall)
+	if !ok {
+		return false
+	}
+	_, ok = aggregateFunctions[fn.Name]
+	return ok
+}
+
+// hasAggregates reports whether a SELECT list uses any aggregate function.
+func hasAggregates(fields []parser.Expression) bool {
+	for _, f := range fields {
+		if isAggregate(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAggregate reports whether expr calls an aggregate function at
+// any depth.
+func containsAggregate(expr parser.Expression) bool {
+	switch e := expr.(type) {
+	case *parser.FunctionCall:
+		if isAggregate(e) {
+			return true
+		}
+		for _, a := range e.Args {
+			if containsAggregate(a) {
+				return true
+			}
+		}
+	case *parser.InfixExpression:
+		return containsAggregate(e.Left) || containsAggregate(e.Right)
+	case *parser.NotExpression:
+		return containsAggregate(e.Expr)
+	}
+	return false
+}
+
+// isGrouped reports whether a SELECT folds its rows into groups, which it
+// does whenever it has a GROUP BY or HAVING clause.
+func isGrouped(stmt *parser.SelectStmt) bool {
+	return len(stmt.GroupBy) > 0 || stmt.Having != nil
+}
+
+// isCountStar reports whether expr is exactly COUNT(*).
+func isCountStar(expr parser.Expression) bool {
+	fn, ok := expr.(*parser.FunctionCall)
+	if !ok || fn.Name != "COUNT" || fn.Distinct || len(fn.Args) != 1 {
+		return false
+	}
+	return isStar(fn.Args[0])
+}
+
+// isIndexedMinMax reports whether expr is MIN(col) or MAX(col) on a plain
+// column of table that has an ordered index, and returns the column.
+func isIndexedMinMax(expr parser.Expression, table *storage.Table) (string, bool) {
+	fn, ok := expr.(*parser.FunctionCall)
+	if !ok || (fn.Name != "MIN" && fn.Name != "MAX") || fn.Distinct || len(fn.Args) != 1 {
+		return "", false
+	}
+	col, ok := fn.Args[0].(*parser.ColumnRef)
+	if !ok || !table.HasIndex(col.Name) {
+		return "", false
+	}
+	// Index keys are in byte order (or folded, in a unique index), so the
+	// ends of the index are only the MIN and MAX under binary collation
+	if def, _ := table.Def.GetColumn(col.Name); !def.Collation.IsBinary() {
+		return "", false
+	}
+	return col.Name, true
+}
+
+// AggregateNode folds all of its input into a single row holding one value
+// per SELECT field. Every field must be an aggregate call.
+type AggregateNode struct {
+	Input  PlanNode
+	Fields []parser.Expression
+
+	eval evaluator
+}
+
+// newAggregateNode checks that every field is an aggregate call whose
+// arguments fit its input before building the node.
+func newAggregateNode(input PlanNode, fields []parser.Expression, eval evaluator) (*AggregateNode, error) {
+	def := input.Schema()
+	for _, f := range fields {
+		if !isAggregate(f) {
+			return nil, fmt.Errorf("%s must be used inside an aggregate function", f)
+		}
+		if _, err := eval.aggregateType(f.(*parser.FunctionCall), def); err != nil {
+			return nil, err
+		}
+	}
+	return &AggregateNode{Input: input, Fields: fields, eval: eval}, nil
+}
+
+// aggregateType returns the result type of an aggregate call over def.
+func (v evaluator) aggregateType(fn *parser.FunctionCall, def schema.TableDef) (types.DataType, error) {
+	if fn.Distinct && fn.Name != "COUNT" {
+		return "", fmt.Errorf("DISTINCT is only supported in COUNT, not %s", fn.Name)
+	}
+	argTypes := make([]types.DataType, len(fn.Args))
+	for i, a := range fn.Args {
+		if isStar(a) {
+			if fn.Name != "COUNT" {
+				return "", fmt.Errorf("%s does not accept *", fn.Name)
+			}
+			if fn.Distinct {
+				return "", fmt.Errorf("COUNT(DISTINCT *) is not allowed; name a column")
+			}
+			continue
+		}
+		t, err := v.scalarType(a, def)
+		if err != nil {
+			return "", err
+		}
+		argTypes[i] = t
+	}
+	return v.aggregateFunction(fn).resultType(argTypes)
+}
+
+func (n *AggregateNode) Open(ctx context.Context) RowIterator {
+	done := false
+	return iterate(func() (storage.Row, bool, error) {
+		if done {
+			return storage.Row{}, false, nil
+		}
+		done = true
+
+		def := n.Input.Schema()
+		states := make([]aggregateState, len(n.Fields))
+		for i, f := range n.Fields {
+			states[i] = n.eval.newAggregateState(f.(*parser.FunctionCall), def)
+		}
+		input := n.Input.Open(ctx)
+		for {
+			row, ok := input.Next()
+			if !ok {
+				break
+			}
+			if err := n.eval.accumulate(states, n.Fields, row, def); err != nil {
+				return storage.Row{}, false, err
+			}
+		}
+		if err := input.Err(); err != nil {
+			return storage.Row{}, false, err
+		}
+
+		values := make([]types.Value, len(states))
+		for i, s := range states {
+			values[i] = s.result()
+		}
+		return storage.Row{Values: values}, true, nil
+	})
+}
+
+// accumulate feeds one input row to the state of each aggregate call.
+func (v evaluator) accumulate(states []aggregateState, calls []parser.Expression, row storage.Row, def schema.TableDef) error {
+	for i, f := range calls {
+		arg := types.NewInt(1) // stands in for * so every row counts
+		if a := f.(*parser.FunctionCall).Args[0]; !isStar(a) {
+			val, err := v.scalar(a, row, def)
+			if err != nil {
+				return err
+			}
+			arg = val
+		}
+		if err := states[i].add(arg); err != nil {
+			if errors.Is(err, errIntOverflow) {
+				err = fmt.Errorf("%w in %s", errIntOverflow, f)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *AggregateNode) Schema() schema.TableDef {
+	def := n.Input.Schema()
+	cols := make([]schema.ColumnDef, len(n.Fields))
+	for i, f := range n.Fields {
+		t, _ := n.eval.aggregateType(f.(*parser.FunctionCall), def)
+		cols[i] = schema.ColumnDef{Name: f.String(), Type: t}
+	}
+	return schema.TableDef{Name: def.Name, Columns: cols}
+}
+
+func isStar(expr parser.Expression) bool {
+	_, ok := expr.(*parser.Star)
+	return ok
+}
+
+// CountNode answers a bare SELECT COUNT(*) from the table's row count
+// without scanning any rows.
+type CountNode struct {
+	Table *storage.Table
+	Field parser.Expression
+}
+
+func (n *CountNode) Open(ctx context.Context) RowIterator {
+	row := storage.Row{Values: []types.Value{types.NewInt(n.Table.RowCount())}}
+	return sliceIterator(ctx, []storage.Row{row})
+}
+
+func (n *CountNode) Schema() schema.TableDef {
+	return schema.TableDef{
+		Name:    n.Table.Def.Name,
+		Columns: []schema.ColumnDef{{Name: n.Field.String(), Type: types.TypeInt}},
+	}
+}
+
+// MinMaxNode answers a bare SELECT MIN(col) or MAX(col) by reading the first
+// or last key of the column's ordered index instead of scanning.
+type MinMaxNode struct {
+	Table  *storage.Table
+	Column string
+	Field  parser.Expression
+}
+
+func (n *MinMaxNode) Open(ctx context.Context) RowIterator {
+	lo, hi, _ := n.Table.IndexBounds(n.Column)
+	v := lo
+	if n.Field.(*parser.FunctionCall).Name == "MAX" {
+		v = hi
+	}
+	return sliceIterator(ctx, []storage.Row{{Values: []types.Value{v}}})
+}
+
+func (n *MinMaxNode) Schema() schema.TableDef {
+	col, _ := n.Table.Def.GetColumn(n.Column)
+	return schema.TableDef{
+		Name:    n.Table.Def.Name,
+		Columns: []schema.ColumnDef{{Name: n.Field.String(), Type: col.Type}},
+	}
+}
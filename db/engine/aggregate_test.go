@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"testing"
+)
+
+func TestCountStarFastPathMatchesScan(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT, note TEXT)",
+		"INSERT INTO orders VALUES (1, 10, 'a')",
+		"INSERT INTO orders VALUES (2, 20, 'b')",
+		"INSERT INTO orders VALUES (3, 30, 'c')",
+		"DELETE FROM orders WHERE id = 2",
+		"INSERT INTO orders VALUES (4, 40, 'd')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	count := func(sql string) int {
+		t.Helper()
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(res.Rows) != 1 || len(res.Rows[0].Values) != 1 {
+			t.Fatalf("%s: expected a single value, got %v", sql, res.Rows)
+		}
+		if res.Columns[0] != "COUNT(*)" && res.Columns[0] != "COUNT(note)" {
+			t.Errorf("%s: unexpected column label %q", sql, res.Columns[0])
+		}
+		return res.Rows[0].Values[0].Val.(int)
+	}
+
+	fast := count("SELECT COUNT(*) FROM orders")
+	scanned := count("SELECT COUNT(*) FROM orders WHERE amount >= 0")
+	if fast != 3 || scanned != fast {
+		t.Errorf("fast count %d, scanned count %d, want 3 for both", fast, scanned)
+	}
+	if got := count("SELECT COUNT(*) FROM orders WHERE amount > 10"); got != 2 {
+		t.Errorf("filtered count = %d, want 2", got)
+	}
+	if got := count("SELECT COUNT(note) FROM orders"); got != 3 {
+		t.Errorf("COUNT(note) = %d, want 3", got)
+	}
+
+	// Only the bare form takes the fast path.
+	plans := map[string]bool{
+		"SELECT COUNT(*) FROM orders":                  true,
+		"SELECT COUNT(*) FROM orders WHERE amount > 0": false,
+		"SELECT COUNT(note) FROM orders":               false,
+	}
+	for sql, wantFast := range plans {
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			t.Fatalf("parse %s: %v", sql, err)
+		}
+		if err := e.resolveSelect(stmt.(*parser.SelectStmt)); err != nil {
+			t.Fatalf("resolve %s: %v", sql, err)
+		}
+		plan, err := NewPlanner(e.Tables).CreatePlan(stmt)
+		if err != nil {
+			t.Fatalf("plan %s: %v", sql, err)
+		}
+		if _, isFast := plan.(*CountNode); isFast != wantFast {
+			t.Errorf("%s: planned %T, fast path = %v, want %v", sql, plan, isFast, wantFast)
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT id, COUNT(*) FROM orders",
+		"SELECT GREATEST(*) FROM orders",
+		"SELECT COUNT(id, amount) FROM orders",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected an error", sql)
+		}
+	}
+}
+
+func TestCountDistinct(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, note TEXT)",
+		"INSERT INTO orders VALUES (1, 10, 'a')",
+		"INSERT INTO orders VALUES (2, 20, 'a')",
+		"INSERT INTO orders VALUES (3, 10, 'b')",
+		"INSERT INTO orders VALUES (4, 10, 'a')",
+		"INSERT INTO orders VALUES (5, 30, 'b')",
+		"INSERT INTO orders VALUES (6, 20, 'c')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT COUNT(DISTINCT user_id), COUNT(user_id), count(distinct note) FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if res.Columns[0] != "COUNT(DISTINCT user_id)" {
+		t.Errorf("Unexpected column label %q", res.Columns[0])
+	}
+	if got := fmt.Sprint(res.Rows[0].Values); got != "[3 6 3]" {
+		t.Errorf("Expected counts [3 6 3], got %s", got)
+	}
+
+	res, err = e.Execute(ctx, "SELECT COUNT(DISTINCT user_id) FROM orders WHERE id > 2")
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if got := res.Rows[0].Values[0].Val; got != 3 {
+		t.Errorf("Expected 3 distinct users after id 2, got %v", got)
+	}
+
+	for _, sql := range []string{
+		"SELECT COUNT(DISTINCT *) FROM orders",
+		"SELECT SUM(DISTINCT user_id) FROM orders",
+		"SELECT LENGTH(DISTINCT note) FROM orders",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
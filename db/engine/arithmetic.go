@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/types"
+)
+
+// errIntOverflow reports an INT result that does not fit in Go's int.
+// Callers wrap it with the expression that overflowed.
+var errIntOverflow = errors.New("integer overflow")
+
+// OverflowMode is what INT arithmetic and SUM do with a result that does
+// not fit in an int.
+type OverflowMode string
+
+const (
+	// OverflowError fails the statement with "integer overflow in <expr>".
+	// It is the default.
+	OverflowError OverflowMode = "error"
+	// OverflowPromote computes arithmetic and SUM as NUMERIC, an integer of
+	// any size, so they cannot overflow. Their result columns are NUMERIC
+	// even when the values would fit in an INT.
+	OverflowPromote OverflowMode = "promote"
+)
+
+// ParseOverflowMode returns the mode named by s; empty means OverflowError.
+func ParseOverflowMode(s string) (OverflowMode, error) {
+	switch m := OverflowMode(s); m {
+	case "":
+		return OverflowError, nil
+	case OverflowError, OverflowPromote:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown overflow mode: %s (want error or promote)", s)
+}
+
+// isArithmetic reports whether e is an arithmetic operator node.
+func isArithmetic(e *parser.InfixExpression) bool {
+	switch e.Operator {
+	case "+", "-", "*", "/":
+		return true
+	}
+	return false
+}
+
+// arithmeticType checks that both operands are INT (or NULL), or with
+// promote also NUMERIC, and returns the result type.
+func arithmeticType(e *parser.InfixExpression, left, right types.DataType, promote bool) (types.DataType, error) {
+	for _, t := range []types.DataType{left, right} {
+		if t != "" && t != types.TypeInt && !(promote && t == types.TypeNumeric) {
+			return "", fmt.Errorf("%s: operator %s requires INT operands, got %s", e, e.Operator, t)
+		}
+	}
+	if promote {
+		return types.TypeNumeric, nil
+	}
+	return types.TypeInt, nil
+}
+
+// applyArithmetic computes left op right over INTs. A NULL operand gives
+// NULL; overflow and division by zero are errors. With promote the result
+// is NUMERIC and cannot overflow.
+func applyArithmetic(e *parser.InfixExpression, left, right types.Value, promote bool) (types.Value, error) {
+	if promote {
+		return applyNumeric(e, left, right)
+	}
+	if left.IsNull() || right.IsNull() {
+		return types.NewNull(types.TypeInt), nil
+	}
+	a, err := left.AsInt()
+	if err != nil {
+		return types.Value{}, fmt.Errorf("%s: %w", e, err)
+	}
+	b, err := right.AsInt()
+	if err != nil {
+		return types.Value{}, fmt.Errorf("%s: %w", e, err)
+	}
+
+	var result int
+	ok := true
+	switch e.Operator {
+	case "+":
+		result, ok = addInt(a, b)
+	case "-":
+		result, ok = subInt(a, b)
+	case "*":
+		result, ok = mulInt(a, b)
+	case "/":
+		if b == 0 {
+			return types.Value{}, fmt.Errorf("division by zero in %s", e)
+		}
+		if a == math.MinInt && b == -1 {
+			ok = false
+		} else {
+			result = a / b
+		}
+	}
+	if !ok {
+		return types.Value{}, fmt.Errorf("%w in %s", errIntOverflow, e)
+	}
+	return types.NewInt(result), nil
+}
+
+// applyNumeric is applyArithmetic for OverflowPromote. Division truncates
+// toward zero, as it does for INT.
+func applyNumeric(e *parser.InfixExpression, left, right types.Value) (types.Value, error) {
+	if left.IsNull() || right.IsNull() {
+		return types.NewNull(types.TypeNumeric), nil
+	}
+	a, err := left.AsNumeric()
+	if err != nil {
+		return types.Value{}, fmt.Errorf("%s: %w", e, err)
+	}
+	b, err := right.AsNumeric()
+	if err != nil {
+		return types.Value{}, fmt.Errorf("%s: %w", e, err)
+	}
+
+	result := new(big.Int)
+	switch e.Operator {
+	case "+":
+		result.Add(a, b)
+	case "-":
+		result.Sub(a, b)
+	case "*":
+		result.Mul(a, b)
+	case "/":
+		if b.Sign() == 0 {
+			return types.Value{}, fmt.Errorf("division by zero in %s", e)
+		}
+		result.Quo(a, b)
+	}
+	return types.NewNumeric(result), nil
+}
+
+// addInt returns a+b and whether it fit in an int.
+func addInt(a, b int) (int, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+// subInt returns a-b and whether it fit in an int.
+func subInt(a, b int) (int, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+
+// mulInt returns a*b and whether it fit in an int.
+func mulInt(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return c, false
+	}
+	return c, true
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"mini-rdbms/db/types"
+	"strings"
+	"testing"
+)
+
+func TestArithmeticAndSum(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT, note TEXT)",
+		"INSERT INTO orders VALUES (1, 10, 'a')",
+		"INSERT INTO orders VALUES (2, 25, 'b')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT id, amount * 2 + 1, (amount + 1) * 2, amount - id - 1, amount / 4 FROM orders")
+	if err != nil {
+		t.Fatalf("arithmetic select: %v", err)
+	}
+	want := [][]int{{1, 21, 22, 8, 2}, {2, 51, 52, 22, 6}}
+	for i, row := range res.Rows {
+		for j, v := range row.Values {
+			if v.Val != want[i][j] {
+				t.Errorf("row %d column %s: got %v, want %d", i, res.Columns[j], v.Val, want[i][j])
+			}
+		}
+	}
+
+	res, err = e.Execute(ctx, "SELECT SUM(amount), SUM(amount * 2) FROM orders")
+	if err != nil {
+		t.Fatalf("sum: %v", err)
+	}
+	if got := res.Rows[0].Values; got[0].Val != 35 || got[1].Val != 70 {
+		t.Errorf("SUM results = %v, want 35 and 70", got)
+	}
+	res, err = e.Execute(ctx, "SELECT SUM(amount) FROM orders WHERE amount > 100")
+	if err != nil {
+		t.Fatalf("empty sum: %v", err)
+	}
+	if !res.Rows[0].Values[0].IsNull() {
+		t.Errorf("SUM over no rows = %v, want NULL", res.Rows[0].Values[0])
+	}
+
+	// Values near the edge of int overflow instead of wrapping.
+	big := fmt.Sprintf("INSERT INTO orders VALUES (3, %d, 'big')", math.MaxInt-5)
+	if _, err := e.Execute(ctx, big); err != nil {
+		t.Fatalf("%s: %v", big, err)
+	}
+	overflows := []string{
+		"SELECT SUM(amount) FROM orders",
+		"SELECT amount * 1000000 FROM orders",
+		"SELECT amount + 10 FROM orders WHERE id = 3",
+	}
+	for _, sql := range overflows {
+		_, err := e.Execute(ctx, sql)
+		if err == nil || !strings.Contains(err.Error(), "integer overflow in") {
+			t.Errorf("%s: expected integer overflow error, got %v", sql, err)
+		}
+	}
+	if _, err := e.Execute(ctx, "SELECT SUM(amount) FROM orders"); err == nil || !strings.Contains(err.Error(), "SUM(") {
+		t.Errorf("expected the overflow error to name the SUM, got %v", err)
+	}
+
+	for _, sql := range []string{
+		"SELECT amount / 0 FROM orders",
+		"SELECT note + 1 FROM orders",
+		"SELECT SUM(note) FROM orders",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected an error", sql)
+		}
+	}
+}
+
+func TestProjectColumnsWithExpressions(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, name TEXT, amount INT)",
+		"CREATE TABLE items (id INT PRIMARY KEY, order_id INT, qty INT)",
+		"INSERT INTO orders VALUES (1, 'pen', 5)",
+		"INSERT INTO orders VALUES (2, 'ink', 7)",
+		"INSERT INTO items VALUES (10, 2, 3)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		sql     string
+		columns string
+		rows    string
+	}{
+		{"SELECT name, amount * 2 FROM orders", "[name amount * 2]", "[[pen 10] [ink 14]]"},
+		{"SELECT amount * 2 AS double, name, LENGTH(name) FROM orders WHERE id = 2", "[double name LENGTH(name)]", "[[14 ink 3]]"},
+		{"SELECT *, amount + id FROM orders", "[id name amount amount + id]", "[[1 pen 5 6] [2 ink 7 9]]"},
+		{"SELECT orders.name, items.qty * orders.amount AS total FROM orders JOIN items ON orders.id = items.order_id", "[orders.name total]", "[[ink 21]]"},
+		{"SELECT orders.*, qty - 1 FROM orders JOIN items ON orders.id = items.order_id", "[id name amount qty - 1]", "[[2 ink 7 2]]"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		var rows [][]interface{}
+		for _, row := range res.Rows {
+			var vals []interface{}
+			for _, v := range row.Values {
+				vals = append(vals, v.Val)
+			}
+			rows = append(rows, vals)
+		}
+		if got := fmt.Sprint(res.Columns); got != tt.columns {
+			t.Errorf("%s: columns %s, want %s", tt.sql, got, tt.columns)
+		}
+		if got := fmt.Sprint(rows); got != tt.rows {
+			t.Errorf("%s: rows %s, want %s", tt.sql, got, tt.rows)
+		}
+	}
+}
+
+func TestComparisonRightArithmetic(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE u (id INT PRIMARY KEY, n INT, m INT)",
+		"INSERT INTO u VALUES (1, 5, 1)",
+		"INSERT INTO u VALUES (2, 150, 100)",
+		"INSERT INTO u VALUES (3, 250, 100)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		// used to run as n > 2
+		{"SELECT id FROM u WHERE n > 2 * 50", "[2 3]"},
+		{"SELECT id FROM u WHERE n > m * 2", "[1 3]"},
+		{"SELECT id FROM u WHERE n = m + 50 OR id = 1", "[1 2]"},
+		{"SELECT id FROM u WHERE id = 1 + 1", "[2]"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		var ids []interface{}
+		for _, row := range res.Rows {
+			ids = append(ids, row.Values[0].Val)
+		}
+		if got := fmt.Sprint(ids); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.sql, got, tt.want)
+		}
+	}
+
+	if _, err := e.Execute(ctx, "SELECT id FROM u WHERE n > SUM(m)"); err == nil {
+		t.Errorf("expected an error for an aggregate in WHERE")
+	}
+}
+
+func TestOverflowPromote(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir(), IntOverflow: OverflowPromote})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT)",
+		"INSERT INTO orders VALUES (1, 10)",
+		fmt.Sprintf("INSERT INTO orders VALUES (2, %d)", math.MaxInt-5),
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// MaxInt-5 + 10, past what an INT holds
+	past := new(big.Int).Add(big.NewInt(math.MaxInt), big.NewInt(5)).String()
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT SUM(amount) FROM orders", past},
+		{"SELECT amount + 10 FROM orders WHERE id = 2", past},
+		{"SELECT amount * 2 FROM orders WHERE id = 1", "20"},
+		{"SELECT id FROM orders WHERE amount * 2 > 100", "2"},
+		{"SELECT SUM(amount) FROM orders WHERE amount > 100000 * 100000 * 100000 * 100000", "NULL"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if len(res.Rows) != 1 {
+			t.Fatalf("%s: got %d rows, want 1", tt.sql, len(res.Rows))
+		}
+		if got := res.Rows[0].Values[0].String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.sql, got, tt.want)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT SUM(amount), amount * 2 FROM orders GROUP BY amount")
+	if err != nil {
+		t.Fatalf("grouped: %v", err)
+	}
+	for i, typ := range res.ColumnTypes {
+		if typ != types.TypeNumeric {
+			t.Errorf("column %s is %s, want NUMERIC", res.Columns[i], typ)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAutoIncrementPrimaryKey(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY AUTO_INCREMENT, amount INT, description TEXT)",
+		"INSERT INTO orders VALUES (250, 'Samosa')",          // id omitted -> 1
+		"INSERT INTO orders VALUES (NULL, 45, 'Chapati')",    // NULL id -> 2
+		"INSERT INTO orders VALUES (10, 120, 'Explicit')",    // explicit id moves the counter
+		"INSERT INTO orders VALUES (3500, 'After explicit')", // -> 11
+	}
+	wantIDs := []int{1, 2, 10, 11}
+	for i, sql := range stmts {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		// The result reports the id that was assigned
+		if i > 0 && res.LastInsertID.Val != wantIDs[i-1] {
+			t.Errorf("%s: LastInsertID = %v, want %d", sql, res.LastInsertID, wantIDs[i-1])
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT id FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != len(wantIDs) {
+		t.Fatalf("Expected %d rows, got %d", len(wantIDs), len(res.Rows))
+	}
+	for i, w := range wantIDs {
+		if got, _ := res.Rows[i].Values[0].AsInt(); got != w {
+			t.Errorf("Row %d: expected id %d, got %d", i, w, got)
+		}
+	}
+
+	// A fresh engine resumes the counter from disk.
+	e2 := NewEngineWithConfig(Config{DataDir: dir})
+	if _, err := e2.Execute(ctx, "INSERT INTO orders VALUES (1, 'Reloaded')"); err != nil {
+		t.Fatalf("Failed to insert after reload: %v", err)
+	}
+	res, err = e2.Execute(ctx, "SELECT * FROM orders WHERE id = 12")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != 1 {
+		t.Fatalf("Expected resumed id 12, got %d rows", len(res.Rows))
+	}
+	if desc, _ := res.Rows[0].Values[2].AsText(); desc != "Reloaded" {
+		t.Errorf("Expected description 'Reloaded', got %q", desc)
+	}
+}
+
+func TestAutoIncrementValidation(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE t (id TEXT PRIMARY KEY AUTO_INCREMENT)"); err == nil {
+		t.Errorf("Expected error for AUTO_INCREMENT on TEXT key")
+	}
+	if _, err := e.Execute(ctx, "CREATE TABLE plain (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := e.Execute(ctx, "INSERT INTO plain VALUES (NULL, 'x')"); err == nil {
+		t.Errorf("Expected NULL primary key to be rejected without AUTO_INCREMENT")
+	}
+	if _, err := e.Execute(ctx, "INSERT INTO plain VALUES ('x')"); err == nil {
+		t.Errorf("Expected missing primary key to be rejected without AUTO_INCREMENT")
+	}
+}
+
+func TestImplicitRowID(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE events (name TEXT, at INT)",
+		"INSERT INTO events VALUES ('start', 100)",
+		"INSERT INTO events VALUES ('stop', 200)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	def, err := e.TableDef("events")
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	if len(def.Columns) != 3 || def.Columns[0].Name != "rowid" || !def.Columns[0].IsPrimary {
+		t.Fatalf("Expected a rowid primary key first, got %+v", def.Columns)
+	}
+
+	res, err := e.Execute(ctx, "SELECT * FROM events WHERE rowid = 2")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if got := strings.Join(res.Columns, ","); got != "rowid,name,at" {
+		t.Errorf("Unexpected columns: %s", got)
+	}
+	if len(res.Rows) != 1 || res.Rows[0].Values[1].Val != "stop" {
+		t.Errorf("Expected the second row, got %v", res.Rows)
+	}
+
+	// The rowid survives a reload like any other column
+	reopened := NewEngineWithConfig(Config{DataDir: dir})
+	res, err = reopened.Execute(ctx, "SELECT rowid FROM events")
+	if err != nil || len(res.Rows) != 2 {
+		t.Fatalf("Expected 2 rowids after reload, got %v (%v)", res, err)
+	}
+
+	// The name is configurable and may not clash with a declared column
+	custom := NewEngineWithConfig(Config{DataDir: t.TempDir(), RowIDColumn: "_id"})
+	if _, err := custom.Execute(ctx, "CREATE TABLE logs (line TEXT)"); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	if def, _ := custom.TableDef("logs"); def.Columns[0].Name != "_id" {
+		t.Errorf("Expected an _id column, got %+v", def.Columns)
+	}
+	if _, err := e.Execute(ctx, "CREATE TABLE clash (rowid TEXT)"); err == nil {
+		t.Errorf("Expected error for a declared rowid column without a primary key")
+	}
+}
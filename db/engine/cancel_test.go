@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mini-rdbms/db/storage"
+	"testing"
+)
+
+func TestCancelAbortsScanAndJoin(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	setup := context.Background()
+	if _, err := e.Execute(setup, "CREATE TABLE items (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for i := 1; i <= 20; i++ {
+		sql := fmt.Sprintf("INSERT INTO items VALUES (%d, 'item%d')", i, i)
+		if _, err := e.Execute(setup, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	items := e.Tables["items"]
+
+	// Each plan cancels its context after visiting a few rows.
+	plans := map[string]func(cancel func()) PlanNode{
+		"scan": func(cancel func()) PlanNode {
+			return &ScanNode{Table: items, Predicate: cancelAfter(3, cancel)}
+		},
+		"join": func(cancel func()) PlanNode {
+			return &JoinNode{
+				Left:     &ScanNode{Table: items, Predicate: cancelAfter(3, cancel)},
+				Right:    &ScanNode{Table: items},
+				LeftCol:  "id",
+				RightCol: "id",
+			}
+		},
+	}
+	for name, build := range plans {
+		ctx, cancel := context.WithCancel(context.Background())
+		rows, err := materialize(ctx, build(cancel))
+		cancel()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v (%d rows)", name, err, len(rows))
+		}
+	}
+
+	// A deadline that has already passed fails the whole statement.
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	if _, err := e.Execute(ctx, "SELECT * FROM items"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// cancelAfter returns a predicate that accepts every row and calls cancel
+// once n rows have been seen.
+func cancelAfter(n int, cancel func()) func(storage.Row) bool {
+	seen := 0
+	return func(storage.Row) bool {
+		seen++
+		if seen == n {
+			cancel()
+		}
+		return true
+	}
+}
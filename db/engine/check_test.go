@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckConstraints(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE orders (id INT PRIMARY KEY, amount INT CHECK (amount > 0 AND amount <= 1000), note TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	if _, err := e.Execute(ctx, "INSERT INTO orders VALUES (1, 250, 'ok')"); err != nil {
+		t.Fatalf("Expected valid insert to pass: %v", err)
+	}
+
+	for _, sql := range []string{
+		"INSERT INTO orders VALUES (2, 0, 'zero')",
+		"INSERT INTO orders VALUES (3, 5000, 'too big')",
+	} {
+		_, err := e.Execute(ctx, sql)
+		if err == nil || !strings.Contains(err.Error(), "CHECK constraint failed for column amount") {
+			t.Errorf("%s: expected CHECK failure, got %v", sql, err)
+		}
+	}
+
+	// Updates are checked too, and a failed update leaves the row alone.
+	if _, err := e.Execute(ctx, "UPDATE orders SET amount = 0 WHERE id = 1"); err == nil {
+		t.Errorf("Expected CHECK failure on update")
+	}
+	res, err := e.Execute(ctx, "SELECT amount FROM orders WHERE id = 1")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if got, _ := res.Rows[0].Values[0].AsInt(); got != 250 {
+		t.Errorf("Expected amount to stay 250, got %d", got)
+	}
+
+	// The constraint is persisted with the schema.
+	e2 := NewEngineWithConfig(Config{DataDir: dir})
+	if _, err := e2.Execute(ctx, "INSERT INTO orders VALUES (4, -1, 'reloaded')"); err == nil {
+		t.Errorf("Expected CHECK to survive reload")
+	}
+
+	if _, err := e.Execute(ctx, "CREATE TABLE bad (id INT PRIMARY KEY, amount INT CHECK (total > 0))"); err == nil {
+		t.Errorf("Expected error for CHECK on unknown column")
+	}
+}
+
+func TestCheckOnQuotedKeywordColumn(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, `CREATE TABLE "order" (id INT PRIMARY KEY, "limit" INT CHECK ("limit" > 0))`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := e.Execute(ctx, `INSERT INTO "order" VALUES (1, 5)`); err != nil {
+		t.Fatalf("Expected valid insert to pass: %v", err)
+	}
+	_, err := e.Execute(ctx, `INSERT INTO "order" VALUES (2, 0)`)
+	if err == nil || !strings.Contains(err.Error(), "CHECK constraint failed for column limit") {
+		t.Errorf("expected CHECK failure, got %v", err)
+	}
+}
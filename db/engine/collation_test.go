@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWhereUsesColumnCollation(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE people (id INT PRIMARY KEY, name TEXT UNIQUE COLLATE NOCASE, nick TEXT)",
+		"INSERT INTO people VALUES (1, 'Alice', 'Ally')",
+		"INSERT INTO people VALUES (2, 'Bob', 'Bobby')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// NOCASE column matches regardless of case, even though it is indexed.
+	res, err := e.Execute(ctx, "SELECT * FROM people WHERE name = 'alice'")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != 1 {
+		t.Errorf("Expected 1 row for NOCASE match, got %d", len(res.Rows))
+	}
+
+	// Default (binary) column stays case-sensitive.
+	res, err = e.Execute(ctx, "SELECT * FROM people WHERE nick = 'ally'")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != 0 {
+		t.Errorf("Expected 0 rows for binary mismatch, got %d", len(res.Rows))
+	}
+
+	if _, err := e.Execute(ctx, "CREATE TABLE bad (id INT PRIMARY KEY COLLATE NOCASE)"); err == nil {
+		t.Errorf("Expected error for COLLATE on INT column")
+	}
+}
+
+func TestWhereCollateOverride(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE people (id INT PRIMARY KEY, name TEXT UNIQUE)",
+		"INSERT INTO people VALUES (1, 'Alice')",
+		"INSERT INTO people VALUES (2, 'bob')",
+		"INSERT INTO people VALUES (3, 'Carol')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		sql  string
+		want []int
+	}{
+		// Binary by default, so the unique index finds nothing
+		{"SELECT id FROM people WHERE name = 'alice'", nil},
+		{"SELECT id FROM people WHERE name = 'alice' COLLATE NOCASE", []int{1}},
+		// 'b' sorts after every upper-case letter in binary order only
+		{"SELECT id FROM people WHERE name > 'B'", []int{2, 3}},
+		{"SELECT id FROM people WHERE name > 'B' COLLATE NOCASE", []int{2, 3}},
+		{"SELECT id FROM people WHERE name < 'C'", []int{1}},
+		{"SELECT id FROM people WHERE name < 'C' COLLATE nocase", []int{1, 2}},
+		{"SELECT id FROM people WHERE name LIKE 'b%' COLLATE NOCASE", []int{2}},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		var got []int
+		for _, row := range res.Rows {
+			got = append(got, row.Values[0].Val.(int))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+
+	// DELETE and UPDATE take the same path
+	res, err := e.Execute(ctx, "DELETE FROM people WHERE name = 'CAROL' COLLATE NOCASE")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if res.RowsAffected != 1 {
+		t.Errorf("Expected 1 row deleted, got %d", res.RowsAffected)
+	}
+
+	if _, err := e.Execute(ctx, "SELECT id FROM people WHERE name = 'x' COLLATE KLINGON"); err == nil {
+		t.Errorf("Expected an error for an unknown collation")
+	}
+}
+
+func TestUniqueUsesColumnCollation(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE people (id INT PRIMARY KEY, name TEXT UNIQUE COLLATE NOCASE, visits INT)",
+		"CREATE TABLE tags (name TEXT PRIMARY KEY COLLATE NOCASE)",
+		"INSERT INTO people VALUES (1, 'Alice', 0)",
+		"INSERT INTO people VALUES (2, 'Bob', 0)",
+		"INSERT INTO tags VALUES ('Go')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	for _, sql := range []string{
+		"INSERT INTO people VALUES (3, 'alice', 0)",
+		"INSERT INTO tags VALUES ('GO')",
+		"UPDATE people SET name = 'ALICE' WHERE id = 2",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil || !strings.Contains(err.Error(), "duplicate") {
+			t.Errorf("%s: expected a duplicate error, got %v", sql, err)
+		}
+	}
+
+	// A row may change the case of its own value
+	if _, err := e.Execute(ctx, "UPDATE people SET name = 'ALICE' WHERE id = 1"); err != nil {
+		t.Fatalf("recasing own value: %v", err)
+	}
+	// ON CONFLICT finds the row under the collation too
+	if _, err := e.Execute(ctx, "INSERT INTO people VALUES (4, 'bob', 0) ON CONFLICT (name) DO UPDATE SET visits = 1"); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	res, err := e.Execute(ctx, "SELECT id, name, visits FROM people")
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	var rows [][]interface{}
+	for _, row := range res.Rows {
+		rows = append(rows, []interface{}{row.Values[0].Val, row.Values[1].Val, row.Values[2].Val})
+	}
+	if got, want := fmt.Sprint(rows), "[[1 ALICE 0] [2 Bob 1]]"; got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+
+	if _, err := e.Execute(ctx, "DELETE FROM people WHERE id = 1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := e.Execute(ctx, "INSERT INTO people VALUES (5, 'alice', 0)"); err != nil {
+		t.Errorf("the deleted row's value should be free again: %v", err)
+	}
+}
+
+func TestCollateOverrideOnFoldedUniqueIndex(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE people (id INT PRIMARY KEY, name TEXT UNIQUE COLLATE NOCASE)",
+		"INSERT INTO people VALUES (1, 'Alice')",
+		"INSERT INTO people VALUES (2, 'Bob')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// The unique index keys 'alice'; a BINARY comparison must not use it
+	tests := []struct {
+		cond string
+		want string
+	}{
+		{"name = 'alice' COLLATE BINARY", "[]"},
+		{"name = 'alice' COLLATE BINARY AND id = 1", "[]"},
+		{"name = 'Alice' COLLATE BINARY", "[1]"},
+		{"name = 'alice'", "[1]"},
+		{"name = 'ALICE' COLLATE NOCASE", "[1]"},
+		{"name LIKE 'A%' COLLATE BINARY", "[1]"},
+		{"name >= 'B' COLLATE BINARY", "[2]"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, "SELECT id FROM people WHERE "+tt.cond)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.cond, err)
+		}
+		var ids []interface{}
+		for _, row := range res.Rows {
+			ids = append(ids, row.Values[0].Val)
+		}
+		if got := fmt.Sprint(ids); got != tt.want {
+			t.Errorf("WHERE %s: got %s, want %s", tt.cond, got, tt.want)
+		}
+	}
+
+	res, err := e.Execute(ctx, "DELETE FROM people WHERE name = 'alice' COLLATE BINARY")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if res.RowsAffected != 0 {
+		t.Errorf("deleted %d rows, want 0", res.RowsAffected)
+	}
+}
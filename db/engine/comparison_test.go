@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWhereComparisonOperators(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE nums (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := e.Tables["nums"].Insert(intText(i, "")[:1]); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	tests := map[string]int{
+		"SELECT * FROM nums WHERE id < 3":            2,
+		"SELECT * FROM nums WHERE id <= 3":           3,
+		"SELECT * FROM nums WHERE id > 3":            2,
+		"SELECT * FROM nums WHERE id >= 3":           3,
+		"SELECT * FROM nums WHERE id > 1 AND id < 5": 3,
+		"SELECT * FROM nums WHERE id >= 10":          0,
+	}
+	for sql, want := range tests {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(res.Rows) != want {
+			t.Errorf("%s: expected %d rows, got %d", sql, want, len(res.Rows))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Run with -race: statements on different goroutines must not race on the
+// engine's table map.
+func TestConcurrentCreateAndSelect(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+	if _, err := e.Execute(ctx, "CREATE TABLE base (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	// A second engine loads base lazily while the others are created
+	e = NewEngineWithConfig(Config{DataDir: dir})
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*2)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			sql := fmt.Sprintf("CREATE TABLE t%d (id INT PRIMARY KEY)", i)
+			if _, err := e.Execute(ctx, sql); err != nil {
+				errs <- fmt.Errorf("%s: %w", sql, err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for _, sql := range []string{"SELECT * FROM base", "SELECT * FROM information_schema.tables"} {
+				if _, err := e.Execute(ctx, sql); err != nil {
+					errs <- fmt.Errorf("%s: %w", sql, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	names, err := e.TableNames()
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+	if len(names) != workers+1 {
+		t.Errorf("Expected %d tables, got %v", workers+1, names)
+	}
+}
+
+// Run with -race: with RowLocks, UPDATEs of unindexed columns run alongside
+// scans of the same table.
+func TestRowLocksUpdateDuringSelect(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir(), RowLocks: true, DeferWrites: true})
+	ctx := context.Background()
+	if _, err := e.Execute(ctx, "CREATE TABLE items (id INT PRIMARY KEY, qty INT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	const rows = 20
+	for i := 0; i < rows; i++ {
+		if _, err := e.Execute(ctx, fmt.Sprintf("INSERT INTO items VALUES (%d, 0)", i)); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, rows*2)
+	for i := 0; i < rows; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			sql := fmt.Sprintf("UPDATE items SET qty = %d WHERE id = %d", i+1, i)
+			if _, err := e.Execute(ctx, sql); err != nil {
+				errs <- fmt.Errorf("%s: %w", sql, err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			res, err := e.Execute(ctx, "SELECT * FROM items")
+			if err != nil {
+				errs <- err
+			} else if len(res.Rows) != rows {
+				errs <- fmt.Errorf("SELECT saw %d rows, want %d", len(res.Rows), rows)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	res, err := e.Execute(ctx, "SELECT SUM(qty) FROM items")
+	if err != nil {
+		t.Fatalf("Failed to sum: %v", err)
+	}
+	if got := res.Rows[0].Values[0].Val; got != rows*(rows+1)/2 {
+		t.Errorf("Expected every update to land, sum = %v", got)
+	}
+}
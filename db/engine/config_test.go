@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/storage"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEngineConfiguredDataDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "custom")
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	if e.DataDir() != dir {
+		t.Fatalf("Expected data dir %s, got %s", dir, e.DataDir())
+	}
+
+	if _, err := e.Execute(ctx, "CREATE TABLE items (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := e.Execute(ctx, "INSERT INTO items VALUES (1, 'pen')"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "items.json")); err != nil {
+		t.Fatalf("Expected table file in configured dir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("data", "items.json")); err == nil {
+		t.Errorf("Table file should not be written to the default data dir")
+	}
+
+	// A second engine on the same dir sees the persisted rows.
+	e2 := NewEngineWithConfig(Config{DataDir: dir})
+	if _, err := e2.Execute(ctx, "INSERT INTO items VALUES (2, 'ink')"); err != nil {
+		t.Fatalf("Failed to insert via second engine: %v", err)
+	}
+	if len(e2.Tables["items"].Rows) != 2 {
+		t.Errorf("Expected 2 rows after reload, got %d", len(e2.Tables["items"].Rows))
+	}
+}
+
+func TestNewEngineDefaultsDataDir(t *testing.T) {
+	e := NewEngine()
+	if e.DataDir() != "data" {
+		t.Errorf("Expected default data dir 'data', got %s", e.DataDir())
+	}
+}
+
+func TestStorageFormatGob(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// A gob engine converts the JSON table the next time it saves it
+	e = NewEngineWithConfig(Config{DataDir: dir, StorageFormat: storage.FormatGob})
+	for _, sql := range []string{
+		"INSERT INTO users VALUES (2, 'Ben')",
+		"CREATE TABLE tags (id INT PRIMARY KEY)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	for _, name := range []string{"users", "tags"} {
+		if _, err := os.Stat(filepath.Join(dir, name+".gob")); err != nil {
+			t.Errorf("Expected %s.gob: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, name+".json")); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s.json, got %v", name, err)
+		}
+	}
+
+	// Without a format set, tables load from whichever file there is
+	reloaded := NewEngineWithConfig(Config{DataDir: dir})
+	res, err := reloaded.Execute(ctx, "SELECT name FROM users WHERE id = 2")
+	if err != nil || len(res.Rows) != 1 || res.Rows[0].Values[0].Val != "Ben" {
+		t.Fatalf("Expected Ben after reload, got %v (%v)", res, err)
+	}
+	res, err = reloaded.Execute(ctx, "SELECT table_name FROM information_schema.tables")
+	if err != nil || len(res.Rows) != 2 {
+		t.Errorf("Expected 2 tables, got %v (%v)", res, err)
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"strconv"
+)
+
+// WriteCSV writes the result as CSV: a header row of column names, then one
+// record per row with each value rendered by Value.String. Fields containing
+// commas, quotes or newlines are quoted.
+func (r *ResultSet) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(r.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(r.Columns))
+	for _, row := range r.Rows {
+		for i, v := range row.Values {
+			record[i] = v.String()
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportResult reports how a CSV import went.
+type ImportResult struct {
+	Imported int
+	Failed   []ImportFailure
+}
+
+// ImportFailure is a CSV record that could not be inserted. Line counts the
+// header as line 1.
+type ImportFailure struct {
+	Line int
+	Err  error
+}
+
+// ImportCSV inserts the records of a CSV file into an existing table. The
+// header row names the columns, in any order; every column must appear
+// except an AUTO_INCREMENT primary key. Each record is converted to the
+// column types and inserted with the usual constraint checks. Records that
+// fail are reported in the result and skipped; the rest are saved once at
+// the end. An empty INT cell is NULL, so an AUTO_INCREMENT key can be left
+// blank.
+func (e *Engine) ImportCSV(ctx context.Context, tableName string, r io.Reader) (*ImportResult, error) {
+	table, err := e.getTable(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	// positions[i] is the header field holding column i, or -1
+	positions := make([]int, len(table.Def.Columns))
+	for i := range positions {
+		positions[i] = -1
+	}
+	for field, name := range header {
+		i := table.Def.GetColumnIndex(name)
+		if i == -1 {
+			return nil, fmt.Errorf("column not found: %s", name)
+		}
+		if positions[i] != -1 {
+			return nil, fmt.Errorf("column %s appears twice in CSV header", name)
+		}
+		positions[i] = field
+	}
+	for i, col := range table.Def.Columns {
+		if positions[i] == -1 && !(col.IsPrimary && col.AutoIncrement) {
+			return nil, fmt.Errorf("CSV header is missing column %s", col.Name)
+		}
+	}
+
+	result := &ImportResult{}
+	for line := 2; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		values, err := csvValues(table, positions, record)
+		if err == nil {
+			_, err = e.addRow(table, values)
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, ImportFailure{Line: line, Err: err})
+			continue
+		}
+		result.Imported++
+	}
+
+	if result.Imported > 0 {
+		if err := e.persist(table); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// csvValues converts one CSV record into a row for table.
+func csvValues(table *storage.Table, positions []int, record []string) ([]types.Value, error) {
+	values := make([]types.Value, len(table.Def.Columns))
+	for i, col := range table.Def.Columns {
+		if positions[i] == -1 {
+			values[i] = types.NewNull(col.Type)
+			continue
+		}
+		cell := record[positions[i]]
+		switch col.Type {
+		case types.TypeInt:
+			if cell == "" {
+				values[i] = types.NewNull(col.Type)
+				continue
+			}
+			n, err := strconv.Atoi(cell)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: invalid INT %q", col.Name, cell)
+			}
+			values[i] = types.NewInt(n)
+		case types.TypeDate:
+			if cell == "" {
+				values[i] = types.NewNull(col.Type)
+				continue
+			}
+			d, err := types.ParseDate(cell)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.Name, err)
+			}
+			values[i] = d
+		default:
+			values[i] = types.NewText(cell)
+		}
+	}
+	return values, nil
+}
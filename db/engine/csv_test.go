@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"mini-rdbms/db/types"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT, note TEXT)",
+		"INSERT INTO users VALUES (1, 'Alice', 'plain')",
+		"INSERT INTO users VALUES (2, 'Smith, Bob', 'has, comma')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	if _, err := e.ExecutePrepared(ctx, "INSERT INTO users VALUES (3, ?, ?)", types.NewText(`O'Brien "Ob"`), types.NewText("line\nbreak")); err != nil {
+		t.Fatalf("Failed to insert quoted row: %v", err)
+	}
+
+	res, err := e.Execute(ctx, "SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := res.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to re-parse CSV: %v\n%s", err, buf.String())
+	}
+	want := [][]string{
+		{"id", "name", "note"},
+		{"1", "Alice", "plain"},
+		{"2", "Smith, Bob", "has, comma"},
+		{"3", `O'Brien "Ob"`, "line\nbreak"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Expected %d records, got %d: %q", len(want), len(records), records)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("Record %d field %d: expected %q, got %q", i, j, want[i][j], records[i][j])
+			}
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE users (id INT PRIMARY KEY AUTO_INCREMENT, name TEXT UNIQUE, age INT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// Columns in a different order than the table, with an omitted id on
+	// line 4 and two bad records (line 5 duplicate name, line 6 bad INT).
+	input := "age,name,id\n" +
+		"30,\"Smith, Ann\",1\n" +
+		"41,Bob,2\n" +
+		"25,Cy,\n" +
+		"50,Bob,9\n" +
+		"old,Dee,10\n"
+	res, err := e.ImportCSV(ctx, "users", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ImportCSV: %v", err)
+	}
+	if res.Imported != 3 {
+		t.Errorf("Expected 3 rows imported, got %d", res.Imported)
+	}
+	if len(res.Failed) != 2 || res.Failed[0].Line != 5 || res.Failed[1].Line != 6 {
+		t.Fatalf("Expected failures on lines 5 and 6, got %+v", res.Failed)
+	}
+
+	// Reload from disk to confirm the rows were saved.
+	e2 := NewEngineWithConfig(Config{DataDir: dir})
+	sel, err := e2.Execute(ctx, "SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	want := [][]interface{}{{1, "Smith, Ann", 30}, {2, "Bob", 41}, {3, "Cy", 25}}
+	if len(sel.Rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %v", len(want), sel.Rows)
+	}
+	for i, row := range sel.Rows {
+		for j, v := range row.Values {
+			if v.Val != want[i][j] {
+				t.Errorf("Row %d column %d: expected %v, got %v", i, j, want[i][j], v.Val)
+			}
+		}
+	}
+
+	for _, header := range []string{"id,name\n", "id,name,age,email\n", "id,name,name,age\n"} {
+		if _, err := e.ImportCSV(ctx, "users", strings.NewReader(header)); err == nil {
+			t.Errorf("Expected header %q to be rejected", strings.TrimSpace(header))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateColumns(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	e := NewEngineWithConfig(Config{DataDir: dir, Clock: func() time.Time { return now }})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, created_at DATE, shipped DATE ON UPDATE CURRENT_TIMESTAMP, INDEX (created_at))",
+		"INSERT INTO orders VALUES (1, DATE '2024-03-15', DATE '2024-03-16')",
+		"INSERT INTO orders VALUES (2, DATE '2023-12-31', DATE '2024-01-02')",
+		"INSERT INTO orders VALUES (3, DATE '2024-01-01', DATE '2024-01-01')",
+		"CREATE TABLE days (day DATE PRIMARY KEY, note TEXT)",
+		"INSERT INTO days VALUES (DATE '2024-02-01', 'b')",
+		"INSERT INTO days VALUES (DATE '2023-11-30', 'a')",
+		"INSERT INTO days VALUES (DATE '2024-10-05', 'c')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	query := func(e *Engine, sql string) string {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var out []string
+		for _, row := range res.Rows {
+			for _, v := range row.Values {
+				out = append(out, v.String())
+			}
+		}
+		return fmt.Sprint(out)
+	}
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT id FROM orders WHERE created_at >= DATE '2024-01-01'", "[1 3]"},
+		{"SELECT id FROM orders WHERE created_at < DATE '2024-01-01'", "[2]"},
+		{"SELECT id FROM orders WHERE created_at = DATE '2024-03-15'", "[1]"},
+		{"SELECT id FROM orders WHERE created_at BETWEEN DATE '2023-12-31' AND DATE '2024-01-01'", "[2 3]"},
+		{"SELECT id FROM orders WHERE shipped > created_at", "[1 2]"},
+		// Scans return rows in primary key order, which for a DATE key is by day
+		{"SELECT day, note FROM days", "[2023-11-30 a 2024-02-01 b 2024-10-05 c]"},
+		{"SELECT created_at, COUNT(id) FROM orders GROUP BY created_at", "[2023-12-31 1 2024-01-01 1 2024-03-15 1]"},
+	}
+	for _, tt := range tests {
+		if got := query(e, tt.sql); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.sql, got, tt.want)
+		}
+	}
+
+	if _, err := e.Execute(ctx, "INSERT INTO orders VALUES (4, '2024-01-01', DATE '2024-01-01')"); err == nil {
+		t.Errorf("Expected TEXT in a DATE column to be rejected")
+	}
+
+	// ON UPDATE CURRENT_TIMESTAMP stores today's date in a DATE column
+	if _, err := e.Execute(ctx, "UPDATE orders SET created_at = DATE '2024-04-30' WHERE id = 2"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if got := query(e, "SELECT created_at, shipped FROM orders WHERE id = 2"); got != "[2024-04-30 2024-05-01]" {
+		t.Errorf("After update: got %s", got)
+	}
+
+	res, err := e.Execute(ctx, "SELECT id, created_at FROM orders WHERE id = 1")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if data, err := res.ToJSON(); err != nil || string(data) != `[{"id":1,"created_at":"2024-03-15"}]` {
+		t.Errorf("Unexpected JSON %s (%v)", data, err)
+	}
+
+	// Dates survive a reload, and the index on them is rebuilt
+	reloaded := NewEngineWithConfig(Config{DataDir: dir})
+	if got := query(reloaded, "SELECT id FROM orders WHERE created_at >= DATE '2024-01-01'"); got != "[1 2 3]" {
+		t.Errorf("After reload: got %s", got)
+	}
+	if got := query(reloaded, "SELECT note FROM days WHERE day = DATE '2024-02-01'"); got != "[b]" {
+		t.Errorf("After reload: got %s", got)
+	}
+}
+
+func TestDateComparedWithText(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, d DATE, raw DATE, INDEX (d))",
+		"INSERT INTO orders VALUES (1, DATE '2024-01-02', DATE '2024-01-02')",
+		"INSERT INTO orders VALUES (2, DATE '2024-03-04', DATE '2024-03-04')",
+		"CREATE TABLE events (id INT PRIMARY KEY, day DATE CHECK (day >= '2024-01-01'))",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// A TEXT value against a DATE column reads as a date, with or without
+	// an index to look it up in
+	for _, sql := range []string{
+		"SELECT id FROM orders WHERE d = '2024-01-02'",
+		"SELECT id FROM orders WHERE raw = '2024-01-02'",
+		"SELECT id FROM orders WHERE d < '2024-02-01'",
+		"SELECT id FROM orders WHERE raw BETWEEN '2024-01-01' AND '2024-02-01'",
+	} {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if got := fmt.Sprint(res.Rows); got != "[{[1]}]" {
+			t.Errorf("%s: expected row 1, got %s", sql, got)
+		}
+	}
+	res, err := e.ExecutePrepared(ctx, "SELECT id FROM orders WHERE d = ?", types.NewText("2024-03-04"))
+	if err != nil || fmt.Sprint(res.Rows) != "[{[2]}]" {
+		t.Errorf("Expected row 2 for a bound TEXT argument, got %v (%v)", res, err)
+	}
+	if _, err := e.Execute(ctx, "UPDATE orders SET raw = DATE '2025-01-01' WHERE d = '2024-03-04'"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if row, _ := e.Tables["orders"].GetRow(2); row.Values[2].String() != "2025-01-01" {
+		t.Errorf("Expected the UPDATE to find row 2, got %v", row.Values)
+	}
+
+	// CHECK constraints compare the same way
+	if _, err := e.Execute(ctx, "INSERT INTO events VALUES (1, DATE '2024-06-01')"); err != nil {
+		t.Errorf("Expected a date after the CHECK bound to pass: %v", err)
+	}
+	if _, err := e.Execute(ctx, "INSERT INTO events VALUES (2, DATE '2023-06-01')"); err == nil {
+		t.Errorf("Expected a date before the CHECK bound to fail")
+	}
+
+	// Text that is not a date is an error, not an empty result
+	for _, sql := range []string{
+		"SELECT id FROM orders WHERE d = 'yesterday'",
+		"DELETE FROM orders WHERE raw > '2024-13-01'",
+		"CREATE TABLE bad (id INT PRIMARY KEY, day DATE CHECK (day > 'soon'))",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil || !strings.Contains(err.Error(), "invalid DATE") {
+			t.Errorf("%s: expected an invalid DATE error, got %v", sql, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeleteAllRows(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, email TEXT UNIQUE, city TEXT, INDEX (city))",
+		"INSERT INTO users VALUES (1, 'a@x.com', 'Nairobi')",
+		"INSERT INTO users VALUES (2, 'b@x.com', 'Mombasa')",
+		"INSERT INTO users VALUES (3, 'c@x.com', 'Nairobi')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "DELETE FROM users")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if res.Message != "Deleted 3 rows" {
+		t.Errorf("Unexpected message: %s", res.Message)
+	}
+
+	tbl := e.Tables["users"]
+	if n := tbl.RowCount(); n != 0 {
+		t.Errorf("Expected empty table, got %d rows", n)
+	}
+	if n := len(tbl.Indices["email"].Data); n != 0 {
+		t.Errorf("Expected empty unique index, got %d entries", n)
+	}
+	if n := len(tbl.SecondaryIndices["city"].Data); n != 0 {
+		t.Errorf("Expected empty secondary index, got %d entries", n)
+	}
+
+	// Freed unique values can be reused.
+	if _, err := e.Execute(ctx, "INSERT INTO users VALUES (4, 'a@x.com', 'Kisumu')"); err != nil {
+		t.Errorf("Failed to reuse email after delete-all: %v", err)
+	}
+}
+
+func TestDeleteWhereFunction(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, email TEXT UNIQUE, name TEXT)",
+		"INSERT INTO users VALUES (1, '', 'Alice')",
+		"INSERT INTO users VALUES (2, 'b@x.com', 'Bob')",
+		"INSERT INTO users VALUES (3, 'c', 'Émile')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "DELETE FROM users WHERE LENGTH(email) = 0")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if res.RowsAffected != 1 {
+		t.Errorf("Expected 1 row deleted, got %d", res.RowsAffected)
+	}
+
+	// LENGTH counts characters, not bytes
+	res, err = e.Execute(ctx, "SELECT id FROM users WHERE LENGTH(name) + 1 = 6 AND LENGTH(email) < 2")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != 1 || res.Rows[0].Values[0].Val != 3 {
+		t.Errorf("Expected only row 3, got %v", res.Rows)
+	}
+
+	for _, sql := range []string{
+		"DELETE FROM users WHERE NOPE(email) = 0",
+		"DELETE FROM users WHERE LENGTH(missing) = 0",
+		"DELETE FROM users WHERE COUNT(id) = 1",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected an error", sql)
+		}
+	}
+	if n := e.Tables["users"].RowCount(); n != 2 {
+		t.Errorf("Expected 2 rows left, got %d", n)
+	}
+}
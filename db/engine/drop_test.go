@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDropAll(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT)",
+		"INSERT INTO users VALUES (1, 'Alice')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// A stray temp file from an interrupted save, and an unrelated file.
+	if err := os.WriteFile(filepath.Join(dir, "tmp-123.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.DropAll(); err != nil {
+		t.Fatalf("DropAll failed: %v", err)
+	}
+
+	if len(e.Tables) != 0 {
+		t.Errorf("Expected no tables in memory, got %d", len(e.Tables))
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(matches) != 0 {
+		t.Errorf("Expected all table files removed, found %v", matches)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("Unrelated file was removed: %v", err)
+	}
+	if _, err := e.Execute(ctx, "SELECT * FROM users"); err == nil {
+		t.Errorf("Expected users to be gone")
+	}
+}
+
+func TestDropDatabaseRequiresFlag(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	if _, err := e.Execute(ctx, "CREATE TABLE users (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if _, err := e.Execute(ctx, "DROP DATABASE"); err == nil {
+		t.Fatalf("Expected DROP DATABASE to be refused without the flag")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users.json")); err != nil {
+		t.Fatalf("Refused DROP DATABASE still removed files: %v", err)
+	}
+
+	e = NewEngineWithConfig(Config{DataDir: dir, AllowDropDatabase: true})
+	res, err := e.Execute(ctx, "DROP DATABASE")
+	if err != nil {
+		t.Fatalf("DROP DATABASE failed: %v", err)
+	}
+	if res.Message != "Database dropped" {
+		t.Errorf("Unexpected message: %s", res.Message)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected users.json to be removed, got %v", err)
+	}
+}
@@ -84,3 +84,85 @@ func TestEngineIntegration(t *testing.T) {
 		t.Errorf("Expected 1 row, got %d", len(res.Rows))
 	}
 }
+
+func TestExecuteEmptyInput(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	for _, sql := range []string{"", "   \n\t", "-- nothing here", "/* nor here */"} {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Errorf("%q: expected no error, got %v", sql, err)
+			continue
+		}
+		if res == nil || len(res.Rows) != 0 || len(res.Columns) != 0 || res.Message != "" {
+			t.Errorf("%q: expected empty result, got %+v", sql, res)
+		}
+	}
+
+	// Comments around a real statement are ignored.
+	res, err := e.Execute(ctx, "-- make a table\nCREATE TABLE t (id INT PRIMARY KEY) /* done */")
+	if err != nil {
+		t.Fatalf("Failed to create with comments: %v", err)
+	}
+	if res.Message != "Table t created" {
+		t.Errorf("Unexpected message: %s", res.Message)
+	}
+}
+
+func TestQuotedKeywordIdentifiers(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE \"order\" (id INT PRIMARY KEY, `select` TEXT, \"limit\" INT, INDEX (\"limit\"))",
+		"INSERT INTO `order` VALUES (1, 'a', 10)",
+		"INSERT INTO \"order\" VALUES (2, 'b', 20)",
+		"UPDATE \"order\" SET \"select\" = 'c' WHERE \"limit\" = 20",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT \"order\".`select`, \"limit\" FROM \"order\" WHERE \"limit\" > 5 AND `select` = 'c'")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Columns) != 2 || res.Columns[0] != "order.select" || res.Columns[1] != "limit" {
+		t.Errorf("Unexpected columns: %v", res.Columns)
+	}
+	if len(res.Rows) != 1 || res.Rows[0].Values[0].Val != "c" || res.Rows[0].Values[1].Val != 20 {
+		t.Errorf("Expected one row (c, 20), got %v", res.Rows)
+	}
+
+	if _, err := e.Execute(ctx, "SELECT select FROM \"order\""); err == nil {
+		t.Errorf("Expected an unquoted keyword column to fail to parse")
+	}
+}
+
+func TestCreateTableRejectsAmbiguousColumns(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"CREATE TABLE t (id INT PRIMARY KEY, id TEXT)", "duplicate column name: id"},
+		{"CREATE TABLE t (id INT PRIMARY KEY, name TEXT, name INT)", "duplicate column name: name"},
+		{"CREATE TABLE t (id INT PRIMARY KEY, code TEXT PRIMARY KEY)", "multiple primary keys for table t: id and code"},
+	}
+	for _, tt := range tests {
+		_, err := e.Execute(ctx, tt.sql)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: expected %q, got %v", tt.sql, tt.want, err)
+		}
+	}
+	if _, ok := e.Table("t"); ok {
+		t.Errorf("Expected no table to be created")
+	}
+
+	if _, err := e.Execute(ctx, "CREATE TABLE t (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create a valid table: %v", err)
+	}
+}
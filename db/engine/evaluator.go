@@ -1,46 +1,371 @@
 package engine
 
 import (
+	"fmt"
 	"mini-rdbms/db/parser"
 	"mini-rdbms/db/schema"
 	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-// Evaluate returns true if the row satisfies the expression.
+// evaluator evaluates expressions under one Config.IntOverflow setting. The
+// zero value makes INT overflow an error.
+type evaluator struct {
+	promote bool             // OverflowPromote: arithmetic and SUM produce NUMERIC
+	clock   func() time.Time // read by NOW(); nil means time.Now
+}
+
+// now returns the current time by v's clock.
+func (v evaluator) now() time.Time {
+	if v.clock == nil {
+		return time.Now()
+	}
+	return v.clock()
+}
+
+// Evaluate returns true if the row satisfies the expression. A condition
+// that is unknown for the row, such as a comparison with NULL, is not
+// satisfied.
 func Evaluate(expr parser.Expression, row storage.Row, def schema.TableDef) bool {
+	return evaluator{}.matches(expr, row, def)
+}
+
+// matches is Evaluate under v's overflow setting.
+func (v evaluator) matches(expr parser.Expression, row storage.Row, def schema.TableDef) bool {
+	return v.truth(expr, row, def) == truthTrue
+}
+
+// truth is the value of a condition in SQL's three-valued logic.
+type truth int
+
+const (
+	truthFalse truth = iota
+	truthTrue
+	// truthUnknown is the result of a comparison with NULL or between values
+	// that cannot be compared. NOT leaves it unknown, so neither a condition
+	// nor its negation selects the row.
+	truthUnknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+// truth evaluates a condition for row.
+func (v evaluator) truth(expr parser.Expression, row storage.Row, def schema.TableDef) truth {
 	if expr == nil {
-		return true
+		return truthTrue
 	}
 
 	switch e := expr.(type) {
 	case *parser.ComparisonExpression:
-		idx := def.GetColumnIndex(e.Column)
-		if idx == -1 {
-			return false
-		} // Error?
-		val := row.Values[idx]
+		val, coll, err := v.comparisonLeft(e, row, def)
+		if err != nil || val.IsNull() {
+			return truthUnknown
+		}
+
+		if e.Operator == "LIKE" {
+			text, ok := val.Val.(string)
+			pattern, isText := e.Value.Val.(string)
+			if !ok || !isText {
+				return truthUnknown
+			}
+			if coll == types.CollationNoCase {
+				text, pattern = strings.ToLower(text), strings.ToLower(pattern)
+			}
+			return truthOf(likeMatch(text, pattern))
+		}
+
+		other := e.Value
+		if e.Right != nil {
+			ridx, err := def.ResolveColumn(e.Right.Table, e.Right.Name)
+			if err != nil {
+				return truthUnknown
+			}
+			other = row.Values[ridx]
+		}
+		if e.RightExpr != nil {
+			if other, err = v.scalar(e.RightExpr, row, def); err != nil {
+				return truthUnknown
+			}
+		}
+		if other.IsNull() {
+			return truthUnknown
+		}
 
+		cmp, err := val.CompareCollated(other, coll)
+		if err != nil {
+			return truthUnknown
+		}
 		switch e.Operator {
 		case "=":
-			cmp, _ := val.Compare(e.Value)
-			return cmp == 0
-		// Add >, < later
+			return truthOf(cmp == 0)
+		case "!=":
+			return truthOf(cmp != 0)
+		case "<":
+			return truthOf(cmp < 0)
+		case ">":
+			return truthOf(cmp > 0)
+		case "<=":
+			return truthOf(cmp <= 0)
+		case ">=":
+			return truthOf(cmp >= 0)
 		default:
-			return false
+			return truthUnknown
+		}
+
+	case *parser.NotExpression:
+		switch v.truth(e.Expr, row, def) {
+		case truthTrue:
+			return truthFalse
+		case truthFalse:
+			return truthTrue
 		}
+		return truthUnknown
 
 	case *parser.InfixExpression:
-		left := Evaluate(e.Left, row, def)
-		right := Evaluate(e.Right, row, def)
+		left := v.truth(e.Left, row, def)
+		right := v.truth(e.Right, row, def)
 
 		switch e.Operator {
 		case "AND":
-			return left && right
+			if left == truthFalse || right == truthFalse {
+				return truthFalse
+			}
+			if left == truthTrue && right == truthTrue {
+				return truthTrue
+			}
+			return truthUnknown
 		case "OR":
-			return left || right
+			if left == truthTrue || right == truthTrue {
+				return truthTrue
+			}
+			if left == truthFalse && right == truthFalse {
+				return truthFalse
+			}
+			return truthUnknown
+		}
+	}
+	return truthUnknown
+}
+
+// comparisonLeft returns the left side of a comparison for row and the
+// collation to compare it with: an explicit COLLATE, else the column's.
+// Computed values default to binary collation.
+func (v evaluator) comparisonLeft(e *parser.ComparisonExpression, row storage.Row, def schema.TableDef) (types.Value, types.Collation, error) {
+	if e.Left != nil {
+		val, err := v.scalar(e.Left, row, def)
+		return val, comparisonCollation(e, types.CollationBinary), err
+	}
+	idx, err := def.ResolveColumn(e.Table, e.Column)
+	if err != nil {
+		return types.Value{}, "", err
+	}
+	return row.Values[idx], comparisonCollation(e, def.Columns[idx].Collation), nil
+}
+
+// comparisonCollation returns the collation a comparison uses on a column
+// with collation coll.
+func comparisonCollation(e *parser.ComparisonExpression, coll types.Collation) types.Collation {
+	if e.Collation != "" {
+		return e.Collation
+	}
+	return coll
+}
+
+// likeMatch reports whether s matches a LIKE pattern, where % matches any
+// run of characters and _ matches exactly one.
+func likeMatch(s, pattern string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '%':
+			rest := pattern[1:]
+			for i := range s {
+				if likeMatch(s[i:], rest) {
+					return true
+				}
+			}
+			return likeMatch("", rest)
+		case '_':
+			if s == "" {
+				return false
+			}
+			_, size := utf8.DecodeRuneInString(s)
+			s, pattern = s[size:], pattern[1:]
 		default:
-			return false
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+			s, pattern = s[1:], pattern[1:]
+		}
+	}
+	return s == ""
+}
+
+// likePrefix returns the literal text a LIKE pattern's matches must start
+// with, up to its first wildcard.
+func likePrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "%_"); i != -1 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// ReferencedColumns lists the columns an expression reads, in order of appearance.
+func ReferencedColumns(expr parser.Expression) []parser.ColumnRef {
+	switch e := expr.(type) {
+	case *parser.ComparisonExpression:
+		refs := []parser.ColumnRef{{Table: e.Table, Name: e.Column}}
+		if e.Left != nil {
+			refs = ReferencedColumns(e.Left)
+		}
+		if e.Right != nil {
+			refs = append(refs, *e.Right)
+		}
+		if e.RightExpr != nil {
+			refs = append(refs, ReferencedColumns(e.RightExpr)...)
+		}
+		return refs
+	case *parser.ColumnRef:
+		return []parser.ColumnRef{*e}
+	case *parser.InfixExpression:
+		return append(ReferencedColumns(e.Left), ReferencedColumns(e.Right)...)
+	case *parser.NotExpression:
+		return ReferencedColumns(e.Expr)
+	case *parser.FunctionCall:
+		var refs []parser.ColumnRef
+		for _, a := range e.Args {
+			refs = append(refs, ReferencedColumns(a)...)
+		}
+		return refs
+	}
+	return nil
+}
+
+// EvaluateScalar computes a value-producing expression (column, literal,
+// function call or arithmetic) for one row.
+func EvaluateScalar(expr parser.Expression, row storage.Row, def schema.TableDef) (types.Value, error) {
+	return evaluator{}.scalar(expr, row, def)
+}
+
+// scalar is EvaluateScalar under v's overflow setting.
+func (v evaluator) scalar(expr parser.Expression, row storage.Row, def schema.TableDef) (types.Value, error) {
+	switch e := expr.(type) {
+	case *parser.ColumnRef:
+		idx, err := def.ResolveColumn(e.Table, e.Name)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return row.Values[idx], nil
+	case *parser.Literal:
+		return e.Value, nil
+	case *parser.FunctionCall:
+		if isAggregate(e) {
+			// Above a GroupByNode each aggregate's result is a column
+			idx, err := aggregateColumn(e, def)
+			if err != nil {
+				return types.Value{}, err
+			}
+			return row.Values[idx], nil
+		}
+		fn, ok := scalarFunctions[e.Name]
+		if !ok {
+			return types.Value{}, fmt.Errorf("unknown function: %s", e.Name)
+		}
+		args := make([]types.Value, len(e.Args))
+		for i, a := range e.Args {
+			arg, err := v.scalar(a, row, def)
+			if err != nil {
+				return types.Value{}, err
+			}
+			args[i] = arg
+		}
+		return fn.call(v, args)
+	case *parser.InfixExpression:
+		if !isArithmetic(e) {
+			break
+		}
+		left, err := v.scalar(e.Left, row, def)
+		if err != nil {
+			return types.Value{}, err
+		}
+		right, err := v.scalar(e.Right, row, def)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return applyArithmetic(e, left, right, v.promote)
+	}
+	return types.Value{}, fmt.Errorf("unsupported expression: %s", expr.String())
+}
+
+// ScalarType returns the type EvaluateScalar produces for expr, checking
+// columns and function arguments without reading any rows. An untyped NULL
+// yields an empty DataType.
+func ScalarType(expr parser.Expression, def schema.TableDef) (types.DataType, error) {
+	return evaluator{}.scalarType(expr, def)
+}
+
+// scalarType is ScalarType under v's overflow setting.
+func (v evaluator) scalarType(expr parser.Expression, def schema.TableDef) (types.DataType, error) {
+	switch e := expr.(type) {
+	case *parser.ColumnRef:
+		idx, err := def.ResolveColumn(e.Table, e.Name)
+		if err != nil {
+			return "", err
 		}
+		return def.Columns[idx].Type, nil
+	case *parser.Literal:
+		return e.Value.Type, nil
+	case *parser.FunctionCall:
+		if isAggregate(e) {
+			idx, err := aggregateColumn(e, def)
+			if err != nil {
+				return "", err
+			}
+			return def.Columns[idx].Type, nil
+		}
+		fn, ok := scalarFunctions[e.Name]
+		if !ok {
+			return "", fmt.Errorf("unknown function: %s", e.Name)
+		}
+		argTypes := make([]types.DataType, len(e.Args))
+		for i, a := range e.Args {
+			t, err := v.scalarType(a, def)
+			if err != nil {
+				return "", err
+			}
+			argTypes[i] = t
+		}
+		return fn.resultType(argTypes)
+	case *parser.InfixExpression:
+		if !isArithmetic(e) {
+			break
+		}
+		left, err := v.scalarType(e.Left, def)
+		if err != nil {
+			return "", err
+		}
+		right, err := v.scalarType(e.Right, def)
+		if err != nil {
+			return "", err
+		}
+		return arithmeticType(e, left, right, v.promote)
+	}
+	return "", fmt.Errorf("unsupported expression: %s", expr.String())
+}
+
+// aggregateColumn finds the column holding the result of an aggregate call,
+// which only exists in the rows a GroupByNode folds each group into.
+func aggregateColumn(fn *parser.FunctionCall, def schema.TableDef) (int, error) {
+	idx, err := def.ResolveColumn("", fn.String())
+	if err != nil {
+		return -1, fmt.Errorf("aggregate %s cannot be used here", fn)
 	}
-	return false
+	return idx, nil
 }
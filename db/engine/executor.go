@@ -2,41 +2,157 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mini-rdbms/db/parser"
 	"mini-rdbms/db/schema"
 	"mini-rdbms/db/storage"
 	"mini-rdbms/db/types"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // ResultSet holds the result of a query.
 type ResultSet struct {
-	Columns []string
-	Rows    []storage.Row
-	Message string // For INSERT/UPDATE/DELETE/CREATE
+	Columns     []string
+	ColumnTypes []types.DataType // Parallel to Columns
+	Rows        []storage.Row
+	Message     string // For INSERT/UPDATE/DELETE/CREATE
+	// RowsAffected counts rows written by INSERT, UPDATE or DELETE.
+	RowsAffected int
+	// LastInsertID is the primary key of the row an INSERT added, including
+	// one assigned by AUTO_INCREMENT. NULL for other statements.
+	LastInsertID types.Value
+}
+
+// Config holds the settings an Engine is constructed with.
+type Config struct {
+	// DataDir is where table files are stored. Defaults to storage.DefaultDataDir.
+	DataDir string
+	// SampleSeed makes TABLESAMPLE reproducible. Zero seeds from the clock.
+	SampleSeed int64
+	// AllowDropDatabase must be set for the DROP DATABASE statement to run.
+	AllowDropDatabase bool
+	// Clock supplies the current time for CURRENT_TIMESTAMP. Defaults to time.Now.
+	Clock func() time.Time
+	// DeferWrites stops INSERT, UPDATE, DELETE and CSV imports from
+	// rewriting the table file after every statement. Changed tables are
+	// marked dirty and saved by Flush or every FlushInterval instead, so
+	// changes made since the last flush are lost if the process dies. Off by
+	// default: every write is on disk before the statement returns.
+	DeferWrites bool
+	// FlushInterval, with DeferWrites, saves dirty tables in the background
+	// this often. Zero means only Flush saves them.
+	FlushInterval time.Duration
+	// RowIDColumn names the INT AUTO_INCREMENT primary key added as the first
+	// column of a table created without a PRIMARY KEY. Defaults to "rowid".
+	RowIDColumn string
+	// RowLocks turns on storage.Table.EnableRowLocks for every table, so an
+	// UPDATE that changes no indexed column locks only the rows it writes
+	// rather than waiting for running scans of the table.
+	RowLocks bool
+	// ResultCacheSize, if positive, keeps the results of up to this many
+	// recent SELECTs and answers a repeat of one without planning or
+	// scanning. Writes made through this engine drop the cached results of
+	// the tables they change. Zero disables the cache.
+	ResultCacheSize int
+	// MaxResultRows, if positive, fails any SELECT whose result would hold
+	// more rows than this, whatever its LIMIT, rather than building it in
+	// memory. Zero means no bound.
+	MaxResultRows int
+	// StorageFormat is the encoding tables are saved in: storage.FormatJSON
+	// or storage.FormatGob. Empty saves new tables as JSON and keeps an
+	// existing table in the format it was loaded from; otherwise an existing
+	// table is converted the next time it is saved.
+	StorageFormat storage.Format
+	// IntOverflow is what INT arithmetic and SUM do when a result does not
+	// fit in an INT: fail (OverflowError, the default) or compute it as
+	// NUMERIC (OverflowPromote).
+	IntOverflow OverflowMode
 }
 
 type Engine struct {
+	// Tables holds the tables loaded so far. It is guarded by mu; code that
+	// may run alongside other statements should use Table and the other
+	// accessors instead of reading the map directly.
 	Tables map[string]*storage.Table
+	mu     sync.RWMutex
+	config Config
+
+	// results caches SELECT results; nil unless Config.ResultCacheSize is set
+	results *resultCache
+
+	// dirty holds tables changed since they were last saved (DeferWrites).
+	dirty   map[string]*storage.Table
+	dirtyMu sync.Mutex
+	// stop ends the background flusher; see Close.
+	stop      chan struct{}
+	closeOnce sync.Once
+	started   time.Time // by config.Clock, for SHOW STATUS uptime
 }
 
+// NewEngine creates an engine using the default configuration.
 func NewEngine() *Engine {
+	return NewEngineWithConfig(Config{})
+}
+
+// NewEngineWithConfig creates an engine with the given settings,
+// filling in defaults for anything left unset.
+func NewEngineWithConfig(cfg Config) *Engine {
+	if cfg.DataDir == "" {
+		cfg.DataDir = storage.DefaultDataDir
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	if cfg.RowIDColumn == "" {
+		cfg.RowIDColumn = "rowid"
+	}
+	if cfg.IntOverflow == "" {
+		cfg.IntOverflow = OverflowError
+	}
 	// Load tables from disk? Or empty?
 	// For now, empty, but we might want `Init()` to load from data dir.
 	e := &Engine{
-		Tables: make(map[string]*storage.Table),
+		Tables:  make(map[string]*storage.Table),
+		config:  cfg,
+		results: newResultCache(cfg.ResultCacheSize),
+		dirty:   make(map[string]*storage.Table),
+		stop:    make(chan struct{}),
+		started: cfg.Clock(),
+	}
+	if cfg.DeferWrites && cfg.FlushInterval > 0 {
+		go e.flushEvery(cfg.FlushInterval)
 	}
-	// Load existing?
 	return e
 }
 
+// DataDir returns the directory the engine persists tables to.
+func (e *Engine) DataDir() string {
+	return e.config.DataDir
+}
+
 func (e *Engine) Execute(ctx context.Context, sql string) (*ResultSet, error) {
+	return e.ExecutePrepared(ctx, sql)
+}
+
+// ExecutePrepared runs sql with each ? placeholder bound to the matching
+// entry of args, in order. Bound values are never interpolated into the SQL
+// text, so quotes and keywords inside them are stored verbatim.
+func (e *Engine) ExecutePrepared(ctx context.Context, sql string, args ...types.Value) (*ResultSet, error) {
 	// 1. Tokenize
 	tokenizer := parser.NewTokenizer(sql)
 
 	// 2. Parse
 	p := parser.NewParser(tokenizer)
+	p.Bind(args...)
 	stmt, err := p.ParseStatement()
+	if errors.Is(err, parser.ErrEmptyStatement) {
+		// Nothing to run (blank or comment-only input)
+		return &ResultSet{}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
@@ -48,50 +164,226 @@ func (e *Engine) Execute(ctx context.Context, sql string) (*ResultSet, error) {
 	case *parser.InsertStmt:
 		return e.execInsert(s)
 	case *parser.UpdateStmt:
-		return e.execUpdate(s)
+		return e.execUpdate(ctx, s)
 	case *parser.DeleteStmt:
-		return e.execDelete(s)
-	case *parser.SelectStmt:
-		// 4. Query Planning & Execution
-		planner := NewPlanner(e.Tables)
-		plan, err := planner.CreatePlan(s)
-		if err != nil {
+		return e.execDelete(ctx, s)
+	case *parser.DropDatabaseStmt:
+		if !e.config.AllowDropDatabase {
+			return nil, fmt.Errorf("DROP DATABASE is disabled; enable Config.AllowDropDatabase to allow it")
+		}
+		if err := e.DropAll(); err != nil {
 			return nil, err
 		}
+		return &ResultSet{Message: "Database dropped"}, nil
+	case *parser.VerifyStmt:
+		return e.execVerify()
+	case *parser.ShowStatusStmt:
+		return e.execShowStatus()
+	case *parser.ShowStatsStmt:
+		return e.execShowStats(s.TableName)
+	case *parser.VacuumStmt:
+		return e.execVacuum(s)
+	case *parser.ExplainStmt:
+		return e.execExplain(ctx, s)
+	case *parser.SelectStmt:
+		return e.execSelectCached(ctx, s, sql, args)
+	}
 
-		rows, err := plan.Execute(ctx)
-		if err != nil {
-			return nil, err
+	return nil, fmt.Errorf("unknown statement type")
+}
+
+// execSelect resolves, plans and runs a SELECT.
+func (e *Engine) execSelect(ctx context.Context, s *parser.SelectStmt) (*ResultSet, error) {
+	// 4. Name Resolution, Query Planning & Execution
+	labels := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		labels[i] = f.String()
+		if ref, ok := f.(*parser.ColumnRef); ok {
+			// A column is labelled by its name, never quoted
+			labels[i] = ref.Name
+			if ref.Table != "" {
+				labels[i] = ref.Table + "." + ref.Name
+			}
 		}
+		if s.Aliases[i] != "" {
+			labels[i] = s.Aliases[i]
+		}
+	}
+	if err := e.resolveSelect(s); err != nil {
+		return nil, err
+	}
 
-		// 5. Projection (Filter Columns)
-		return e.projectResult(rows, plan.Schema(), s.Fields)
+	plan, err := e.newPlanner().CreatePlan(s)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("unknown statement type")
+	rows, err := materializeAtMost(ctx, plan, e.config.MaxResultRows)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Projection (Filter Columns)
+	if hasAggregates(s.Fields) || isGrouped(s) {
+		// Aggregate plans already produce one column per field
+		out := plan.Schema()
+		colTypes := make([]types.DataType, len(out.Columns))
+		for i, c := range out.Columns {
+			colTypes[i] = c.Type
+		}
+		return &ResultSet{Columns: labels, ColumnTypes: colTypes, Rows: rows}, nil
+	}
+	return e.projectResult(rows, plan.Schema(), s.Fields, labels)
+}
+
+// DropAll removes every table from memory and deletes all table files
+// (including leftover temp files) from the data directory. Unlike a reset it
+// does not recreate or reseed anything.
+func (e *Engine) DropAll() error {
+	if err := storage.DeleteAllTables(e.config.DataDir); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.Tables = make(map[string]*storage.Table)
+	e.mu.Unlock()
+	e.dirtyMu.Lock()
+	e.dirty = make(map[string]*storage.Table)
+	e.dirtyMu.Unlock()
+	e.results.invalidate("")
+	return nil
+}
+
+// RowCount returns the current number of rows in a table without scanning it.
+func (e *Engine) RowCount(tableName string) (int, error) {
+	t, err := e.getTable(tableName)
+	if err != nil {
+		return 0, err
+	}
+	return t.RowCount(), nil
+}
+
+// TableNames lists every table, loaded or only on disk, sorted by name.
+func (e *Engine) TableNames() ([]string, error) {
+	onDisk, err := storage.ListTables(e.config.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range onDisk {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range e.loadedTables() {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// TableDef returns the definition of a table, loading it from disk if needed.
+func (e *Engine) TableDef(name string) (schema.TableDef, error) {
+	t, err := e.getTable(name)
+	if err != nil {
+		return schema.TableDef{}, fmt.Errorf("table not found: %s", name)
+	}
+	return t.Def, nil
 }
 
 func (e *Engine) execCreate(stmt *parser.CreateTableStmt) (*ResultSet, error) {
-	if _, exists := e.Tables[stmt.TableName]; exists {
+	if _, exists := e.Table(stmt.TableName); exists {
 		return nil, fmt.Errorf("table already exists: %s", stmt.TableName)
 	}
+	if _, virtual := informationSchema[stmt.TableName]; virtual {
+		return nil, fmt.Errorf("table name is reserved: %s", stmt.TableName)
+	}
 
 	// Create def
 	def := schema.TableDef{
-		Name:    stmt.TableName,
-		Columns: stmt.Columns,
+		Name:           stmt.TableName,
+		Columns:        stmt.Columns,
+		Indexes:        stmt.Indexes,
+		ForeignKeys:    stmt.ForeignKeys,
+		InsertionOrder: stmt.InsertionOrder,
+	}
+
+	// Column names must be unique and at most one column may be the key,
+	// or lookups by name and by key become ambiguous
+	columns := make(map[string]bool)
+	primary := ""
+	for _, col := range def.Columns {
+		if columns[col.Name] {
+			return nil, fmt.Errorf("duplicate column name: %s", col.Name)
+		}
+		columns[col.Name] = true
+		if col.IsPrimary {
+			if primary != "" {
+				return nil, fmt.Errorf("multiple primary keys for table %s: %s and %s", def.Name, primary, col.Name)
+			}
+			primary = col.Name
+		}
 	}
 
-	// Validate (Must have primary key)
+	// Without a primary key, key rows by an implicit rowid column
 	if _, ok := def.GetPrimaryKey(); !ok {
-		return nil, fmt.Errorf("table must have a primary key")
+		if _, taken := def.GetColumn(e.config.RowIDColumn); taken {
+			return nil, fmt.Errorf("table has no primary key and column %s is taken by another column", e.config.RowIDColumn)
+		}
+		rowid := schema.ColumnDef{Name: e.config.RowIDColumn, Type: types.TypeInt, IsPrimary: true, AutoIncrement: true}
+		def.Columns = append([]schema.ColumnDef{rowid}, def.Columns...)
+	}
+
+	// Validate CHECK constraints reference real columns
+	for _, col := range def.Columns {
+		if col.Check == "" {
+			continue
+		}
+		expr, err := parser.ParseExpression(col.Check)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHECK on %s: %w", col.Name, err)
+		}
+		for _, ref := range ReferencedColumns(expr) {
+			if _, err := def.ResolveColumn(ref.Table, ref.Name); err != nil {
+				return nil, fmt.Errorf("CHECK on %s references unknown column: %s", col.Name, ref.String())
+			}
+		}
+		if err := newNameScope(def).resolveExpr(expr); err != nil {
+			return nil, fmt.Errorf("invalid CHECK on %s: %w", col.Name, err)
+		}
+	}
+
+	// Validate INDEX clauses
+	seen := make(map[string]bool)
+	for _, colName := range def.Indexes {
+		if _, ok := def.GetColumn(colName); !ok {
+			return nil, fmt.Errorf("cannot index unknown column: %s", colName)
+		}
+		if seen[colName] {
+			return nil, fmt.Errorf("duplicate index on column: %s", colName)
+		}
+		seen[colName] = true
+	}
+
+	// Validate FOREIGN KEY clauses; the referenced table is checked on insert
+	for _, fk := range def.ForeignKeys {
+		if _, ok := def.GetColumn(fk.Column); !ok {
+			return nil, fmt.Errorf("foreign key on unknown column: %s", fk.Column)
+		}
 	}
 
 	table := storage.NewTable(def)
-	e.Tables[stmt.TableName] = table
+	if e.config.RowLocks {
+		table.EnableRowLocks()
+	}
+	table.SetFormat(e.config.StorageFormat)
+	if err := e.addTable(table); err != nil {
+		return nil, err
+	}
 
 	// Save immediately
-	if err := storage.SaveTable(table); err != nil {
+	if err := storage.SaveTable(e.config.DataDir, table); err != nil {
 		return nil, err
 	}
 
@@ -99,231 +391,557 @@ func (e *Engine) execCreate(stmt *parser.CreateTableStmt) (*ResultSet, error) {
 }
 
 func (e *Engine) getTable(name string) (*storage.Table, error) {
-	if t, ok := e.Tables[name]; ok {
+	if t, ok := e.Table(name); ok {
 		return t, nil
 	}
 	// Try load from disk
-	t, err := storage.LoadTable(name)
+	t, err := storage.LoadTable(e.config.DataDir, name)
 	if err != nil {
 		return nil, err
 	}
+	if e.config.RowLocks {
+		t.EnableRowLocks()
+	}
+	if e.config.StorageFormat != "" {
+		t.SetFormat(e.config.StorageFormat)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// Another statement may have loaded or created it in the meantime
+	if existing, ok := e.Tables[name]; ok {
+		return existing, nil
+	}
 	e.Tables[name] = t
 	return t, nil
 }
 
+// Table returns a loaded table without touching the disk. Unlike reading
+// Tables directly, it is safe while other statements run.
+func (e *Engine) Table(name string) (*storage.Table, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	t, ok := e.Tables[name]
+	return t, ok
+}
+
+// addTable registers a newly created table, failing if the name is taken.
+func (e *Engine) addTable(t *storage.Table) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.Tables[t.Def.Name]; exists {
+		return fmt.Errorf("table already exists: %s", t.Def.Name)
+	}
+	e.Tables[t.Def.Name] = t
+	return nil
+}
+
+// loadedTables returns a copy of the loaded tables, for planners and other
+// code that reads many of them.
+func (e *Engine) loadedTables() map[string]*storage.Table {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	tables := make(map[string]*storage.Table, len(e.Tables))
+	for name, t := range e.Tables {
+		tables[name] = t
+	}
+	return tables
+}
+
 func (e *Engine) execInsert(stmt *parser.InsertStmt) (*ResultSet, error) {
 	table, err := e.getTable(stmt.TableName)
 	if err != nil {
 		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
 	}
+	values, err := e.insertValues(table, stmt.Values)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.OnConflict != nil {
+		return e.upsertRow(table, values, stmt.OnConflict)
+	}
+	return e.insertRow(table, values)
+}
 
-	// Validate Foreign Key Constraints
-	if err := e.validateForeignKeys(table, stmt.Values); err != nil {
+// insertValues evaluates an INSERT's value expressions. A value that is just
+// NOW() or CURRENT_TIMESTAMP takes the type of the column it fills, as ON
+// UPDATE CURRENT_TIMESTAMP does.
+func (e *Engine) insertValues(table *storage.Table, exprs []parser.Expression) ([]types.Value, error) {
+	values := make([]types.Value, len(exprs))
+	var now []int
+	for i, expr := range exprs {
+		if fn, ok := expr.(*parser.FunctionCall); ok && fn.Name == "NOW" && len(fn.Args) == 0 {
+			now = append(now, i)
+			continue
+		}
+		if _, err := e.evaluator().scalarType(expr, schema.TableDef{}); err != nil {
+			return nil, err
+		}
+		v, err := e.evaluator().scalar(expr, storage.Row{}, schema.TableDef{})
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	if len(now) == 0 {
+		return values, nil
+	}
+
+	filled := table.PadAutoIncrement(values)
+	pkCol, _ := table.Def.GetPrimaryKey()
+	pkIdx := table.Def.GetColumnIndex(pkCol.Name)
+	for _, i := range now {
+		if len(filled) != len(values) && i >= pkIdx {
+			i++ // after the omitted AUTO_INCREMENT key
+		}
+		if i < len(table.Def.Columns) {
+			filled[i] = e.currentTimestamp(table.Def.Columns[i].Type)
+		}
+	}
+	return filled, nil
+}
+
+// upsertRow runs INSERT ... ON CONFLICT (col) DO UPDATE SET. The new row is
+// checked as for a plain INSERT first; if its col value is taken, the row
+// holding it gets the SET values instead, with the checks of UPDATE.
+func (e *Engine) upsertRow(table *storage.Table, values []types.Value, conflict *parser.ConflictClause) (*ResultSet, error) {
+	// The update runs under the table's lock, where foreign keys pointing
+	// at the old key cannot be checked, so it may not re-key the row
+	pkCol, _ := table.Def.GetPrimaryKey()
+	if _, ok := conflict.Set[pkCol.Name]; ok {
+		return nil, fmt.Errorf("ON CONFLICT DO UPDATE cannot change the primary key %s", pkCol.Name)
+	}
+	values, err := e.checkRow(table, values)
+	if err != nil {
+		return nil, err
+	}
+	pk, inserted, err := table.Upsert(values, conflict.Column, func(row storage.Row) ([]types.Value, error) {
+		return e.updatedValues(table, row, conflict.Set)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.persist(table); err != nil {
+		return nil, err
+	}
+
+	if inserted {
+		return &ResultSet{Message: "Insert successful", RowsAffected: 1, LastInsertID: pk}, nil
+	}
+	return &ResultSet{Message: "Updated 1 rows", RowsAffected: 1, LastInsertID: pk}, nil
+}
+
+// Insert adds a row from typed values keyed by column name, bypassing SQL
+// text entirely. An omitted AUTO_INCREMENT primary key is assigned.
+func (e *Engine) Insert(tableName string, values map[string]types.Value) (*ResultSet, error) {
+	table, err := e.getTable(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+
+	for name := range values {
+		if _, ok := table.Def.GetColumn(name); !ok {
+			return nil, fmt.Errorf("column not found: %s", name)
+		}
+	}
+
+	row := make([]types.Value, len(table.Def.Columns))
+	for i, col := range table.Def.Columns {
+		val, ok := values[col.Name]
+		if !ok && !(col.IsPrimary && col.AutoIncrement) {
+			return nil, fmt.Errorf("missing value for column %s", col.Name)
+		}
+		row[i] = val // NULL placeholder when an AUTO_INCREMENT key is omitted
+	}
+	return e.insertRow(table, row)
+}
+
+// insertRow validates and inserts one row, then persists the table.
+func (e *Engine) insertRow(table *storage.Table, values []types.Value) (*ResultSet, error) {
+	pk, err := e.addRow(table, values)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := table.Insert(stmt.Values); err != nil {
+	if err := e.persist(table); err != nil {
 		return nil, err
 	}
 
-	if err := storage.SaveTable(table); err != nil {
+	return &ResultSet{Message: "Insert successful", RowsAffected: 1, LastInsertID: pk}, nil
+}
+
+// BatchResult reports how an InsertMany went.
+type BatchResult struct {
+	Inserted int
+	// Errors is parallel to the rows given: nil for a row that was inserted,
+	// otherwise why it was skipped.
+	Errors []error
+}
+
+// InsertMany inserts rows, each a full list of column values as for INSERT
+// INTO ... VALUES, into an existing table. Every row gets the usual checks;
+// rows that fail are reported and skipped. The table is locked once for the
+// whole batch and saved once at the end, so this is much cheaper than one
+// INSERT per row.
+func (e *Engine) InsertMany(tableName string, rows [][]types.Value) (*BatchResult, error) {
+	table, err := e.getTable(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+
+	result := &BatchResult{Errors: make([]error, len(rows))}
+	var valid [][]types.Value
+	var positions []int // index in rows of each entry of valid
+	for i, values := range rows {
+		values, err := e.checkRow(table, values)
+		if err != nil {
+			result.Errors[i] = err
+			continue
+		}
+		valid = append(valid, values)
+		positions = append(positions, i)
+	}
+
+	_, errs := table.InsertBatch(valid)
+	for j, err := range errs {
+		result.Errors[positions[j]] = err
+		if err == nil {
+			result.Inserted++
+		}
+	}
+
+	if result.Inserted > 0 {
+		if err := e.persist(table); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// addRow validates and inserts one row in memory without saving the table,
+// returning the row's primary key.
+func (e *Engine) addRow(table *storage.Table, values []types.Value) (types.Value, error) {
+	values, err := e.checkRow(table, values)
+	if err != nil {
+		return types.Value{}, err
+	}
+	return table.InsertKey(values)
+}
+
+// checkRow lines values up with the table's columns and applies the checks
+// that need the engine (CHECK constraints and foreign keys). The table
+// itself checks types and keys when the row is inserted.
+func (e *Engine) checkRow(table *storage.Table, values []types.Value) ([]types.Value, error) {
+	// Line values up with the columns if an AUTO_INCREMENT key was omitted
+	values = table.PadAutoIncrement(values)
+
+	if len(values) != len(table.Def.Columns) {
+		names := make([]string, len(table.Def.Columns))
+		for i, col := range table.Def.Columns {
+			names[i] = col.Name
+		}
+		return nil, fmt.Errorf("INSERT INTO %s: expected %d values (%s), got %d",
+			table.Def.Name, len(names), strings.Join(names, ", "), len(values))
+	}
+
+	if err := e.checkConstraints(table, values); err != nil {
 		return nil, err
 	}
 
-	return &ResultSet{Message: "Insert successful"}, nil
+	// Validate Foreign Key Constraints
+	if err := e.validateForeignKeys(table, values); err != nil {
+		return nil, err
+	}
+	return values, nil
 }
 
-func (e *Engine) execUpdate(stmt *parser.UpdateStmt) (*ResultSet, error) {
+func (e *Engine) execUpdate(ctx context.Context, stmt *parser.UpdateStmt) (*ResultSet, error) {
 	table, err := e.getTable(stmt.TableName)
 	if err != nil {
 		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
 	}
-
-	// Find rows to update.
-	// Use Planner for finding rows?
-	// Reuse ScanNode logic or duplicate for now.
+	keysToUpdate, err := e.targetKeys(ctx, stmt, table, stmt.Where)
+	if err != nil {
+		return nil, err
+	}
 
 	count := 0
-	// Simplified: Iterate all rows safely using Scan to gather keys first.
-	// Since we support Index in WHERE, we should use it.
-
-	// Check if Where uses PK
-	var pkTarget interface{}
-	useIndex := false
-
-	if stmt.Where != nil {
-		if comp, ok := stmt.Where.Expr.(*parser.ComparisonExpression); ok {
-			if comp.Operator == "=" {
-				if col, ok := table.Def.GetColumn(comp.Column); ok && col.IsPrimary {
-					useIndex = true
-					pkTarget = comp.Value.Val
-				}
+
+	// Every matched row gets the same value, so setting a unique column on
+	// more than one row must fail. Check up front so no row is half-updated.
+	if len(keysToUpdate) > 1 {
+		for colName, val := range stmt.Set {
+			if col, ok := table.Def.GetColumn(colName); ok && col.IsPrimary {
+				return nil, fmt.Errorf("duplicate primary key: %v", val.Val)
+			} else if ok && col.IsUnique {
+				return nil, fmt.Errorf("duplicate unique value for column %s", colName)
 			}
 		}
 	}
 
-	if useIndex {
-		row, exists := table.GetRow(pkTarget)
-		if exists {
-			// Apply Update
-			if err := e.applyUpdate(table, row, stmt.Set, pkTarget); err != nil {
-				return nil, err
-			}
-			count++
+	for _, pk := range keysToUpdate {
+		// Re-fetch to be safe or update directly?
+		// Need the row to check values.
+		row, ok := table.GetRow(pk)
+		if !ok {
+			continue
 		}
-	} else {
-		// Scan
-		// Collect keys to update to avoid issues during iteration (though Table.Scan is safe for read,
-		// updating inside a Scan might block constraint checks depending on impl, best to collect IDs first).
-		var keysToUpdate []interface{}
-		// idx := table.Def.GetColumnIndex(stmt.Where.Column) -- Not needed for generic Evaluate
-
-		table.Scan(func(pk interface{}, row storage.Row) bool {
-			// Check Where
-			if stmt.Where == nil || Evaluate(stmt.Where.Expr, row, table.Def) {
-				keysToUpdate = append(keysToUpdate, pk)
-			}
-			return true
-		})
-
-		for _, pk := range keysToUpdate {
-			// Re-fetch to be safe or update directly?
-			// Need the row to check values.
-			row, ok := table.GetRow(pk)
-			if !ok {
+		if err := e.applyUpdate(table, row, stmt.Set, pk); err != nil {
+			e.results.invalidate(table.Def.Name) // earlier rows may have changed
+			return nil, err
+		}
+		count++
+	}
+
+	e.persist(table)
+	return &ResultSet{Message: fmt.Sprintf("Updated %d rows", count), RowsAffected: count}, nil
+}
+
+// targetKeys returns the primary keys of the rows an UPDATE or DELETE
+// changes, found through the same plan SELECT would use. Keys are collected
+// before any row is changed, so callers can mutate freely.
+func (e *Engine) targetKeys(ctx context.Context, stmt parser.Statement, table *storage.Table, where *parser.WhereClause) ([]interface{}, error) {
+	plan, err := e.planWrite(stmt, table.Def.Name, where)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := materialize(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+	pkCol, _ := table.Def.GetPrimaryKey()
+	pkIdx := table.Def.GetColumnIndex(pkCol.Name)
+	keys := make([]interface{}, len(rows))
+	for i, row := range rows {
+		keys[i] = row.Values[pkIdx].Val
+	}
+	return keys, nil
+}
+
+func (e *Engine) applyUpdate(t *storage.Table, row storage.Row, setMap map[string]types.Value, pk interface{}) error {
+	newValues, err := e.updatedValues(t, row, setMap)
+	if err != nil {
+		return err
+	}
+
+	// We can just construct a value.
+	// We know PK column type.
+	pkCol, _ := t.Def.GetPrimaryKey()
+	pkValue := types.Value{Type: pkCol.Type, Val: pk}
+
+	if newValues[t.Def.GetColumnIndex(pkCol.Name)].Val != pk {
+		if err := e.checkNotReferenced(t, pkValue); err != nil {
+			return err
+		}
+	}
+	return t.Update(pkValue, newValues)
+}
+
+// checkNotReferenced fails if a foreign key anywhere still points at the
+// row of t with primary key pk, which is about to get a new key.
+func (e *Engine) checkNotReferenced(t *storage.Table, pk types.Value) error {
+	if err := e.loadAllTables(); err != nil {
+		return err
+	}
+	pkCol, _ := t.Def.GetPrimaryKey()
+	for _, child := range e.loadedTables() {
+		for _, fk := range child.Def.ForeignKeys {
+			if fk.RefTable != t.Def.Name || fk.RefColumn != pkCol.Name {
 				continue
 			}
-			if err := e.applyUpdate(table, row, stmt.Set, pk); err != nil {
-				return nil, err
+			colIdx := child.Def.GetColumnIndex(fk.Column)
+			referenced := false
+			child.Scan(func(_ interface{}, row storage.Row) bool {
+				referenced = row.Values[colIdx].Val == pk.Val
+				return !referenced
+			})
+			if referenced {
+				return fmt.Errorf("cannot change primary key %v of %s: still referenced by %s.%s",
+					pk.Val, t.Def.Name, child.Def.Name, fk.Column)
 			}
-			count++
 		}
 	}
-
-	storage.SaveTable(table)
-	return &ResultSet{Message: fmt.Sprintf("Updated %d rows", count)}, nil
+	return nil
 }
 
-func (e *Engine) applyUpdate(t *storage.Table, row storage.Row, setMap map[string]types.Value, pk interface{}) error {
+// updatedValues returns row's values after SET setMap, with ON UPDATE
+// CURRENT_TIMESTAMP columns refreshed and CHECK constraints applied.
+func (e *Engine) updatedValues(t *storage.Table, row storage.Row, setMap map[string]types.Value) ([]types.Value, error) {
 	newValues := make([]types.Value, len(row.Values))
 	copy(newValues, row.Values)
 
 	for colName, newVal := range setMap {
 		idx := t.Def.GetColumnIndex(colName)
 		if idx == -1 {
-			return fmt.Errorf("column not found: %s", colName)
+			return nil, fmt.Errorf("column not found: %s", colName)
 		}
 		newValues[idx] = newVal
 	}
 
-	// We can just construct a value.
-	// We know PK column type.
-	pkCol, _ := t.Def.GetPrimaryKey()
-	pkValue := types.Value{Type: pkCol.Type, Val: pk}
+	// ON UPDATE CURRENT_TIMESTAMP columns refresh unless SET names them
+	for i, col := range t.Def.Columns {
+		if _, explicit := setMap[col.Name]; col.OnUpdateNow && !explicit {
+			newValues[i] = e.currentTimestamp(col.Type)
+		}
+	}
 
-	return t.Update(pkValue, newValues)
+	if err := e.checkConstraints(t, newValues); err != nil {
+		return nil, err
+	}
+	return newValues, nil
 }
 
-func (e *Engine) execDelete(stmt *parser.DeleteStmt) (*ResultSet, error) {
+// currentTimestamp returns the engine clock's time, for ON UPDATE
+// CURRENT_TIMESTAMP and NOW() values, as a value of type typ.
+func (e *Engine) currentTimestamp(typ types.DataType) types.Value {
+	return timestampAs(e.config.Clock(), typ)
+}
+
+func (e *Engine) execDelete(ctx context.Context, stmt *parser.DeleteStmt) (*ResultSet, error) {
 	table, err := e.getTable(stmt.TableName)
 	if err != nil {
 		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
 	}
-
-	count := 0
-	var keysToDelete []interface{}
-
-	// Optimization: PK Lookup
-	useIndex := false
-	var pkTarget interface{}
-	if stmt.Where != nil {
-		if comp, ok := stmt.Where.Expr.(*parser.ComparisonExpression); ok {
-			if comp.Operator == "=" {
-				if col, ok := table.Def.GetColumn(comp.Column); ok && col.IsPrimary {
-					useIndex = true
-					pkTarget = comp.Value.Val
-				}
-			}
-		}
-	}
-
-	if useIndex {
-		keysToDelete = append(keysToDelete, pkTarget)
-	} else {
-		// Scan for keys
-		// idx := table.Def.GetColumnIndex(stmt.Where.Column)
-
-		table.Scan(func(pk interface{}, row storage.Row) bool {
-			if stmt.Where == nil || Evaluate(stmt.Where.Expr, row, table.Def) {
-				keysToDelete = append(keysToDelete, pk)
-			}
-			return true
-		})
+	keys, err := e.targetKeys(ctx, stmt, table, stmt.Where)
+	if err != nil {
+		return nil, err
 	}
 
+	count := 0
 	pkCol, _ := table.Def.GetPrimaryKey()
 
-	for _, pk := range keysToDelete {
+	for _, pk := range keys {
 		pkValue := types.Value{Type: pkCol.Type, Val: pk}
 		if err := table.Delete(pkValue); err == nil {
 			count++
 		}
 	}
 
-	storage.SaveTable(table)
-	return &ResultSet{Message: fmt.Sprintf("Deleted %d rows", count)}, nil
+	e.persist(table)
+	return &ResultSet{Message: fmt.Sprintf("Deleted %d rows", count), RowsAffected: count}, nil
 }
 
-func (e *Engine) projectResult(rows []storage.Row, schema schema.TableDef, fields []string) (*ResultSet, error) {
-	// If fields contains "*", return all
-	showAll := false
-	for _, f := range fields {
-		if f == "*" {
-			showAll = true
-		}
+// projectResult evaluates the SELECT list over rows. labels holds each
+// field's column name as the user wrote it, before name resolution.
+func (e *Engine) projectResult(rows []storage.Row, schema schema.TableDef, fields []parser.Expression, labels []string) (*ResultSet, error) {
+	// An output column either copies input column idx or computes expr.
+	type outputColumn struct {
+		name string
+		typ  types.DataType
+		idx  int
+		expr parser.Expression
 	}
 
-	if showAll {
-		// Return all columns
-		colNames := make([]string, len(schema.Columns))
-		for i, c := range schema.Columns {
-			colNames[i] = c.Name
+	var out []outputColumn
+	passthrough := true // only *, so rows can be returned as they are
+	for j, f := range fields {
+		switch f := f.(type) {
+		case *parser.Star:
+			for i, col := range schema.Columns {
+				if f.Table != "" && sourceTable(schema, col) != f.Table {
+					continue
+				}
+				out = append(out, outputColumn{name: col.Name, typ: col.Type, idx: i})
+			}
+			if f.Table != "" {
+				passthrough = false
+			}
+		case *parser.ColumnRef:
+			passthrough = false
+			i, err := schema.ResolveColumn(f.Table, f.Name)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, outputColumn{name: labels[j], typ: schema.Columns[i].Type, idx: i})
+		default:
+			passthrough = false
+			typ, err := e.evaluator().scalarType(f, schema)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, outputColumn{name: labels[j], typ: typ, idx: -1, expr: f})
 		}
-		return &ResultSet{Columns: colNames, Rows: rows}, nil
 	}
 
-	// Filter columns
-	var resultIndices []int
-	var resultNames []string
-
-	for _, f := range fields {
-		// Remove prefix
-		fieldName := stripTablePrefix(f)
-		found := false
-		for i, col := range schema.Columns {
-			if col.Name == fieldName {
-				resultIndices = append(resultIndices, i)
-				resultNames = append(resultNames, f) // Keep original requested name? Or cleaned?
-				found = true
-				break
-			}
-		}
-		if !found {
-			return nil, fmt.Errorf("column not found in result: %s", f)
-		}
+	resultNames := make([]string, len(out))
+	resultTypes := make([]types.DataType, len(out))
+	for i, c := range out {
+		resultNames[i] = c.name
+		resultTypes[i] = c.typ
+	}
+	if passthrough && len(out) == len(schema.Columns) {
+		return &ResultSet{Columns: resultNames, ColumnTypes: resultTypes, Rows: rows}, nil
 	}
 
 	// Construct new rows
 	newRows := make([]storage.Row, len(rows))
 	for i, r := range rows {
-		newVals := make([]types.Value, len(resultIndices))
-		for j, idx := range resultIndices {
-			newVals[j] = r.Values[idx]
+		newVals := make([]types.Value, len(out))
+		for j, c := range out {
+			if c.expr == nil {
+				newVals[j] = r.Values[c.idx]
+				continue
+			}
+			v, err := e.evaluator().scalar(c.expr, r, schema)
+			if err != nil {
+				return nil, err
+			}
+			newVals[j] = v
 		}
 		newRows[i] = storage.Row{Values: newVals}
 	}
 
-	return &ResultSet{Columns: resultNames, Rows: newRows}, nil
+	return &ResultSet{Columns: resultNames, ColumnTypes: resultTypes, Rows: newRows}, nil
+}
+
+// newPlanner returns a planner over the loaded tables with the engine's
+// settings applied.
+func (e *Engine) newPlanner() *Planner {
+	p := NewPlanner(e.loadedTables())
+	p.SampleSeed = e.config.SampleSeed
+	p.PromoteOverflow = e.config.IntOverflow == OverflowPromote
+	p.Clock = e.config.Clock
+	return p
+}
+
+// evaluator returns the evaluator for expressions outside of a plan.
+func (e *Engine) evaluator() evaluator {
+	return evaluator{promote: e.config.IntOverflow == OverflowPromote, clock: e.config.Clock}
+}
+
+// sourceTable returns the table col comes from: the one recorded for joined
+// columns, otherwise the table schema describes.
+func sourceTable(def schema.TableDef, col schema.ColumnDef) string {
+	if col.Table != "" {
+		return col.Table
+	}
+	return def.Name
+}
+
+// checkConstraints evaluates every column CHECK against the candidate row values.
+func (e *Engine) checkConstraints(table *storage.Table, values []types.Value) error {
+	if len(values) != len(table.Def.Columns) {
+		// Leave the count error to Table.Insert/Update
+		return nil
+	}
+	row := storage.Row{Values: values}
+	for _, col := range table.Def.Columns {
+		if col.Check == "" {
+			continue
+		}
+		expr, err := parser.ParseExpression(col.Check)
+		if err == nil {
+			err = newNameScope(table.Def).resolveExpr(expr)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid CHECK on %s: %w", col.Name, err)
+		}
+		if !e.evaluator().matches(expr, row, table.Def) {
+			return fmt.Errorf("CHECK constraint failed for column %s: %s", col.Name, col.Check)
+		}
+	}
+	return nil
 }
 
 // validateForeignKeys checks all FK constraints for the given values.
@@ -337,18 +955,19 @@ func (e *Engine) validateForeignKeys(table *storage.Table, values []types.Value)
 		}
 
 		// Get the value being inserted
+		if colIdx >= len(values) {
+			return fmt.Errorf("column count mismatch: expected %d, got %d", len(table.Def.Columns), len(values))
+		}
 		fkValue := values[colIdx]
+		if fkValue.IsNull() {
+			// NULL references nothing; the column's own constraints apply
+			continue
+		}
 
 		// Get the referenced table
-		refTable, ok := e.Tables[fk.RefTable]
-		if !ok {
-			// Try to load it
-			var err error
-			refTable, err = storage.LoadTable(fk.RefTable)
-			if err != nil {
-				return fmt.Errorf("referenced table not found: %s", fk.RefTable)
-			}
-			e.Tables[fk.RefTable] = refTable
+		refTable, err := e.getTable(fk.RefTable)
+		if err != nil {
+			return fmt.Errorf("referenced table not found: %s", fk.RefTable)
 		}
 
 		// Check if the referenced value exists
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"strings"
+)
+
+// execExplain plans a SELECT, UPDATE or DELETE without running it and
+// returns the plan as one "plan" row per node, children indented under their
+// parent. For UPDATE and DELETE the rows the statement would change are
+// found and counted, but nothing is written.
+func (e *Engine) execExplain(ctx context.Context, stmt *parser.ExplainStmt) (*ResultSet, error) {
+	var lines []string
+	switch s := stmt.Stmt.(type) {
+	case *parser.SelectStmt:
+		if err := e.resolveSelect(s); err != nil {
+			return nil, err
+		}
+		plan, err := e.newPlanner().CreatePlan(s)
+		if err != nil {
+			return nil, err
+		}
+		lines = explainPlan(plan, 0)
+
+	case *parser.UpdateStmt:
+		plan, err := e.planWrite(s, s.TableName, s.Where)
+		if err != nil {
+			return nil, err
+		}
+		lines, err = explainWrite(ctx, "Update "+s.TableName, plan)
+		if err != nil {
+			return nil, err
+		}
+
+	case *parser.DeleteStmt:
+		plan, err := e.planWrite(s, s.TableName, s.Where)
+		if err != nil {
+			return nil, err
+		}
+		lines, err = explainWrite(ctx, "Delete from "+s.TableName, plan)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("EXPLAIN supports SELECT, UPDATE and DELETE")
+	}
+
+	rows := make([]storage.Row, len(lines))
+	for i, line := range lines {
+		rows[i] = storage.Row{Values: []types.Value{types.NewText(line)}}
+	}
+	return &ResultSet{Columns: []string{"plan"}, ColumnTypes: []types.DataType{types.TypeText}, Rows: rows}, nil
+}
+
+// planWrite resolves the WHERE clause of an UPDATE or DELETE and plans how
+// its rows are found.
+func (e *Engine) planWrite(stmt parser.Statement, table string, where *parser.WhereClause) (PlanNode, error) {
+	t, err := e.getTable(table)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", table)
+	}
+	if where != nil {
+		if err := newNameScope(t.Def).resolveExpr(where.Expr); err != nil {
+			return nil, err
+		}
+		if err := e.resolveSubqueries(where.Expr); err != nil {
+			return nil, err
+		}
+	}
+	return e.newPlanner().CreatePlan(stmt)
+}
+
+// explainWrite renders a write under the heading and counts the rows it
+// would change.
+func explainWrite(ctx context.Context, heading string, plan PlanNode) ([]string, error) {
+	rows, err := materialize(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+	lines := append([]string{heading}, explainPlan(plan, 1)...)
+	return append(lines, fmt.Sprintf("Rows matched: %d", len(rows))), nil
+}
+
+// explainPlan describes node and its inputs, indenting each level by two
+// spaces starting at depth.
+func explainPlan(node PlanNode, depth int) []string {
+	var line string
+	var inputs []PlanNode
+	switch n := node.(type) {
+	case *ScanNode:
+		line = "Full scan on " + n.Table.Def.Name
+		if n.Where != nil {
+			line += " (filter: " + n.Where.String() + ")"
+		}
+	case *IndexScanNode:
+		line = fmt.Sprintf("Index lookup on %s.%s = %s", n.Table.Def.Name, n.IndexName, planValue(n.Value))
+	case *IndexRangeNode:
+		line = fmt.Sprintf("Index range scan on %s.%s from %s to %s", n.Table.Def.Name, n.IndexName, rangeBound(n.From), rangeBound(n.To))
+	case *ValuesNode:
+		line = "Virtual table " + n.Def.Name
+		if n.Def.Name == "" {
+			line = "Single row"
+		}
+	case *CountNode:
+		line = "Row count of " + n.Table.Def.Name
+	case *MinMaxNode:
+		line = fmt.Sprintf("Index bounds of %s.%s", n.Table.Def.Name, n.Column)
+	case *SemiJoinNode:
+		line = "Semi join on " + n.In.String()
+		if n.Hash {
+			line = "Hash semi join on " + n.In.String()
+		}
+		inputs = []PlanNode{n.Input, n.Subquery}
+	case *FilterNode:
+		line = "Filter: " + n.Expr.String()
+		inputs = []PlanNode{n.Input}
+	case *JoinNode:
+		line = "Nested loop join"
+		if n.LeftCol != "" {
+			line += fmt.Sprintf(" on %s = %s", n.LeftCol, n.RightCol)
+		}
+		inputs = []PlanNode{n.Left, n.Right}
+	case *SampleNode:
+		line = fmt.Sprintf("Sample %d percent", n.Percent)
+		inputs = []PlanNode{n.Input}
+	case *LimitNode:
+		line = fmt.Sprintf("Limit %d", n.Limit)
+		inputs = []PlanNode{n.Input}
+	case *AggregateNode:
+		fields := make([]string, len(n.Fields))
+		for i, f := range n.Fields {
+			fields[i] = f.String()
+		}
+		line = "Aggregate " + strings.Join(fields, ", ")
+		inputs = []PlanNode{n.Input}
+	case *GroupByNode:
+		keys := make([]string, len(n.Keys))
+		for i, k := range n.Keys {
+			keys[i] = k.String()
+		}
+		line = "Group by " + strings.Join(keys, ", ")
+		if len(keys) == 0 {
+			line = "Single group"
+		}
+		if n.Having != nil {
+			line += " (having: " + n.Having.String() + ")"
+		}
+		inputs = []PlanNode{n.Input}
+	default:
+		line = fmt.Sprintf("%T", node)
+	}
+
+	lines := []string{strings.Repeat("  ", depth) + line}
+	for _, in := range inputs {
+		lines = append(lines, explainPlan(in, depth+1)...)
+	}
+	return lines
+}
+
+// rangeBound renders one end of an index range; NULL means unbounded.
+func rangeBound(v types.Value) string {
+	if v.IsNull() {
+		return "unbounded"
+	}
+	return planValue(v)
+}
+
+// planValue renders a value as it would appear in SQL.
+func planValue(v types.Value) string {
+	if v.IsNull() {
+		return v.String()
+	}
+	switch v.Type {
+	case types.TypeText:
+		return "'" + v.String() + "'"
+	case types.TypeDate:
+		return "DATE '" + v.String() + "'"
+	}
+	return v.String()
+}
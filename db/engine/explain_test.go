@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT, note TEXT)",
+		"INSERT INTO orders VALUES (1, 5, 'a')",
+		"INSERT INTO orders VALUES (2, 50, 'b')",
+		"INSERT INTO orders VALUES (3, 8, 'c')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	explain := func(sql string) string {
+		t.Helper()
+		res, err := e.Execute(ctx, "EXPLAIN "+sql)
+		if err != nil {
+			t.Fatalf("EXPLAIN %s: %v", sql, err)
+		}
+		var lines []string
+		for _, row := range res.Rows {
+			lines = append(lines, row.Values[0].String())
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"DELETE FROM orders WHERE id = 2",
+			"Delete from orders\n  Index lookup on orders.id = 2\nRows matched: 1"},
+		{"DELETE FROM orders WHERE amount < 10",
+			"Delete from orders\n  Full scan on orders (filter: orders.amount < 10)\nRows matched: 2"},
+		{"UPDATE orders SET note = 'x' WHERE id > 1",
+			"Update orders\n  Filter: orders.id > 1\n    Index range scan on orders.id from 1 to unbounded\nRows matched: 2"},
+		{"SELECT note FROM orders WHERE note = 'b' LIMIT 1",
+			"Limit 1\n  Full scan on orders (filter: orders.note = 'b')"},
+	}
+	for _, tt := range tests {
+		if got := explain(tt.sql); got != tt.want {
+			t.Errorf("EXPLAIN %s:\ngot:\n%s\nwant:\n%s", tt.sql, got, tt.want)
+		}
+	}
+
+	// Nothing was written
+	if n := e.Tables["orders"].RowCount(); n != 3 {
+		t.Errorf("Expected 3 rows after EXPLAIN DELETE, got %d", n)
+	}
+	res, _ := e.Execute(ctx, "SELECT id FROM orders WHERE note = 'x'")
+	if len(res.Rows) != 0 {
+		t.Errorf("Expected EXPLAIN UPDATE to change nothing, got %d rows", len(res.Rows))
+	}
+
+	if _, err := e.Execute(ctx, "EXPLAIN INSERT INTO orders VALUES (4, 1, 'd')"); err == nil {
+		t.Errorf("Expected an error explaining INSERT")
+	}
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"errors"
+	"mini-rdbms/db/storage"
+	"time"
+)
+
+// persist saves a table after a write, or with Config.DeferWrites only marks
+// it dirty for the next Flush.
+func (e *Engine) persist(t *storage.Table) error {
+	e.results.invalidate(t.Def.Name)
+	if !e.config.DeferWrites {
+		return storage.SaveTable(e.config.DataDir, t)
+	}
+	e.dirtyMu.Lock()
+	e.dirty[t.Def.Name] = t
+	e.dirtyMu.Unlock()
+	return nil
+}
+
+// Flush saves every table changed since it was last saved. It does nothing
+// unless Config.DeferWrites is set. Tables that fail to save stay dirty.
+func (e *Engine) Flush() error {
+	e.dirtyMu.Lock()
+	pending := e.dirty
+	e.dirty = make(map[string]*storage.Table)
+	e.dirtyMu.Unlock()
+
+	var errs []error
+	for name, t := range pending {
+		if err := storage.SaveTable(e.config.DataDir, t); err != nil {
+			errs = append(errs, err)
+			e.dirtyMu.Lock()
+			if _, again := e.dirty[name]; !again {
+				e.dirty[name] = t
+			}
+			e.dirtyMu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the background flusher and saves every dirty table. Call it
+// before the process exits; the engine should not be used afterwards.
+// Calling Close again only flushes.
+func (e *Engine) Close() error {
+	e.closeOnce.Do(func() { close(e.stop) })
+	return e.Flush()
+}
+
+// flushEvery calls Flush on each tick of interval until Close.
+func (e *Engine) flushEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stop:
+			return
+		}
+	}
+}
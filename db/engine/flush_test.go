@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// diskRows counts the rows a fresh engine reads from the table file in dir.
+func diskRows(t *testing.T, dir, table string) int {
+	t.Helper()
+	n, err := NewEngineWithConfig(Config{DataDir: dir}).RowCount(table)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", table, err)
+	}
+	return n
+}
+
+func TestFlushPersistsDeferredWrites(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir, DeferWrites: true})
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+		"INSERT INTO users VALUES (2, 'Ben')",
+		"INSERT INTO users VALUES (3, 'Cy')",
+		"DELETE FROM users WHERE id = 3",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// CREATE TABLE is saved at once; the rows wait for Flush
+	if n := diskRows(t, dir, "users"); n != 0 {
+		t.Fatalf("Expected no rows on disk before Flush, got %d", n)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if n := diskRows(t, dir, "users"); n != 2 {
+		t.Errorf("Expected 2 rows on disk after Flush, got %d", n)
+	}
+
+	// Nothing is dirty any more, so flushing again is a no-op
+	if err := e.Flush(); err != nil {
+		t.Errorf("Second Flush failed: %v", err)
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir, DeferWrites: true, FlushInterval: 10 * time.Millisecond})
+	defer e.Close()
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for diskRows(t, dir, "users") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the background flush to save the insert")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCloseFlushesDirtyTables(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir, DeferWrites: true, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+		"INSERT INTO users VALUES (2, 'Ben')",
+		"UPDATE users SET name = 'Benny' WHERE id = 2",
+		"INSERT INTO orders VALUES (1, 100)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+
+	reopened := NewEngineWithConfig(Config{DataDir: dir})
+	res, err := reopened.Execute(ctx, "SELECT name FROM users WHERE id = 2")
+	if err != nil {
+		t.Fatalf("Failed to select after reopening: %v", err)
+	}
+	if len(res.Rows) != 1 || res.Rows[0].Values[0].Val != "Benny" {
+		t.Errorf("Expected the update to survive Close, got %v", res.Rows)
+	}
+	if n := diskRows(t, dir, "orders"); n != 1 {
+		t.Errorf("Expected 1 order on disk, got %d", n)
+	}
+}
+
+func BenchmarkBulkInsert(b *testing.B) {
+	ctx := context.Background()
+	for _, deferred := range []bool{false, true} {
+		b.Run(fmt.Sprintf("deferred=%v", deferred), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				e := NewEngineWithConfig(Config{DataDir: b.TempDir(), DeferWrites: deferred})
+				if _, err := e.Execute(ctx, "CREATE TABLE t (id INT PRIMARY KEY, name TEXT)"); err != nil {
+					b.Fatal(err)
+				}
+				for j := 1; j <= 500; j++ {
+					if _, err := e.Execute(ctx, fmt.Sprintf("INSERT INTO t VALUES (%d, 'row %d')", j, j)); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := e.Flush(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestEnginesOnOneDirDoNotShareTables(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	a := NewEngineWithConfig(Config{DataDir: dir})
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+	} {
+		if _, err := a.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// b and c both load the same unchanged file
+	b := NewEngineWithConfig(Config{DataDir: dir, DeferWrites: true})
+	c := NewEngineWithConfig(Config{DataDir: dir})
+	name := func(e *Engine) string {
+		t.Helper()
+		res, err := e.Execute(ctx, "SELECT name FROM users WHERE id = 1")
+		if err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		return res.Rows[0].Values[0].String()
+	}
+	if got := name(b); got != "Ann" {
+		t.Fatalf("b read %s, want Ann", got)
+	}
+	if _, err := b.Execute(ctx, "UPDATE users SET name = 'Bea' WHERE id = 1"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := name(c); got != "Ann" {
+		t.Errorf("c sees b's unflushed update: read %s, want Ann", got)
+	}
+	if b.Tables["users"] == c.Tables["users"] {
+		t.Errorf("b and c share one in-memory table")
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"fmt"
+	"mini-rdbms/db/types"
+	"time"
+	"unicode/utf8"
+)
+
+// scalarFunction is a built-in function usable in a SELECT list or WHERE clause.
+type scalarFunction struct {
+	// resultType checks the argument types and returns the result type.
+	// An empty DataType stands for an untyped NULL.
+	resultType func(args []types.DataType) (types.DataType, error)
+	// call computes the result for one row; v supplies the clock.
+	call func(v evaluator, args []types.Value) (types.Value, error)
+}
+
+// scalarFunctions is keyed by upper-case function name.
+var scalarFunctions = map[string]scalarFunction{
+	"GREATEST": {resultType: sameTypeArgs("GREATEST"), call: extremum(1)},
+	"LEAST":    {resultType: sameTypeArgs("LEAST"), call: extremum(-1)},
+	"LENGTH":   {resultType: lengthType, call: length},
+	"NOW":      {resultType: nowType, call: now},
+}
+
+// timestampLayout is how NOW() and CURRENT_TIMESTAMP are written as TEXT (UTC).
+const timestampLayout = "2006-01-02 15:04:05"
+
+// nowType accepts no arguments; NOW() is TEXT in timestampLayout.
+func nowType(args []types.DataType) (types.DataType, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("NOW takes no arguments")
+	}
+	return types.TypeText, nil
+}
+
+// now reads v's clock.
+func now(v evaluator, args []types.Value) (types.Value, error) {
+	return timestampAs(v.now(), types.TypeText), nil
+}
+
+// timestampAs returns t as a value of type typ: Unix seconds for INT, the
+// UTC date for DATE, timestampLayout text otherwise.
+func timestampAs(t time.Time, typ types.DataType) types.Value {
+	t = t.UTC()
+	switch typ {
+	case types.TypeInt:
+		return types.NewInt(int(t.Unix()))
+	case types.TypeDate:
+		return types.NewDate(t)
+	}
+	return types.NewText(t.Format(timestampLayout))
+}
+
+// lengthType accepts a single TEXT argument.
+func lengthType(args []types.DataType) (types.DataType, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("LENGTH requires exactly one argument")
+	}
+	if args[0] != types.TypeText && args[0] != "" {
+		return "", fmt.Errorf("LENGTH: argument must be TEXT, got %s", args[0])
+	}
+	return types.TypeInt, nil
+}
+
+// length counts the characters in a TEXT value. LENGTH(NULL) is NULL.
+func length(v evaluator, args []types.Value) (types.Value, error) {
+	s, ok := args[0].Val.(string)
+	if !ok {
+		return types.NewNull(types.TypeInt), nil
+	}
+	return types.NewInt(utf8.RuneCountInString(s)), nil
+}
+
+// sameTypeArgs requires at least one argument and that all non-NULL
+// arguments share a type, which becomes the result type.
+func sameTypeArgs(name string) func([]types.DataType) (types.DataType, error) {
+	return func(args []types.DataType) (types.DataType, error) {
+		if len(args) == 0 {
+			return "", fmt.Errorf("%s requires at least one argument", name)
+		}
+		var result types.DataType
+		for _, t := range args {
+			if t == "" {
+				continue
+			}
+			if result != "" && t != result {
+				return "", fmt.Errorf("%s: incompatible argument types %s and %s", name, result, t)
+			}
+			result = t
+		}
+		return result, nil
+	}
+}
+
+// extremum returns the argument that compares as sign (1 for the largest,
+// -1 for the smallest). NULL arguments are ignored; the result is NULL only
+// when every argument is NULL.
+func extremum(sign int) func(evaluator, []types.Value) (types.Value, error) {
+	return func(v evaluator, args []types.Value) (types.Value, error) {
+		var best types.Value
+		for _, v := range args {
+			if v.IsNull() {
+				continue
+			}
+			if best.IsNull() {
+				best = v
+				continue
+			}
+			cmp, err := v.Compare(best)
+			if err != nil {
+				return types.Value{}, err
+			}
+			if cmp == sign {
+				best = v
+			}
+		}
+		return best, nil
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestGreatestLeast(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT, code TEXT)",
+		"INSERT INTO orders VALUES (1, 30, 'beta')",
+		"INSERT INTO orders VALUES (2, 75, 'alpha')",
+		"INSERT INTO orders VALUES (3, 250, 'gamma')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT id, GREATEST(amount, 100), LEAST(amount, 50) FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if res.Columns[1] != "GREATEST(amount, 100)" || res.ColumnTypes[1] != types.TypeInt {
+		t.Errorf("Unexpected column %s of type %s", res.Columns[1], res.ColumnTypes[1])
+	}
+	want := [][2]int{{100, 30}, {100, 50}, {250, 50}}
+	for i, w := range want {
+		if got := res.Rows[i].Values[1].Val; got != w[0] {
+			t.Errorf("Row %d: expected GREATEST %d, got %v", i, w[0], got)
+		}
+		if got := res.Rows[i].Values[2].Val; got != w[1] {
+			t.Errorf("Row %d: expected LEAST %d, got %v", i, w[1], got)
+		}
+	}
+
+	// Text arguments compare byte-wise; nested calls work.
+	res, err = e.Execute(ctx, "SELECT greatest(code, 'beta'), LEAST(code, 'b', LEAST('zz', 'c')) FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	wantText := [][2]string{{"beta", "b"}, {"beta", "alpha"}, {"gamma", "b"}}
+	for i, w := range wantText {
+		if got := res.Rows[i].Values[0].Val; got != w[0] {
+			t.Errorf("Row %d: expected GREATEST %s, got %v", i, w[0], got)
+		}
+		if got := res.Rows[i].Values[1].Val; got != w[1] {
+			t.Errorf("Row %d: expected LEAST %s, got %v", i, w[1], got)
+		}
+	}
+
+	// NULL arguments are ignored; all-NULL gives NULL.
+	res, err = e.Execute(ctx, "SELECT GREATEST(amount, NULL), LEAST(NULL, NULL) FROM orders WHERE id = 1")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if got := res.Rows[0].Values[0].Val; got != 30 {
+		t.Errorf("Expected NULL to be ignored, got %v", got)
+	}
+	if !res.Rows[0].Values[1].IsNull() {
+		t.Errorf("Expected NULL when every argument is NULL, got %v", res.Rows[0].Values[1])
+	}
+
+	for _, sql := range []string{
+		"SELECT GREATEST(amount, code) FROM orders",
+		"SELECT LEAST(amount, 'x') FROM orders",
+		"SELECT GREATEST() FROM orders",
+		"SELECT NOPE(amount) FROM orders",
+		"SELECT GREATEST(missing, 1) FROM orders",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
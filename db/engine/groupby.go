@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"sort"
+	"strconv"
+)
+
+// GroupByNode folds its input into one row per distinct combination of the
+// Keys columns, holding one value per SELECT field. Fields may use the keys
+// and aggregate calls. Groups that fail Having are dropped before they are
+// emitted. Without keys the whole input is one group, even when it is empty.
+// Groups come out in key order, NULL keys first.
+type GroupByNode struct {
+	Input  PlanNode
+	Keys   []*parser.ColumnRef
+	Fields []parser.Expression
+	Having parser.Expression
+
+	keyIdx  []int               // Input column of each key
+	keyColl []types.Collation   // Collation of each key
+	aggs    []parser.Expression // Distinct aggregate calls in Fields and Having
+	eval    evaluator
+}
+
+// newGroupByNode checks that every field and the HAVING condition only read
+// grouping columns outside of aggregate calls before building the node.
+func newGroupByNode(input PlanNode, keys []*parser.ColumnRef, fields []parser.Expression, having parser.Expression, eval evaluator) (*GroupByNode, error) {
+	def := input.Schema()
+	n := &GroupByNode{Input: input, Keys: keys, Fields: fields, Having: having, eval: eval}
+	for _, k := range keys {
+		idx, err := def.ResolveColumn(k.Table, k.Name)
+		if err != nil {
+			return nil, err
+		}
+		n.keyIdx = append(n.keyIdx, idx)
+		n.keyColl = append(n.keyColl, def.Columns[idx].Collation)
+	}
+
+	exprs := fields
+	if having != nil {
+		exprs = append(append([]parser.Expression{}, fields...), having)
+	}
+	seen := make(map[string]bool)
+	for _, expr := range exprs {
+		if isStar(expr) {
+			return nil, fmt.Errorf("* cannot be used with GROUP BY")
+		}
+		if ref := n.ungrouped(expr); ref != nil {
+			return nil, fmt.Errorf("column %s must appear in GROUP BY or be used in an aggregate function", ref)
+		}
+		for _, fn := range aggregateCalls(expr) {
+			if seen[fn.String()] {
+				continue
+			}
+			if _, err := eval.aggregateType(fn, def); err != nil {
+				return nil, err
+			}
+			seen[fn.String()] = true
+			n.aggs = append(n.aggs, fn)
+		}
+	}
+
+	groupDef := n.groupSchema()
+	for _, f := range fields {
+		if _, err := eval.scalarType(f, groupDef); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// ungrouped returns the first column expr reads outside an aggregate call
+// that is not a grouping key, or nil.
+func (n *GroupByNode) ungrouped(expr parser.Expression) *parser.ColumnRef {
+	isKey := func(ref *parser.ColumnRef) bool {
+		for _, k := range n.Keys {
+			if k.Table == ref.Table && k.Name == ref.Name {
+				return true
+			}
+		}
+		return false
+	}
+	switch e := expr.(type) {
+	case *parser.ColumnRef:
+		if !isKey(e) {
+			return e
+		}
+	case *parser.FunctionCall:
+		if isAggregate(e) {
+			return nil
+		}
+		for _, a := range e.Args {
+			if ref := n.ungrouped(a); ref != nil {
+				return ref
+			}
+		}
+	case *parser.InfixExpression:
+		if ref := n.ungrouped(e.Left); ref != nil {
+			return ref
+		}
+		return n.ungrouped(e.Right)
+	case *parser.NotExpression:
+		return n.ungrouped(e.Expr)
+	case *parser.ComparisonExpression:
+		if e.Left == nil {
+			if ref := (&parser.ColumnRef{Table: e.Table, Name: e.Column}); !isKey(ref) {
+				return ref
+			}
+		} else if ref := n.ungrouped(e.Left); ref != nil {
+			return ref
+		}
+		if e.RightExpr != nil {
+			if ref := n.ungrouped(e.RightExpr); ref != nil {
+				return ref
+			}
+		}
+		if e.Right != nil {
+			return n.ungrouped(e.Right)
+		}
+	}
+	return nil
+}
+
+// aggregateCalls returns the aggregate calls in expr, outermost first.
+func aggregateCalls(expr parser.Expression) []*parser.FunctionCall {
+	switch e := expr.(type) {
+	case *parser.FunctionCall:
+		if isAggregate(e) {
+			return []*parser.FunctionCall{e}
+		}
+		var calls []*parser.FunctionCall
+		for _, a := range e.Args {
+			calls = append(calls, aggregateCalls(a)...)
+		}
+		return calls
+	case *parser.InfixExpression:
+		return append(aggregateCalls(e.Left), aggregateCalls(e.Right)...)
+	case *parser.NotExpression:
+		return aggregateCalls(e.Expr)
+	case *parser.ComparisonExpression:
+		var calls []*parser.FunctionCall
+		if e.Left != nil {
+			calls = aggregateCalls(e.Left)
+		}
+		if e.RightExpr != nil {
+			calls = append(calls, aggregateCalls(e.RightExpr)...)
+		}
+		if e.Right != nil {
+			calls = append(calls, aggregateCalls(e.Right)...)
+		}
+		return calls
+	}
+	return nil
+}
+
+// groupSchema describes the row each group is folded into: the key columns
+// under their own names, then one column per aggregate call named after
+// the call, which is how EvaluateScalar finds an aggregate's result.
+func (n *GroupByNode) groupSchema() schema.TableDef {
+	def := n.Input.Schema()
+	var cols []schema.ColumnDef
+	for _, idx := range n.keyIdx {
+		col := def.Columns[idx]
+		if col.Table == "" {
+			col.Table = def.Name
+		}
+		cols = append(cols, col)
+	}
+	for _, fn := range n.aggs {
+		t, _ := n.eval.aggregateType(fn.(*parser.FunctionCall), def)
+		cols = append(cols, schema.ColumnDef{Name: fn.String(), Type: t})
+	}
+	return schema.TableDef{Name: def.Name, Columns: cols}
+}
+
+// group is the key and running aggregates of one GROUP BY group.
+type group struct {
+	key    []types.Value
+	states []aggregateState
+}
+
+func (n *GroupByNode) newGroup(key []types.Value, def schema.TableDef) *group {
+	g := &group{key: key, states: make([]aggregateState, len(n.aggs))}
+	for i, fn := range n.aggs {
+		g.states[i] = n.eval.newAggregateState(fn.(*parser.FunctionCall), def)
+	}
+	return g
+}
+
+// groups reads all of the input and returns the output row of every group
+// that passes Having.
+func (n *GroupByNode) groups(ctx context.Context) ([]storage.Row, error) {
+	def := n.Input.Schema()
+	byKey := make(map[string]*group)
+	var groups []*group
+	input := n.Input.Open(ctx)
+	for {
+		row, ok := input.Next()
+		if !ok {
+			break
+		}
+		key := make([]types.Value, len(n.keyIdx))
+		hash := ""
+		for i, idx := range n.keyIdx {
+			key[i] = row.Values[idx]
+			h := key[i].Fold(n.keyColl[i]).Hash()
+			hash += strconv.Itoa(len(h)) + ":" + h
+		}
+		g, ok := byKey[hash]
+		if !ok {
+			g = n.newGroup(key, def)
+			byKey[hash] = g
+			groups = append(groups, g)
+		}
+		if err := n.eval.accumulate(g.states, n.aggs, row, def); err != nil {
+			return nil, err
+		}
+	}
+	if err := input.Err(); err != nil {
+		return nil, err
+	}
+	if len(n.Keys) == 0 && len(groups) == 0 {
+		groups = append(groups, n.newGroup(nil, def))
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return keyLess(groups[i].key, groups[j].key, n.keyColl)
+	})
+
+	groupDef := n.groupSchema()
+	var out []storage.Row
+	for _, g := range groups {
+		values := append([]types.Value{}, g.key...)
+		for _, s := range g.states {
+			values = append(values, s.result())
+		}
+		folded := storage.Row{Values: values}
+		if n.Having != nil && !n.eval.matches(n.Having, folded, groupDef) {
+			continue
+		}
+		row := storage.Row{Values: make([]types.Value, len(n.Fields))}
+		for i, f := range n.Fields {
+			v, err := n.eval.scalar(f, folded, groupDef)
+			if err != nil {
+				return nil, err
+			}
+			row.Values[i] = v
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// keyLess orders group keys column by column under each key's collation,
+// NULL first.
+func keyLess(a, b []types.Value, colls []types.Collation) bool {
+	for i := range a {
+		if a[i].IsNull() || b[i].IsNull() {
+			if a[i].IsNull() != b[i].IsNull() {
+				return a[i].IsNull()
+			}
+			continue
+		}
+		if c, err := a[i].CompareCollated(b[i], colls[i]); err == nil && c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func (n *GroupByNode) Open(ctx context.Context) RowIterator {
+	var rows RowIterator
+	return iterate(func() (storage.Row, bool, error) {
+		if rows == nil {
+			out, err := n.groups(ctx)
+			if err != nil {
+				return storage.Row{}, false, err
+			}
+			rows = sliceIterator(ctx, out)
+		}
+		row, ok := rows.Next()
+		return row, ok, rows.Err()
+	})
+}
+
+func (n *GroupByNode) Schema() schema.TableDef {
+	groupDef := n.groupSchema()
+	cols := make([]schema.ColumnDef, len(n.Fields))
+	for i, f := range n.Fields {
+		t, _ := n.eval.scalarType(f, groupDef)
+		cols[i] = schema.ColumnDef{Name: f.String(), Type: t}
+	}
+	return schema.TableDef{Name: groupDef.Name, Columns: cols}
+}
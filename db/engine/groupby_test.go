@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGroupByHaving(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT)",
+		"INSERT INTO orders VALUES (1, 2, 80)",
+		"INSERT INTO orders VALUES (2, 1, 30)",
+		"INSERT INTO orders VALUES (3, 2, 50)",
+		"INSERT INTO orders VALUES (4, 1, 40)",
+		"INSERT INTO orders VALUES (5, 3, 500)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	rows := func(sql string) string {
+		t.Helper()
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var out []string
+		for _, r := range res.Rows {
+			out = append(out, fmt.Sprint(r.Values))
+		}
+		return strings.Join(out, " ")
+	}
+
+	// User 1 totals 70 and is dropped; users 2 and 3 pass.
+	sql := "SELECT user_id, SUM(amount) FROM orders GROUP BY user_id HAVING SUM(amount) > 100"
+	if got, want := rows(sql), "[2 130] [3 500]"; got != want {
+		t.Errorf("%s:\n got %s\nwant %s", sql, got, want)
+	}
+
+	// Without HAVING every group is kept, in key order.
+	sql = "SELECT user_id, COUNT(*) FROM orders GROUP BY user_id"
+	if got, want := rows(sql), "[1 2] [2 2] [3 1]"; got != want {
+		t.Errorf("%s:\n got %s\nwant %s", sql, got, want)
+	}
+
+	// HAVING may use an aggregate the SELECT list does not.
+	sql = "SELECT user_id FROM orders GROUP BY user_id HAVING COUNT(*) > 1 AND SUM(amount) < 100"
+	if got, want := rows(sql), "[1]"; got != want {
+		t.Errorf("%s:\n got %s\nwant %s", sql, got, want)
+	}
+
+	for _, bad := range []string{
+		"SELECT amount, SUM(amount) FROM orders GROUP BY user_id",
+		"SELECT * FROM orders GROUP BY user_id",
+		"SELECT user_id FROM orders GROUP BY nope",
+		"SELECT user_id FROM orders WHERE SUM(amount) > 1 GROUP BY user_id",
+	} {
+		if _, err := e.Execute(ctx, bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestGroupByOverJoin(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT)",
+		"INSERT INTO users VALUES (1, 'ann')",
+		"INSERT INTO users VALUES (2, 'ben')",
+		"INSERT INTO users VALUES (3, 'cy')",
+		"INSERT INTO orders VALUES (10, 1, 5)",
+		"INSERT INTO orders VALUES (11, 1, 7)",
+		"INSERT INTO orders VALUES (12, 2, 1)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		// cy has no orders, so the inner join leaves no group for them
+		{"SELECT users.name, COUNT(orders.id) FROM users JOIN orders ON users.id = orders.user_id GROUP BY users.name", "[ann 2] [ben 1]"},
+		{"SELECT users.name, COUNT(orders.id) FROM users, orders WHERE users.id = orders.user_id GROUP BY users.name", "[ann 2] [ben 1]"},
+		{"SELECT name, SUM(amount) FROM users JOIN orders ON users.id = orders.user_id GROUP BY name HAVING COUNT(orders.id) > 1", "[ann 12]"},
+		{"SELECT COUNT(*), COUNT(DISTINCT users.id), SUM(orders.amount) FROM users JOIN orders ON users.id = orders.user_id", "[3 2 13]"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		var out []string
+		for _, r := range res.Rows {
+			out = append(out, fmt.Sprint(r.Values))
+		}
+		if got := strings.Join(out, " "); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.sql, got, tt.want)
+		}
+	}
+
+	// The grouping sits above the join in the plan
+	res, err := e.Execute(ctx, "EXPLAIN SELECT users.name, COUNT(orders.id) FROM users JOIN orders ON users.id = orders.user_id GROUP BY users.name")
+	if err != nil {
+		t.Fatalf("Failed to explain: %v", err)
+	}
+	if plan := fmt.Sprint(res.Rows[0].Values[0], res.Rows[1].Values[0]); !strings.HasPrefix(plan, "Group by users.name") || !strings.Contains(plan, "join") {
+		t.Errorf("Expected Group by above the join, got %v", res.Rows)
+	}
+}
@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestCreateTableSecondaryIndex(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT, INDEX (user_id))",
+		"INSERT INTO orders VALUES (1, 10, 100)",
+		"INSERT INTO orders VALUES (2, 20, 200)",
+		"INSERT INTO orders VALUES (3, 10, 300)",
+		"INSERT INTO orders VALUES (4, 30, 400)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	if _, ok := e.Tables["orders"].SecondaryIndices["user_id"]; !ok {
+		t.Fatalf("Expected secondary index on user_id")
+	}
+
+	// The planner picks the index for equality on user_id.
+	stmt, err := parser.NewParser(parser.NewTokenizer("SELECT * FROM orders WHERE user_id = 10")).ParseStatement()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	plan, err := NewPlanner(e.Tables).CreatePlan(stmt)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if _, ok := plan.(*IndexScanNode); !ok {
+		t.Errorf("Expected IndexScanNode, got %T", plan)
+	}
+
+	assertIDs := func(sql string, want ...int) {
+		t.Helper()
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(res.Rows) != len(want) {
+			t.Fatalf("%s: expected %d rows, got %d", sql, len(want), len(res.Rows))
+		}
+		for i, w := range want {
+			if got, _ := res.Rows[i].Values[0].AsInt(); got != w {
+				t.Errorf("%s: row %d expected id %d, got %d", sql, i, w, got)
+			}
+		}
+	}
+
+	assertIDs("SELECT id FROM orders WHERE user_id = 10", 1, 3)
+
+	// Index follows updates and deletes.
+	if _, err := e.Execute(ctx, "UPDATE orders SET user_id = 10 WHERE id = 2"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if _, err := e.Execute(ctx, "DELETE FROM orders WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	assertIDs("SELECT id FROM orders WHERE user_id = 10", 2, 3)
+	assertIDs("SELECT id FROM orders WHERE user_id = 20")
+
+	// Index definition survives a reload.
+	e = NewEngineWithConfig(Config{DataDir: dir})
+	if _, err := e.Execute(ctx, "INSERT INTO orders VALUES (5, 30, 500)"); err != nil {
+		t.Fatalf("Failed to insert after reload: %v", err)
+	}
+	if _, ok := e.Tables["orders"].SecondaryIndices["user_id"]; !ok {
+		t.Fatalf("Expected secondary index after reload")
+	}
+	assertIDs("SELECT id FROM orders WHERE user_id = 30", 4, 5)
+
+	if _, err := e.Execute(ctx, "CREATE TABLE bad (id INT PRIMARY KEY, INDEX (nope))"); err == nil {
+		t.Errorf("Expected error indexing an unknown column")
+	}
+}
+
+func TestUniqueColumnFastPath(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, email TEXT UNIQUE, name TEXT)",
+		"INSERT INTO users VALUES (1, 'a@x.com', 'Ann')",
+		"INSERT INTO users VALUES (2, 'b@x.com', 'Ben')",
+		"INSERT INTO users VALUES (3, 'c@x.com', 'Cy')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	table := e.Tables["users"]
+
+	// UPDATE plans an index lookup on the unique column, and the keys it
+	// targets match a scan, for hits and misses.
+	for _, email := range []string{"b@x.com", "nobody@x.com"} {
+		sql := "UPDATE users SET name = 'X' WHERE email = '" + email + "'"
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		update := stmt.(*parser.UpdateStmt)
+		plan, err := e.planWrite(update, "users", update.Where)
+		if err != nil {
+			t.Fatalf("plan %s: %v", sql, err)
+		}
+		if _, ok := plan.(*IndexScanNode); !ok {
+			t.Fatalf("%s: expected an index lookup, planned %T", sql, plan)
+		}
+		keys, err := e.targetKeys(ctx, update, table, update.Where)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var scanned []interface{}
+		table.Scan(func(pk interface{}, row storage.Row) bool {
+			if Evaluate(update.Where.Expr, row, table.Def) {
+				scanned = append(scanned, pk)
+			}
+			return true
+		})
+		if len(keys) != len(scanned) || (len(keys) == 1 && keys[0] != scanned[0]) {
+			t.Errorf("%s: index gave %v, scan gave %v", email, keys, scanned)
+		}
+	}
+
+	if _, err := e.Execute(ctx, "UPDATE users SET name = 'Benny' WHERE email = 'b@x.com'"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if row, _ := table.GetRow(2); row.Values[2].Val != "Benny" {
+		t.Errorf("Expected name Benny, got %v", row.Values[2].Val)
+	}
+
+	res, err := e.Execute(ctx, "DELETE FROM users WHERE email = 'c@x.com'")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if res.Message != "Deleted 1 rows" {
+		t.Errorf("Unexpected message: %s", res.Message)
+	}
+	if _, ok := table.GetRow(3); ok {
+		t.Errorf("Expected row 3 to be deleted")
+	}
+}
+
+func TestPlannerScansLowSelectivityIndex(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+	if _, err := e.Execute(ctx, "CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, status TEXT, INDEX (user_id), INDEX (status))"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	rows := make([][]types.Value, 200)
+	for i := range rows {
+		status := "done"
+		if i%10 == 0 {
+			status = "open"
+		}
+		rows[i] = []types.Value{types.NewInt(i + 1), types.NewInt(i%50 + 1), types.NewText(status)}
+	}
+	if _, err := e.InsertMany("orders", rows); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	plan := func(sql string) PlanNode {
+		t.Helper()
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		node, err := NewPlanner(e.Tables).CreatePlan(stmt)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		return node
+	}
+
+	// 50 distinct user ids: about 4 rows each, so the index wins
+	if node := plan("SELECT * FROM orders WHERE user_id = 7"); fmt.Sprintf("%T", node) != "*engine.IndexScanNode" {
+		t.Errorf("Expected an index scan on user_id, got %T", node)
+	}
+	// Two statuses: an estimated 100 rows each, so scanning is cheaper
+	if node := plan("SELECT * FROM orders WHERE status = 'open'"); fmt.Sprintf("%T", node) != "*engine.ScanNode" {
+		t.Errorf("Expected a full scan for status, got %T", node)
+	}
+	// With another indexed term the planner ranges over that one instead
+	node := plan("SELECT * FROM orders WHERE status = 'open' AND id > 150")
+	if f, ok := node.(*FilterNode); !ok {
+		t.Errorf("Expected a filtered range scan, got %T", node)
+	} else if r, ok := f.Input.(*IndexRangeNode); !ok || r.IndexName != "id" {
+		t.Errorf("Expected a range scan on id, got %#v", f.Input)
+	}
+	// The primary key always uses its index
+	if node := plan("SELECT * FROM orders WHERE id = 3"); fmt.Sprintf("%T", node) != "*engine.IndexScanNode" {
+		t.Errorf("Expected an index scan on id, got %T", node)
+	}
+
+	res, err := e.Execute(ctx, "SELECT COUNT(*) FROM orders WHERE status = 'open'")
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if res.Rows[0].Values[0].Val != 20 {
+		t.Errorf("Expected 20 open orders, got %v", res.Rows[0].Values[0])
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"sort"
+)
+
+// informationSchema holds the read-only virtual tables that describe the
+// database itself. Their rows are built from the table definitions each time
+// they are queried and are never stored.
+var informationSchema = map[string]schema.TableDef{
+	"information_schema.tables": {
+		Name: "information_schema.tables",
+		Columns: []schema.ColumnDef{
+			{Name: "table_name", Type: types.TypeText},
+			{Name: "column_count", Type: types.TypeInt},
+			{Name: "row_count", Type: types.TypeInt},
+		},
+	},
+	"information_schema.columns": {
+		Name: "information_schema.columns",
+		Columns: []schema.ColumnDef{
+			{Name: "table_name", Type: types.TypeText},
+			{Name: "column_name", Type: types.TypeText},
+			{Name: "ordinal_position", Type: types.TypeInt},
+			{Name: "data_type", Type: types.TypeText},
+			{Name: "is_primary_key", Type: types.TypeText},
+			{Name: "is_unique", Type: types.TypeText},
+		},
+	},
+}
+
+// ValuesNode produces a fixed list of rows, such as an information_schema table.
+type ValuesNode struct {
+	Def  schema.TableDef
+	Rows []storage.Row
+}
+
+func (n *ValuesNode) Open(ctx context.Context) RowIterator {
+	return sliceIterator(ctx, n.Rows)
+}
+
+func (n *ValuesNode) Schema() schema.TableDef { return n.Def }
+
+// informationSchemaScan builds the rows of an information_schema table from
+// the planner's tables, ordered by table name and then column position.
+func (p *Planner) informationSchemaScan(def schema.TableDef) *ValuesNode {
+	names := make([]string, 0, len(p.Tables))
+	for name := range p.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rows []storage.Row
+	for _, name := range names {
+		t := p.Tables[name]
+		switch def.Name {
+		case "information_schema.tables":
+			rows = append(rows, storage.Row{Values: []types.Value{
+				types.NewText(name),
+				types.NewInt(len(t.Def.Columns)),
+				types.NewInt(t.RowCount()),
+			}})
+		case "information_schema.columns":
+			for i, col := range t.Def.Columns {
+				rows = append(rows, storage.Row{Values: []types.Value{
+					types.NewText(name),
+					types.NewText(col.Name),
+					types.NewInt(i + 1),
+					types.NewText(string(col.Type)),
+					yesNo(col.IsPrimary),
+					yesNo(col.IsUnique),
+				}})
+			}
+		}
+	}
+	return &ValuesNode{Def: def, Rows: rows}
+}
+
+func yesNo(b bool) types.Value {
+	if b {
+		return types.NewText("YES")
+	}
+	return types.NewText("NO")
+}
+
+// loadAllTables reads every table on disk into memory so information_schema
+// describes the whole database, not just the tables used so far.
+func (e *Engine) loadAllTables() error {
+	names, err := e.TableNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := e.getTable(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceDef returns the definition of a table a SELECT reads from, which may
+// be an information_schema table.
+func (e *Engine) sourceDef(name string) (schema.TableDef, error) {
+	if def, ok := informationSchema[name]; ok {
+		return def, e.loadAllTables()
+	}
+	t, err := e.getTable(name)
+	if err != nil {
+		return schema.TableDef{}, err
+	}
+	return t.Def, nil
+}
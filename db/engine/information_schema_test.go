@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInformationSchema(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, email TEXT UNIQUE, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT)",
+		"INSERT INTO users VALUES (1, 'a@x.com', 'Alice')",
+		"INSERT INTO users VALUES (2, 'b@x.com', 'Bob')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// A fresh engine still describes tables it has not loaded yet
+	e = NewEngineWithConfig(Config{DataDir: dir})
+
+	res, err := e.Execute(ctx, "SELECT column_name, ordinal_position, data_type, is_unique FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'email'")
+	if err != nil {
+		t.Fatalf("Failed to query columns: %v", err)
+	}
+	if len(res.Rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(res.Rows))
+	}
+	got := res.Rows[0].Values
+	if got[0].Val != "email" || got[1].Val != 2 || got[2].Val != "TEXT" || got[3].Val != "YES" {
+		t.Errorf("Unexpected row for users.email: %v", got)
+	}
+
+	res, err = e.Execute(ctx, "SELECT * FROM information_schema.tables")
+	if err != nil {
+		t.Fatalf("Failed to query tables: %v", err)
+	}
+	if len(res.Columns) != 3 || len(res.Rows) != 2 {
+		t.Fatalf("Expected 2 rows of 3 columns, got %v", res.Rows)
+	}
+	if res.Rows[1].Values[0].Val != "users" || res.Rows[1].Values[2].Val != 2 {
+		t.Errorf("Expected users with 2 rows last, got %v", res.Rows[1].Values)
+	}
+
+	res, err = e.Execute(ctx, "SELECT COUNT(*) FROM information_schema.columns")
+	if err != nil {
+		t.Fatalf("Failed to count columns: %v", err)
+	}
+	if res.Rows[0].Values[0].Val != 6 {
+		t.Errorf("Expected 6 columns, got %v", res.Rows[0].Values[0])
+	}
+
+	for _, sql := range []string{
+		"INSERT INTO information_schema.tables VALUES ('x', 1, 1)",
+		"CREATE TABLE information_schema.tables (id INT PRIMARY KEY)",
+		"SELECT nope FROM information_schema.columns",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected an error", sql)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestEngineTypedInsert(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE users (id INT PRIMARY KEY AUTO_INCREMENT, name TEXT, age INT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// Quotes and SQL fragments are stored verbatim; there is no SQL text to break.
+	name := "O'Brien'); DELETE FROM users; --"
+	if _, err := e.Insert("users", map[string]types.Value{
+		"name": {Type: types.TypeText, Val: name},
+		"age":  {Type: types.TypeInt, Val: 42},
+	}); err != nil {
+		t.Fatalf("Failed typed insert: %v", err)
+	}
+
+	res, err := e.Execute(ctx, "SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(res.Rows))
+	}
+	row := res.Rows[0].Values
+	if row[0].Type != types.TypeInt || row[0].Val != 1 {
+		t.Errorf("Expected auto id 1, got %#v", row[0])
+	}
+	if row[1].Type != types.TypeText || row[1].Val != name {
+		t.Errorf("Expected name %q, got %#v", name, row[1])
+	}
+	if _, ok := row[2].Val.(int); !ok || row[2].Type != types.TypeInt {
+		t.Errorf("Expected INT age, got %#v", row[2])
+	}
+
+	bad := []map[string]types.Value{
+		{"name": {Type: types.TypeText, Val: "x"}},                                                                             // missing age
+		{"name": {Type: types.TypeText, Val: "x"}, "age": {Type: types.TypeText, Val: "old"}},                                  // wrong type
+		{"name": {Type: types.TypeText, Val: "x"}, "age": {Type: types.TypeInt, Val: 1}, "zip": {Type: types.TypeInt, Val: 1}}, // unknown column
+	}
+	for i, vals := range bad {
+		if _, err := e.Insert("users", vals); err == nil {
+			t.Errorf("Case %d: expected error", i)
+		}
+	}
+	if _, err := e.Insert("nope", nil); err == nil {
+		t.Errorf("Expected error for unknown table")
+	}
+}
+
+func TestInsertManyReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY AUTO_INCREMENT, email TEXT UNIQUE, age INT CHECK (age >= 0))",
+		"INSERT INTO users VALUES (1, 'a@x.com', 30)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	text, num := types.NewText, types.NewInt
+	res, err := e.InsertMany("users", [][]types.Value{
+		{text("b@x.com"), num(20)},         // id omitted
+		{num(5), text("c@x.com"), num(40)}, // explicit id
+		{text("a@x.com"), num(50)},         // duplicate email
+		{text("d@x.com"), num(-1)},         // fails the CHECK
+		{num(6), text("e@x.com")},          // wrong value count
+		{num(5), text("f@x.com"), num(10)}, // id taken earlier in the batch
+		{text("g@x.com"), num(60)},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	if res.Inserted != 3 {
+		t.Errorf("Expected 3 rows inserted, got %d", res.Inserted)
+	}
+	if len(res.Errors) != 7 {
+		t.Fatalf("Expected an entry per row, got %d", len(res.Errors))
+	}
+	for i, wantErr := range []bool{false, false, true, true, true, true, false} {
+		if (res.Errors[i] != nil) != wantErr {
+			t.Errorf("Row %d: error = %v, want error %v", i, res.Errors[i], wantErr)
+		}
+	}
+
+	// The batch was saved
+	if n := diskRows(t, dir, "users"); n != 4 {
+		t.Errorf("Expected 4 rows on disk, got %d", n)
+	}
+
+	if _, err := e.InsertMany("nope", nil); err == nil {
+		t.Errorf("Expected error for unknown table")
+	}
+}
+
+func BenchmarkInsertMany(b *testing.B) {
+	const n = 500
+	ctx := context.Background()
+	setup := func(b *testing.B) *Engine {
+		e := NewEngineWithConfig(Config{DataDir: b.TempDir()})
+		if _, err := e.Execute(ctx, "CREATE TABLE t (id INT PRIMARY KEY, name TEXT)"); err != nil {
+			b.Fatal(err)
+		}
+		return e
+	}
+
+	b.Run("single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			e := setup(b)
+			for j := 1; j <= n; j++ {
+				if _, err := e.Execute(ctx, fmt.Sprintf("INSERT INTO t VALUES (%d, 'row %d')", j, j)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			e := setup(b)
+			rows := make([][]types.Value, n)
+			for j := range rows {
+				rows[j] = []types.Value{types.NewInt(j + 1), types.NewText(fmt.Sprintf("row %d", j+1))}
+			}
+			res, err := e.InsertMany("t", rows)
+			if err != nil || res.Inserted != n {
+				b.Fatalf("InsertMany: %v, %v", res, err)
+			}
+		}
+	})
+}
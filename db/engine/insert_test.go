@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestInsertValueCountError(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE users (id INT PRIMARY KEY, name TEXT, email TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"INSERT INTO users VALUES (1, 'Alice')", "INSERT INTO users: expected 3 values (id, name, email), got 2"},
+		{"INSERT INTO users VALUES (1, 'Alice', 'a@x.com', 'extra')", "INSERT INTO users: expected 3 values (id, name, email), got 4"},
+	}
+	for _, tt := range tests {
+		_, err := e.Execute(ctx, tt.sql)
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.sql)
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.sql, tt.want, err.Error())
+		}
+	}
+}
+
+func TestNegativeIntegers(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE accounts (id INT PRIMARY KEY, balance INT CHECK (balance > -1000), INDEX (balance))",
+		"INSERT INTO accounts VALUES (-1, -100)",
+		"INSERT INTO accounts VALUES (2, -500)",
+		"INSERT INTO accounts VALUES (3, 250)",
+		"UPDATE accounts SET balance = -50 WHERE id = -1",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		where string
+		want  string
+	}{
+		{"balance = -50", "[-1]"},
+		{"id = -1", "[-1]"},
+		{"balance < 0", "[-1 2]"},
+		{"balance BETWEEN -600 AND -100", "[2]"},
+		{"balance - 10 = -60", "[-1]"},
+		{"balance > -100 AND id > -5", "[-1 3]"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, "SELECT id FROM accounts WHERE "+tt.where)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.where, err)
+		}
+		var ids []interface{}
+		for _, r := range res.Rows {
+			ids = append(ids, r.Values[0].Val)
+		}
+		if got := fmt.Sprint(ids); got != tt.want {
+			t.Errorf("%s: expected ids %s, got %s", tt.where, tt.want, got)
+		}
+	}
+
+	// The CHECK with a negative bound survives a reload and still applies
+	e = NewEngineWithConfig(Config{DataDir: dir})
+	if _, err := e.Execute(ctx, "INSERT INTO accounts VALUES (4, -2000)"); err == nil {
+		t.Errorf("Expected the CHECK to reject -2000")
+	}
+}
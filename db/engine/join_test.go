@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestJoinWhereQualifiedColumn(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	// Both tables have an id and a status column.
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, status TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, status TEXT)",
+		"INSERT INTO users VALUES (1, 'active')",
+		"INSERT INTO users VALUES (2, 'banned')",
+		"INSERT INTO orders VALUES (10, 1, 'open')",
+		"INSERT INTO orders VALUES (11, 2, 'active')",
+		"INSERT INTO orders VALUES (12, 1, 'shipped')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		where string
+		want  []int
+	}{
+		{"users.status = 'active'", []int{10, 12}},
+		{"orders.status = 'active'", []int{11}},
+		{"users.id = 2", []int{11}},
+		{"orders.id = 12", []int{12}},
+		{"users.status = 'active' AND orders.status = 'open'", []int{10}},
+	}
+	for _, tt := range tests {
+		sql := "SELECT orders.id FROM orders JOIN users ON orders.user_id = users.id WHERE " + tt.where
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(res.Rows) != len(tt.want) {
+			t.Fatalf("%s: expected %d rows, got %d", tt.where, len(tt.want), len(res.Rows))
+		}
+		for i, id := range tt.want {
+			if got := res.Rows[i].Values[0].Val; got != id {
+				t.Errorf("%s: row %d: expected order %d, got %v", tt.where, i, id, got)
+			}
+		}
+	}
+
+	// A bare column present on both sides is ambiguous.
+	_, err := e.Execute(ctx, "SELECT orders.id FROM orders JOIN users ON orders.user_id = users.id WHERE status = 'active'")
+	if err == nil || err.Error() != "ambiguous column reference: status" {
+		t.Errorf("Expected ambiguous column error, got %v", err)
+	}
+}
+
+func TestJoinNameResolution(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+		"INSERT INTO users VALUES (2, 'Ben')",
+		"INSERT INTO orders VALUES (10, 2, 500)",
+		"INSERT INTO orders VALUES (11, 1, 700)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// Same-named columns resolve by qualifier, bare unique names by lookup,
+	// and the ON condition may be written in either order.
+	res, err := e.Execute(ctx, "SELECT users.id, orders.id, name, GREATEST(amount, 600) FROM orders JOIN users ON users.id = user_id")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	wantCols := []string{"users.id", "orders.id", "name", "GREATEST(amount, 600)"}
+	for i, c := range wantCols {
+		if res.Columns[i] != c {
+			t.Errorf("Column %d: expected %s, got %s", i, c, res.Columns[i])
+		}
+	}
+	want := [][]interface{}{{2, 10, "Ben", 600}, {1, 11, "Ann", 700}}
+	if len(res.Rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %d", len(want), len(res.Rows))
+	}
+	for i, w := range want {
+		for j, v := range w {
+			if got := res.Rows[i].Values[j].Val; got != v {
+				t.Errorf("Row %d col %d: expected %v, got %v", i, j, v, got)
+			}
+		}
+	}
+
+	for sql, wantErr := range map[string]string{
+		"SELECT id FROM orders JOIN users ON orders.user_id = users.id":          "ambiguous column reference: id",
+		"SELECT orders.name FROM orders JOIN users ON orders.user_id = users.id": "unknown column: orders.name",
+		"SELECT x.id FROM orders JOIN users ON orders.user_id = users.id":        "unknown table in column reference: x.id",
+		"SELECT name FROM orders JOIN users ON orders.user_id = orders.id":       "JOIN condition must compare columns of orders and users",
+		"SELECT name FROM users WHERE users.nope = 1":                            "unknown column: users.nope",
+	} {
+		_, err := e.Execute(ctx, sql)
+		if err == nil || err.Error() != wantErr {
+			t.Errorf("%s: expected %q, got %v", sql, wantErr, err)
+		}
+	}
+}
+
+func TestImplicitJoinMatchesExplicitJoin(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+		"INSERT INTO users VALUES (2, 'Ben')",
+		"INSERT INTO orders VALUES (10, 2, 500)",
+		"INSERT INTO orders VALUES (11, 1, 700)",
+		"INSERT INTO orders VALUES (12, 2, 50)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	pairs := [][2]string{
+		{
+			"SELECT * FROM orders JOIN users ON orders.user_id = users.id",
+			"SELECT * FROM orders, users WHERE orders.user_id = users.id",
+		},
+		{
+			"SELECT orders.id, name FROM orders JOIN users ON orders.user_id = users.id WHERE amount > 100",
+			"SELECT orders.id, name FROM orders, users WHERE amount > 100 AND users.id = orders.user_id",
+		},
+	}
+	for _, p := range pairs {
+		want, err := e.Execute(ctx, p[0])
+		if err != nil {
+			t.Fatalf("%s: %v", p[0], err)
+		}
+		got, err := e.Execute(ctx, p[1])
+		if err != nil {
+			t.Fatalf("%s: %v", p[1], err)
+		}
+		if len(got.Rows) != len(want.Rows) || len(got.Columns) != len(want.Columns) {
+			t.Fatalf("%s: expected %d rows x %d columns, got %d x %d", p[1], len(want.Rows), len(want.Columns), len(got.Rows), len(got.Columns))
+		}
+		for i := range want.Rows {
+			for j := range want.Rows[i].Values {
+				if got.Rows[i].Values[j] != want.Rows[i].Values[j] {
+					t.Errorf("%s: row %d col %d: expected %v, got %v", p[1], i, j, want.Rows[i].Values[j], got.Rows[i].Values[j])
+				}
+			}
+		}
+	}
+
+	// The WHERE equality becomes the join key instead of a post-join filter.
+	stmt, err := parser.NewParser(parser.NewTokenizer("SELECT * FROM orders, users WHERE users.id = orders.user_id")).ParseStatement()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if err := e.resolveSelect(stmt.(*parser.SelectStmt)); err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	plan, err := NewPlanner(e.Tables).CreatePlan(stmt)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	join, ok := plan.(*JoinNode)
+	if !ok || join.LeftCol != "user_id" || join.RightCol != "id" {
+		t.Errorf("Expected JoinNode on user_id = id, got %#v", plan)
+	}
+
+	// Without a join condition every pair is returned.
+	res, err := e.Execute(ctx, "SELECT orders.id, users.id FROM orders, users")
+	if err != nil {
+		t.Fatalf("Failed to cross join: %v", err)
+	}
+	if len(res.Rows) != 6 {
+		t.Errorf("Expected 6 rows from cross join, got %d", len(res.Rows))
+	}
+}
+
+func TestQualifiedStarInJoin(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT, status TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, status TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann', 'active')",
+		"INSERT INTO orders VALUES (10, 1, 'open')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		sql     string
+		columns string
+		row     string
+	}{
+		{"SELECT orders.*, users.name FROM orders JOIN users ON orders.user_id = users.id",
+			"[id user_id status users.name]", "[10 1 open Ann]"},
+		{"SELECT users.*, orders.* FROM orders JOIN users ON orders.user_id = users.id",
+			"[id name status id user_id status]", "[1 Ann active 10 1 open]"},
+		{"SELECT users.* FROM orders, users WHERE orders.user_id = users.id",
+			"[id name status]", "[1 Ann active]"},
+		{"SELECT orders.* FROM orders", "[id user_id status]", "[10 1 open]"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got := fmt.Sprint(res.Columns); got != tt.columns {
+			t.Errorf("%s: expected columns %s, got %s", tt.sql, tt.columns, got)
+		}
+		if len(res.Rows) != 1 || fmt.Sprint(res.Rows[0].Values) != tt.row {
+			t.Errorf("%s: expected row %s, got %v", tt.sql, tt.row, res.Rows)
+		}
+	}
+
+	if _, err := e.Execute(ctx, "SELECT items.* FROM orders JOIN users ON orders.user_id = users.id"); err == nil {
+		t.Errorf("Expected error for a table not in the query")
+	}
+}
+
+func TestJoinSkipsNullAndMismatchedKeys(t *testing.T) {
+	orders := &ValuesNode{
+		Def: schema.TableDef{Name: "orders", Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt},
+			{Name: "user_id", Type: types.TypeInt},
+		}},
+		Rows: []storage.Row{
+			{Values: []types.Value{types.NewInt(10), types.NewInt(1)}},
+			{Values: []types.Value{types.NewInt(11), types.NewNull(types.TypeInt)}},
+			{Values: []types.Value{types.NewInt(12), types.NewText("1")}},
+			{Values: []types.Value{types.NewInt(13), types.NewInt(2)}},
+		},
+	}
+	users := &ValuesNode{
+		Def: schema.TableDef{Name: "users", Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt},
+		}},
+		Rows: []storage.Row{
+			{Values: []types.Value{types.NewInt(1)}},
+			{Values: []types.Value{types.NewNull(types.TypeInt)}},
+			{Values: []types.Value{types.NewInt(2)}},
+		},
+	}
+
+	join := &JoinNode{Left: orders, Right: users, LeftCol: "user_id", RightCol: "id"}
+	rows, err := materialize(context.Background(), join)
+	if err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	// Order 11 has a NULL user_id and order 12 a TEXT one; neither joins,
+	// not even with the NULL user
+	if got := fmt.Sprint(rows); got != "[{[10 1 1]} {[13 2 2]}]" {
+		t.Errorf("Unexpected join rows: %s", got)
+	}
+}
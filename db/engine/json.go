@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+)
+
+// jsonValue converts a result value to a JSON-friendly Go value using the
+// column's declared type. NULL becomes nil.
+func jsonValue(v types.Value, t types.DataType) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+	switch t {
+	case types.TypeInt:
+		i, _ := v.AsInt()
+		return i
+	case types.TypeText:
+		s, _ := v.AsText()
+		return s
+	case types.TypeDate:
+		return v.String()
+	}
+	return v.Val
+}
+
+// RowValues converts one result row to JSON-friendly values, in column order.
+func (r *ResultSet) RowValues(row storage.Row) []interface{} {
+	vals := make([]interface{}, len(row.Values))
+	for i, v := range row.Values {
+		vals[i] = jsonValue(v, r.ColumnTypes[i])
+	}
+	return vals
+}
+
+// ToJSON encodes the rows as a JSON array of objects keyed by column name.
+// Keys follow the column order rather than being sorted, and each value is
+// converted by its column's type, so INT values are numbers, TEXT values
+// are strings, DATE values are YYYY-MM-DD strings and NULL is null.
+func (r *ResultSet) ToJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for n, row := range r.Rows {
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for i, v := range r.RowValues(row) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(r.Columns[i])
+			if err != nil {
+				return nil, err
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(val)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// WriteJSON writes ToJSON's output followed by a newline.
+func (r *ResultSet) WriteJSON(w io.Writer) error {
+	data, err := r.ToJSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/parser"
+	"testing"
+)
+
+func TestLikePrefixUsesIndexRange(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT UNIQUE, city TEXT)",
+		"INSERT INTO users VALUES (1, 'Jack', 'Oslo')",
+		"INSERT INTO users VALUES (2, 'Jane', 'Lima')",
+		"INSERT INTO users VALUES (3, 'Bob', 'Rome')",
+		"INSERT INTO users VALUES (4, 'Jb', 'Oslo')",
+		"INSERT INTO users VALUES (5, 'Jan', 'Kiev')",
+		"INSERT INTO users VALUES (6, 'jade', 'Lima')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := []struct {
+		where     string
+		wantRange bool
+		want      []int
+	}{
+		{"name LIKE 'Ja%'", true, []int{1, 2, 5}},
+		{"name LIKE 'Ja_e'", true, []int{2}},
+		{"name LIKE 'Jan'", true, []int{5}},
+		{"name LIKE '%a%'", false, []int{1, 2, 5, 6}},
+		{"name LIKE '_a%'", false, []int{1, 2, 5, 6}},
+		{"city LIKE 'Os%'", false, []int{1, 4}}, // no index on city
+	}
+	for _, tt := range tests {
+		sql := "SELECT id FROM users WHERE " + tt.where
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			t.Fatalf("parse %s: %v", sql, err)
+		}
+		if err := e.resolveSelect(stmt.(*parser.SelectStmt)); err != nil {
+			t.Fatalf("resolve %s: %v", sql, err)
+		}
+		plan, err := NewPlanner(e.Tables).CreatePlan(stmt)
+		if err != nil {
+			t.Fatalf("plan %s: %v", sql, err)
+		}
+		filter, ok := plan.(*FilterNode)
+		usedRange := false
+		if ok {
+			_, usedRange = filter.Input.(*IndexRangeNode)
+		}
+		if usedRange != tt.wantRange {
+			t.Errorf("%s: planned %T, range scan = %v, want %v", sql, plan, usedRange, tt.wantRange)
+		}
+
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var got []int
+		for _, r := range res.Rows {
+			got = append(got, r.Values[0].Val.(int))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got ids %v, want %v", sql, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got ids %v, want %v", sql, got, tt.want)
+				break
+			}
+		}
+	}
+
+	// The range follows index changes from updates and deletes.
+	for _, sql := range []string{
+		"UPDATE users SET name = 'Bjorn' WHERE id = 1",
+		"DELETE FROM users WHERE name LIKE 'Jan%'",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	res, err := e.Execute(ctx, "SELECT id FROM users WHERE name LIKE 'Ja%'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Rows) != 0 {
+		t.Errorf("expected no Ja%% names left, got %v", res.Rows)
+	}
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/storage"
+	"testing"
+)
+
+func TestLimitStopsScanEarly(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE items (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	const total = 200
+	for i := 1; i <= total; i++ {
+		sql := fmt.Sprintf("INSERT INTO items VALUES (%d, 'item%d')", i, i)
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	visited := 0
+	scan := &ScanNode{
+		Table: e.Tables["items"],
+		Predicate: func(storage.Row) bool {
+			visited++
+			return true
+		},
+	}
+	rows, err := materialize(ctx, &LimitNode{Input: scan, Limit: 1})
+	if err != nil {
+		t.Fatalf("materialize: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if visited != 1 {
+		t.Errorf("LIMIT 1 visited %d of %d rows, want 1", visited, total)
+	}
+
+	// The same holds with a join streaming the outer side.
+	visited = 0
+	join := &JoinNode{Left: scan, Right: &ScanNode{Table: e.Tables["items"]}, LeftCol: "id", RightCol: "id"}
+	rows, err = materialize(ctx, &LimitNode{Input: join, Limit: 1})
+	if err != nil {
+		t.Fatalf("materialize join: %v", err)
+	}
+	if len(rows) != 1 || visited != 1 {
+		t.Errorf("LIMIT 1 over join: got %d rows after visiting %d left rows, want 1 and 1", len(rows), visited)
+	}
+
+	res, err := e.Execute(ctx, "SELECT * FROM items LIMIT 3")
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if len(res.Rows) != 3 || res.Rows[2].Values[0].Val != 3 {
+		t.Errorf("unexpected LIMIT 3 result: %v", res.Rows)
+	}
+}
+
+func TestMaxResultRows(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir(), MaxResultRows: 3})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE items (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	const total = 50
+	for i := 1; i <= total; i++ {
+		sql := fmt.Sprintf("INSERT INTO items VALUES (%d, 'item%d')", i, i)
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT * FROM items",
+		"SELECT id FROM items WHERE id > 10",
+		"SELECT id FROM items LIMIT 4",
+	} {
+		_, err := e.Execute(ctx, sql)
+		if want := "result has more than 3 rows; add a LIMIT or narrow the WHERE clause"; err == nil || err.Error() != want {
+			t.Errorf("%s: expected %q, got %v", sql, want, err)
+		}
+	}
+
+	// Results within the cap are untouched, and so are writes
+	for sql, want := range map[string]int{
+		"SELECT * FROM items LIMIT 3":          3,
+		"SELECT id FROM items WHERE id <= 2":   2,
+		"SELECT COUNT(*) FROM items":           1,
+		"SELECT name FROM items WHERE id = 40": 1,
+	} {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(res.Rows) != want {
+			t.Errorf("%s: expected %d rows, got %d", sql, want, len(res.Rows))
+		}
+	}
+	res, err := e.Execute(ctx, "UPDATE items SET name = 'x' WHERE id > 10")
+	if err != nil || res.RowsAffected != total-10 {
+		t.Errorf("Expected the cap to leave UPDATE alone, got %v (%v)", res, err)
+	}
+}
@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"testing"
+)
+
+func TestMinMaxFastPathMatchesScan(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE events (id INT PRIMARY KEY, code TEXT UNIQUE, day DATE, score INT, INDEX (day))",
+		"INSERT INTO events VALUES (7, 'm', DATE '2024-03-01', 5)",
+		"INSERT INTO events VALUES (-2, 'b', DATE '2023-12-31', 9)",
+		"INSERT INTO events VALUES (40, 'z', DATE '2024-01-15', 1)",
+		"INSERT INTO events VALUES (3, 'a', DATE '2024-03-01', 4)",
+		"DELETE FROM events WHERE id = 3",
+		"UPDATE events SET id = 50 WHERE id = 40",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	value := func(sql string) string {
+		t.Helper()
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(res.Rows) != 1 || len(res.Rows[0].Values) != 1 {
+			t.Fatalf("%s: expected a single value, got %v", sql, res.Rows)
+		}
+		return res.Rows[0].Values[0].String()
+	}
+
+	// The WHERE clause forces a full scan through the aggregate node.
+	tests := []struct {
+		fast, scan string
+		want       string
+	}{
+		{"SELECT MIN(id) FROM events", "SELECT MIN(id) FROM events WHERE score > 0", "-2"},
+		{"SELECT MAX(id) FROM events", "SELECT MAX(id) FROM events WHERE score > 0", "50"},
+		{"SELECT MIN(code) FROM events", "SELECT MIN(code) FROM events WHERE score > 0", "b"},
+		{"SELECT MAX(code) FROM events", "SELECT MAX(code) FROM events WHERE score > 0", "z"},
+		{"SELECT MIN(day) FROM events", "SELECT MIN(day) FROM events WHERE score > 0", "2023-12-31"},
+		{"SELECT MAX(day) FROM events", "SELECT MAX(day) FROM events WHERE score > 0", "2024-03-01"},
+	}
+	for _, tt := range tests {
+		fast, scanned := value(tt.fast), value(tt.scan)
+		if fast != tt.want || scanned != fast {
+			t.Errorf("%s = %v, scanned %v, want %s for both", tt.fast, fast, scanned, tt.want)
+		}
+	}
+	if got := value("SELECT MAX(score) FROM events"); got != "9" {
+		t.Errorf("MAX(score) = %v, want 9", got)
+	}
+
+	// Only a bare MIN or MAX of an indexed column takes the fast path.
+	plans := map[string]bool{
+		"SELECT MIN(id) FROM events":               true,
+		"SELECT MAX(day) FROM events":              true,
+		"SELECT MAX(score) FROM events":            false,
+		"SELECT MIN(id) FROM events WHERE id > 0":  false,
+		"SELECT MIN(id), MAX(id) FROM events":      false,
+		"SELECT MIN(id) FROM events GROUP BY code": false,
+	}
+	for sql, wantFast := range plans {
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			t.Fatalf("parse %s: %v", sql, err)
+		}
+		if err := e.resolveSelect(stmt.(*parser.SelectStmt)); err != nil {
+			t.Fatalf("resolve %s: %v", sql, err)
+		}
+		plan, err := NewPlanner(e.Tables).CreatePlan(stmt)
+		if err != nil {
+			t.Fatalf("plan %s: %v", sql, err)
+		}
+		if _, isFast := plan.(*MinMaxNode); isFast != wantFast {
+			t.Errorf("%s: planned %T, fast path = %v, want %v", sql, plan, isFast, wantFast)
+		}
+	}
+
+	// An empty table has no bounds, on either path.
+	if _, err := e.Execute(ctx, "DELETE FROM events"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for _, sql := range []string{"SELECT MIN(id) FROM events", "SELECT MAX(score) FROM events"} {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if len(res.Rows) != 1 || !res.Rows[0].Values[0].IsNull() {
+			t.Errorf("%s: expected NULL, got %v", sql, res.Rows)
+		}
+	}
+}
+
+func TestMinMaxFollowsCollation(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	// Byte-wise, 'Mango' < 'Zebra' < 'apple'; under NOCASE 'apple' comes first
+	stmts := []string{
+		"CREATE TABLE fruit (id INT PRIMARY KEY, name TEXT COLLATE NOCASE, INDEX (name))",
+		"INSERT INTO fruit VALUES (1, 'apple')",
+		"INSERT INTO fruit VALUES (2, 'Zebra')",
+		"INSERT INTO fruit VALUES (3, 'Mango')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SHOW STATS fruit")
+	if err != nil {
+		t.Fatalf("SHOW STATS: %v", err)
+	}
+	stats := res.Rows[1].Values
+	if stats[1].Val != "apple" || stats[2].Val != "Zebra" {
+		t.Fatalf("SHOW STATS min/max = %v/%v, want apple/Zebra", stats[1], stats[2])
+	}
+
+	for _, tt := range []struct{ sql, want string }{
+		{"SELECT MIN(name) FROM fruit", "apple"},
+		{"SELECT MAX(name) FROM fruit", "Zebra"},
+		{"SELECT MIN(name) FROM fruit WHERE id > 0", "apple"},
+		{"SELECT MAX(name) FROM fruit WHERE id > 0", "Zebra"},
+		{"SELECT MIN(name) FROM fruit HAVING COUNT(*) > 0", "apple"},
+	} {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got := res.Rows[0].Values[0].String(); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.sql, got, tt.want)
+		}
+	}
+
+	// The index on name is in byte order, so it cannot answer MIN or MAX
+	if _, ok := isIndexedMinMax(&parser.FunctionCall{Name: "MIN", Args: []parser.Expression{&parser.ColumnRef{Name: "name"}}}, e.Tables["fruit"]); ok {
+		t.Errorf("expected MIN(name) on a NOCASE column to scan")
+	}
+}
+
+func TestAggregatesAgreeOnCollation(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE paint (id INT PRIMARY KEY, color TEXT COLLATE NOCASE)",
+		"INSERT INTO paint VALUES (1, 'Red')",
+		"INSERT INTO paint VALUES (2, 'apple')",
+		"INSERT INTO paint VALUES (3, 'red')",
+		"INSERT INTO paint VALUES (4, 'Zebra')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	rows := func(sql string) string {
+		t.Helper()
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var out [][]interface{}
+		for _, row := range res.Rows {
+			var vals []interface{}
+			for _, v := range row.Values {
+				vals = append(vals, v.Val)
+			}
+			out = append(out, vals)
+		}
+		return fmt.Sprint(out)
+	}
+
+	// 'Red' and 'red' are one value to every aggregate
+	tests := []struct{ sql, want string }{
+		{"SELECT COUNT(DISTINCT color), MIN(color), MAX(color) FROM paint", "[[3 apple Zebra]]"},
+		{"SELECT color, COUNT(*) FROM paint GROUP BY color", "[[apple 1] [Red 2] [Zebra 1]]"},
+		{"SHOW STATS paint", "[[id 1 4 4] [color apple Zebra 3]]"},
+	}
+	for _, tt := range tests {
+		if got := rows(tt.sql); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSelectInsertionOrderTable(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE logs (id INT PRIMARY KEY, msg TEXT) INSERTION_ORDER",
+		"INSERT INTO logs VALUES (3, 'boot')",
+		"INSERT INTO logs VALUES (1, 'login')",
+		"INSERT INTO logs VALUES (2, 'logout')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT msg FROM logs")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	want := []string{"boot", "login", "logout"}
+	if len(res.Rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %d", len(want), len(res.Rows))
+	}
+	for i, msg := range want {
+		if res.Rows[i].Values[0].Val != msg {
+			t.Errorf("Row %d: expected %s, got %v", i, msg, res.Rows[i].Values[0].Val)
+		}
+	}
+}
@@ -3,31 +3,132 @@ package engine
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"mini-rdbms/db/parser"
 	"mini-rdbms/db/schema"
 	"mini-rdbms/db/storage"
 	"mini-rdbms/db/types"
-	"strings"
+	"time"
 )
 
 // PlanNode interface for execution plan steps.
+//
+// Nodes produce rows one at a time through the iterator returned by Open, so
+// a LIMIT can stop its input early instead of waiting for a full scan. Use
+// materialize when every row is needed at once.
 type PlanNode interface {
-	// Execute runs the node and returns rows.
-	// For simplicity, we return fully materialized rows.
-	Execute(ctx context.Context) ([]storage.Row, error)
+	// Open starts a pass over the node's rows.
+	Open(ctx context.Context) RowIterator
 	Schema() schema.TableDef
 }
 
+// RowIterator yields a node's rows in order. Next returns false once the rows
+// are exhausted or an error occurred; Err reports which.
+type RowIterator interface {
+	Next() (storage.Row, bool)
+	Err() error
+}
+
+// funcIterator adapts a step function into a RowIterator. The step returns
+// the next row, whether there was one, and any error; after the first false
+// or error the iterator stays finished.
+type funcIterator struct {
+	step func() (storage.Row, bool, error)
+	err  error
+	done bool
+}
+
+func iterate(step func() (storage.Row, bool, error)) RowIterator {
+	return &funcIterator{step: step}
+}
+
+func (it *funcIterator) Next() (storage.Row, bool) {
+	if it.done {
+		return storage.Row{}, false
+	}
+	row, ok, err := it.step()
+	if err != nil || !ok {
+		it.err = err
+		it.done = true
+		return storage.Row{}, false
+	}
+	return row, true
+}
+
+func (it *funcIterator) Err() error { return it.err }
+
+// sliceIterator yields rows from an already materialized slice, checking for
+// cancellation before each one.
+func sliceIterator(ctx context.Context, rows []storage.Row) RowIterator {
+	i := 0
+	return iterate(func() (storage.Row, bool, error) {
+		if ctx.Err() != nil {
+			return storage.Row{}, false, ctx.Err()
+		}
+		if i >= len(rows) {
+			return storage.Row{}, false, nil
+		}
+		i++
+		return rows[i-1], true, nil
+	})
+}
+
+// materialize runs a node to completion and collects its rows.
+func materialize(ctx context.Context, n PlanNode) ([]storage.Row, error) {
+	return materializeAtMost(ctx, n, 0)
+}
+
+// materializeAtMost is materialize with a bound: more than max rows is an
+// error, found as soon as the next row is read, so an oversized result is
+// never held in full. Zero means no bound.
+func materializeAtMost(ctx context.Context, n PlanNode, max int) ([]storage.Row, error) {
+	it := n.Open(ctx)
+	var rows []storage.Row
+	for {
+		row, ok := it.Next()
+		if !ok {
+			break
+		}
+		if max > 0 && len(rows) == max {
+			return nil, fmt.Errorf("result has more than %d rows; add a LIMIT or narrow the WHERE clause", max)
+		}
+		rows = append(rows, row)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
 // Planner converts AST to Plan.
 type Planner struct {
 	Tables map[string]*storage.Table
+	// SampleSeed seeds TABLESAMPLE. Zero means a time-based (non-reproducible) seed.
+	SampleSeed int64
+	// HashSemiJoin answers "col IN (SELECT ...)" by probing a hash set of the
+	// subquery's values instead of comparing against each one. On by default.
+	HashSemiJoin bool
+	// PromoteOverflow makes integer arithmetic and SUM return NUMERIC
+	// instead of failing on overflow (OverflowPromote).
+	PromoteOverflow bool
+	// Clock is read by NOW(). Nil means time.Now.
+	Clock func() time.Time
+}
+
+// eval is the evaluator for expressions in p's plans.
+func (p *Planner) eval() evaluator {
+	return evaluator{promote: p.PromoteOverflow, clock: p.Clock}
 }
 
 func NewPlanner(tables map[string]*storage.Table) *Planner {
-	return &Planner{Tables: tables}
+	return &Planner{Tables: tables, HashSemiJoin: true}
 }
 
 func (p *Planner) CreatePlan(stmt parser.Statement) (PlanNode, error) {
+	if err := p.checkColumns(stmt); err != nil {
+		return nil, err
+	}
 	switch s := stmt.(type) {
 	case *parser.SelectStmt:
 		node, err := p.planSelect(s)
@@ -35,10 +136,23 @@ func (p *Planner) CreatePlan(stmt parser.Statement) (PlanNode, error) {
 			return nil, err
 		}
 
+		if isGrouped(s) {
+			node, err = newGroupByNode(node, s.GroupBy, s.Fields, s.Having, p.eval())
+		} else if hasAggregates(s.Fields) {
+			node, err = p.planAggregate(s, node)
+		}
+		if err != nil {
+			return nil, err
+		}
+
 		if s.Limit > 0 {
 			node = &LimitNode{Input: node, Limit: s.Limit}
 		}
 		return node, nil
+	case *parser.UpdateStmt:
+		return p.planWrite(s.TableName, s.Where)
+	case *parser.DeleteStmt:
+		return p.planWrite(s.TableName, s.Where)
 	default:
 		return nil, fmt.Errorf("planning not implemented for this statement")
 	}
@@ -52,77 +166,132 @@ type LimitNode struct {
 	Limit int
 }
 
-func (n *LimitNode) Execute(ctx context.Context) ([]storage.Row, error) {
-	rows, err := n.Input.Execute(ctx)
-	if err != nil {
-		return nil, err
-	}
-	if len(rows) > n.Limit {
-		return rows[:n.Limit], nil
-	}
-	return rows, nil
+// Open stops pulling from its input once Limit rows have been returned, so
+// the scan beneath it ends early.
+func (n *LimitNode) Open(ctx context.Context) RowIterator {
+	input := n.Input.Open(ctx)
+	seen := 0
+	return iterate(func() (storage.Row, bool, error) {
+		if seen >= n.Limit {
+			return storage.Row{}, false, nil
+		}
+		row, ok := input.Next()
+		if !ok {
+			return storage.Row{}, false, input.Err()
+		}
+		seen++
+		return row, true, nil
+	})
 }
 func (n *LimitNode) Schema() schema.TableDef { return n.Input.Schema() }
 
+// SampleNode keeps each input row independently with probability Percent/100
+// (Bernoulli sampling). The output is non-deterministic unless Rand was seeded
+// with a fixed value.
+type SampleNode struct {
+	Input   PlanNode
+	Percent int
+	Rand    *rand.Rand
+}
+
+func (n *SampleNode) Open(ctx context.Context) RowIterator {
+	input := n.Input.Open(ctx)
+	return iterate(func() (storage.Row, bool, error) {
+		for {
+			row, ok := input.Next()
+			if !ok {
+				return storage.Row{}, false, input.Err()
+			}
+			if n.Rand.Intn(100) < n.Percent {
+				return row, true, nil
+			}
+		}
+	})
+}
+func (n *SampleNode) Schema() schema.TableDef { return n.Input.Schema() }
+
 // ScanNode represents a full table scan or index lookup (if Range is set - simplified).
+// Rows are produced in primary-key order (insertion order for tables created
+// with INSERTION_ORDER) so results are deterministic.
 type ScanNode struct {
 	Table     *storage.Table
 	Predicate func(storage.Row) bool
+	Where     parser.Expression // The condition Predicate tests, for EXPLAIN; nil if none
 }
 
-func (n *ScanNode) Execute(ctx context.Context) ([]storage.Row, error) {
-	var results []storage.Row
-	// Use Safe Scan
-	n.Table.Scan(func(pk interface{}, row storage.Row) bool {
-		// Build-in cancellation check?
-		// Table.Scan doesn't support it yet, so check here.
-		select {
-		case <-ctx.Done():
-			return false // Stop scan
-		default:
-		}
+func (n *ScanNode) Open(ctx context.Context) RowIterator {
+	// Consistent view of the table, in PK order (or insertion order for
+	// tables that track it)
+	rows := n.Table.ScanSnapshot()
+	i := 0
+	return iterate(func() (storage.Row, bool, error) {
+		for i < len(rows) {
+			// Check for cancellation on every row
+			if ctx.Err() != nil {
+				return storage.Row{}, false, ctx.Err()
+			}
+			row := rows[i]
+			i++
 
-		// Apply predicate
-		if n.Predicate != nil {
-			if !n.Predicate(row) {
-				return true // Continue
+			// Apply predicate
+			if n.Predicate != nil && !n.Predicate(row) {
+				continue
 			}
+			return row, true, nil
 		}
-		results = append(results, row)
-		return true // Continue
+		return storage.Row{}, false, nil
 	})
-
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	return results, nil
 }
 func (n *ScanNode) Schema() schema.TableDef { return n.Table.Def }
 
-// IndexScanNode represents an index lookup (O(1)).
+// IndexScanNode represents an index lookup (O(1)) on a unique or secondary index.
 type IndexScanNode struct {
 	Table     *storage.Table
 	IndexName string
 	Value     types.Value
 }
 
-func (n *IndexScanNode) Execute(ctx context.Context) ([]storage.Row, error) {
+func (n *IndexScanNode) Open(ctx context.Context) RowIterator {
 	if ctx.Err() != nil {
-		return nil, ctx.Err()
+		return sliceIterator(ctx, nil)
 	}
-	pk, found := n.Table.IndexLookup(n.IndexName, n.Value)
-	if !found {
-		return []storage.Row{}, nil
+	pks, _ := n.Table.IndexLookupAll(n.IndexName, n.Value)
+	// Rows deleted between lookup and fetch are skipped
+	return sliceIterator(ctx, n.Table.GetRows(pks))
+}
+func (n *IndexScanNode) Schema() schema.TableDef { return n.Table.Def }
+
+// IndexRangeNode reads the rows whose indexed column lies in [From, To),
+// found by binary search over the index's ordered keys. A NULL From or To
+// leaves that end of the range open. Rows come back in primary-key order.
+type IndexRangeNode struct {
+	Table     *storage.Table
+	IndexName string
+	From      types.Value
+	To        types.Value
+}
+
+func (n *IndexRangeNode) Open(ctx context.Context) RowIterator {
+	if ctx.Err() != nil {
+		return sliceIterator(ctx, nil)
 	}
-	row, ok := n.Table.GetRow(pk)
-	if !ok {
-		// Inconsistency?
-		return []storage.Row{}, nil
+	pks, _ := n.Table.IndexRange(n.IndexName, n.From, n.To)
+	return sliceIterator(ctx, n.Table.GetRows(pks))
+}
+func (n *IndexRangeNode) Schema() schema.TableDef { return n.Table.Def }
+
+// prefixEnd returns the smallest string greater than every string starting
+// with prefix ("Ja" -> "Jb"), or NULL if there is none.
+func prefixEnd(prefix string) types.Value {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return types.NewText(string(b[:i+1]))
+		}
 	}
-	return []storage.Row{row}, nil
+	return types.NewNull(types.TypeText)
 }
-func (n *IndexScanNode) Schema() schema.TableDef { return n.Table.Def }
 
 // JoinNode implements INNER JOIN using the Nested Loop Join algorithm.
 //
@@ -157,90 +326,106 @@ type JoinNode struct {
 
 	// Join condition: LeftCol = RightCol
 	// Example: "user_id" = "id" for orders.user_id = users.id
+	// Both empty means a cross join (every pair of rows).
 	LeftCol  string
 	RightCol string
 }
 
-// Execute performs the INNER JOIN operation.
+// Open performs the INNER JOIN operation.
 //
 // ALGORITHM: Nested Loop Join
-//  1. Materialize left relation (all rows from Left table)
-//  2. Materialize right relation (all rows from Right table)
+//  1. Materialize right relation (all rows from Right table)
+//  2. Stream left relation one row at a time
 //  3. For each row in Left:
 //     For each row in Right:
 //     If Left[LeftCol] == Right[RightCol]:
-//     Combine rows and add to result
+//     Combine rows and emit them
+//
+// Only the inner side is held in memory, so a LIMIT above the join stops
+// reading the left relation as soon as it has enough rows.
 //
 // TIME COMPLEXITY: O(|R| * |S|) where |R| = left rows, |S| = right rows
-// SPACE COMPLEXITY: O(|R| + |S| + |Result|)
+// SPACE COMPLEXITY: O(|S|)
 //
 // DETERMINISM GUARANTEE:
 // Results are deterministic because:
-// - Input rows are sorted by primary key (via GetSnapshot)
+// - ScanNode inputs are sorted by primary key (via Table.SortedSnapshot)
 // - Iteration order is stable (slice iteration, not map)
 // - Join condition is deterministic (equality check)
-func (n *JoinNode) Execute(ctx context.Context) ([]storage.Row, error) {
-	// Step 1: Materialize left relation
-	leftRows, err := n.Left.Execute(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	// Step 2: Materialize right relation
+func (n *JoinNode) Open(ctx context.Context) RowIterator {
+	// Step 1: Materialize right relation
 	// Note: For optimization, if Right is an IndexScanNode, we could
 	// iterate Left and perform index lookups instead of full materialization.
 	// Current implementation prioritizes simplicity and correctness.
-	rightRows, err := n.Right.Execute(ctx)
+	rightRows, err := materialize(ctx, n.Right)
 	if err != nil {
-		return nil, err
+		return iterate(func() (storage.Row, bool, error) { return storage.Row{}, false, err })
 	}
 
-	// Prepare result accumulator
-	var results []storage.Row
-
 	// Get schemas to locate join columns
 	lSchema := n.Left.Schema()
 	rSchema := n.Right.Schema()
 
-	// Find column indices for join condition
+	// Find column indices for join condition; no columns means a cross join
+	cross := n.LeftCol == "" && n.RightCol == ""
 	lIdx := lSchema.GetColumnIndex(n.LeftCol)
 	rIdx := rSchema.GetColumnIndex(n.RightCol)
 
-	if lIdx == -1 || rIdx == -1 {
-		return nil, fmt.Errorf("join columns not found: %s, %s", n.LeftCol, n.RightCol)
+	if !cross && (lIdx == -1 || rIdx == -1) {
+		err := fmt.Errorf("join columns not found: %s, %s", n.LeftCol, n.RightCol)
+		return iterate(func() (storage.Row, bool, error) { return storage.Row{}, false, err })
 	}
 
-	// Step 3: Nested loop join
-	// Outer loop: iterate through left relation
-	for _, lRow := range leftRows {
-		// Check for cancellation (allows query timeout/cancellation)
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
-		}
+	// Step 2: Stream left relation
+	left := n.Left.Open(ctx)
+	var lRow storage.Row
+	rPos := len(rightRows) // forces the first left row to be fetched
+
+	// Step 3: Nested loop join, resumed on each call
+	return iterate(func() (storage.Row, bool, error) {
+		for {
+			if rPos >= len(rightRows) {
+				// Outer loop: advance to the next left row
+				var ok bool
+				if lRow, ok = left.Next(); !ok {
+					return storage.Row{}, false, left.Err()
+				}
+				rPos = 0
+			}
+			// Check for cancellation (allows query timeout/cancellation)
+			if ctx.Err() != nil {
+				return storage.Row{}, false, ctx.Err()
+			}
+
+			// Inner loop: iterate through right relation
+			rRow := rightRows[rPos]
+			rPos++
 
-		// Inner loop: iterate through right relation
-		for _, rRow := range rightRows {
 			// Evaluate join condition: Left[LeftCol] == Right[RightCol]
-			// Uses type-safe comparison from types.Value
-			cmp, err := lRow.Values[lIdx].Compare(rRow.Values[rIdx])
+			match := cross || joinKeysEqual(lRow.Values[lIdx], rRow.Values[rIdx])
 
-			// If comparison succeeds and values are equal (cmp == 0)
-			if err == nil && cmp == 0 {
-				// INNER JOIN: Combine matching rows
+			// INNER JOIN semantics: only matching rows are emitted
+			if match {
 				// Result schema: [Left columns..., Right columns...]
-				combined := storage.Row{
-					Values: append(lRow.Values, rRow.Values...),
-				}
-				results = append(results, combined)
+				// Copy into a fresh slice; appending to lRow.Values could
+				// share its backing array between result rows.
+				values := make([]types.Value, 0, len(lRow.Values)+len(rRow.Values))
+				values = append(values, lRow.Values...)
+				return storage.Row{Values: append(values, rRow.Values...)}, true, nil
 			}
-			// If values don't match (cmp != 0), skip this combination
-			// This is the INNER JOIN semantics: only matching rows included
 		}
-	}
+	})
+}
 
-	// Return all matching row combinations
-	// If no matches found, returns empty slice (not an error)
-	return results, nil
+// joinKeysEqual reports whether two join keys match. As in standard SQL a
+// NULL key matches nothing, not even another NULL, and keys of different
+// types never match rather than failing the join.
+func joinKeysEqual(a, b types.Value) bool {
+	if a.IsNull() || b.IsNull() || a.Type != b.Type {
+		return false
+	}
+	cmp, err := a.Compare(b)
+	return err == nil && cmp == 0
 }
 
 // Schema returns the combined schema of the joined tables.
@@ -256,32 +441,258 @@ func (n *JoinNode) Schema() schema.TableDef {
 	r := n.Right.Schema()
 	return schema.TableDef{
 		Name:    l.Name + "_" + r.Name, // Virtual name for joined relation
-		Columns: append(l.Columns, r.Columns...),
+		Columns: append(withSource(l), withSource(r)...),
 	}
 }
 
+// withSource copies a schema's columns, recording which table each came from
+// so qualified references still resolve after a join.
+func withSource(def schema.TableDef) []schema.ColumnDef {
+	cols := make([]schema.ColumnDef, len(def.Columns))
+	for i, c := range def.Columns {
+		if c.Table == "" {
+			c.Table = def.Name
+		}
+		cols[i] = c
+	}
+	return cols
+}
+
+// FilterNode keeps rows that satisfy a WHERE expression evaluated against
+// its input's schema. It applies WHERE after a join, where references like
+// orders.amount must resolve against the combined columns.
+type FilterNode struct {
+	Input PlanNode
+	Expr  parser.Expression
+
+	eval evaluator
+}
+
+func (n *FilterNode) Open(ctx context.Context) RowIterator {
+	input := n.Input.Open(ctx)
+	def := n.Input.Schema()
+	return iterate(func() (storage.Row, bool, error) {
+		for {
+			row, ok := input.Next()
+			if !ok {
+				return storage.Row{}, false, input.Err()
+			}
+			if n.eval.matches(n.Expr, row, def) {
+				return row, true, nil
+			}
+		}
+	})
+}
+func (n *FilterNode) Schema() schema.TableDef { return n.Input.Schema() }
+
 // --- Planning Logic ---
 
+// planAggregate folds the rows of input into one row of aggregate results.
+// A bare COUNT(*) over a whole table reads the row count, and a bare MIN or
+// MAX of an indexed column reads the ends of the index, instead of scanning.
+func (p *Planner) planAggregate(stmt *parser.SelectStmt, input PlanNode) (PlanNode, error) {
+	_, virtual := informationSchema[stmt.TableName]
+	virtual = virtual || stmt.TableName == ""
+	if len(stmt.Fields) == 1 && !virtual && stmt.Where == nil && stmt.Join == nil && stmt.Sample == nil {
+		table := p.Tables[stmt.TableName]
+		if isCountStar(stmt.Fields[0]) {
+			return &CountNode{Table: table, Field: stmt.Fields[0]}, nil
+		}
+		if col, ok := isIndexedMinMax(stmt.Fields[0], table); ok {
+			return &MinMaxNode{Table: table, Column: col, Field: stmt.Fields[0]}, nil
+		}
+	}
+	return newAggregateNode(input, stmt.Fields, p.eval())
+}
+
+// checkColumns is the planner's semantic check: every column a statement
+// names must exist in a table it reads, or planning fails with "unknown
+// column" rather than building a predicate that is silently never true.
+// Column references are qualified with their table as a side effect. The
+// engine resolves statements before planning, so this mainly guards plans
+// built straight from a parsed statement.
+func (p *Planner) checkColumns(stmt parser.Statement) error {
+	var tables []string
+	var exprs []parser.Expression
+	var where *parser.WhereClause
+	var having parser.Expression
+	switch s := stmt.(type) {
+	case *parser.SelectStmt:
+		tables = append(tables, s.TableName)
+		if s.Join != nil {
+			tables = append(tables, s.Join.Table)
+			if s.Join.OnLeft != nil {
+				exprs = append(exprs, s.Join.OnLeft, s.Join.OnRight)
+			}
+		}
+		exprs = append(exprs, s.Fields...)
+		for _, k := range s.GroupBy {
+			exprs = append(exprs, k)
+		}
+		having = s.Having
+		where = s.Where
+	case *parser.UpdateStmt:
+		tables = append(tables, s.TableName)
+		for col := range s.Set {
+			exprs = append(exprs, &parser.ColumnRef{Name: col})
+		}
+		where = s.Where
+	case *parser.DeleteStmt:
+		tables = append(tables, s.TableName)
+		where = s.Where
+	default:
+		return nil
+	}
+	if where != nil {
+		exprs = append(exprs, where.Expr)
+	}
+
+	scope := newNameScope()
+	for _, name := range tables {
+		if name == "" {
+			continue // SELECT without FROM
+		}
+		if def, ok := informationSchema[name]; ok {
+			scope = append(scope, def)
+		} else if t, ok := p.Tables[name]; ok {
+			scope = append(scope, t.Def)
+		} else {
+			return fmt.Errorf("table not found: %s", name)
+		}
+	}
+	for _, expr := range exprs {
+		if err := scope.resolveExpr(expr); err != nil {
+			return err
+		}
+	}
+	if having != nil {
+		return scope.resolveHaving(having)
+	}
+	return nil
+}
+
 func (p *Planner) planSelect(stmt *parser.SelectStmt) (PlanNode, error) {
-	// We need a way to load tables in planner too, but executor currently handles the map.
-	// For web/dashboard select, we assume they are already in the map or loaded by setup.
-	t, ok := p.Tables[stmt.TableName]
+	// With a join, WHERE may reference either side, so it is applied to the
+	// joined rows by a FilterNode below instead of to the base scan.
+	where, ins := splitIn(stmt.Where)
+	if stmt.Join != nil {
+		where, ins = nil, nil
+	}
+
+	var node PlanNode
+	if stmt.TableName == "" {
+		// No FROM: a single row with no columns for the fields to compute over
+		node = &ValuesNode{Rows: []storage.Row{{}}}
+	} else if def, ok := informationSchema[stmt.TableName]; ok {
+		node = p.informationSchemaScan(def)
+		if where != nil {
+			node = &FilterNode{Input: node, Expr: where.Expr, eval: p.eval()}
+		}
+	} else {
+		// We need a way to load tables in planner too, but executor currently handles the map.
+		// For web/dashboard select, we assume they are already in the map or loaded by setup.
+		t, ok := p.Tables[stmt.TableName]
+		if !ok {
+			// Since Planner doesn't have storage access directly, we expect it to be passed in.
+			// However, in a full impl, we'd have a catalog.
+			return nil, fmt.Errorf("table not found: %s", stmt.TableName)
+		}
+		node = planAccess(t, where, p.eval())
+	}
+	node, err := p.planSemiJoins(node, ins)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Sampling applies to the base table, before any join
+	if stmt.Sample != nil {
+		seed := p.SampleSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		node = &SampleNode{
+			Input:   node,
+			Percent: stmt.Sample.Percent,
+			Rand:    rand.New(rand.NewSource(seed)),
+		}
+	}
+
+	// 3. Join
+	if stmt.Join != nil {
+		// Right Node (Scan for now)
+		var rightNode PlanNode
+		if def, ok := informationSchema[stmt.Join.Table]; ok {
+			rightNode = p.informationSchemaScan(def)
+		} else {
+			rightTable, ok := p.Tables[stmt.Join.Table]
+			if !ok {
+				return nil, fmt.Errorf("join table not found: %s", stmt.Join.Table)
+			}
+			rightNode = &ScanNode{Table: rightTable}
+		}
+
+		// Join Node
+		joinNode := &JoinNode{
+			Left:  node,
+			Right: rightNode,
+		}
+
+		var filter parser.Expression
+		rest, ins := splitIn(stmt.Where)
+		if rest != nil {
+			filter = rest.Expr
+		}
+		if stmt.Join.OnLeft != nil {
+			joinNode.LeftCol = stmt.Join.OnLeft.Name
+			joinNode.RightCol = stmt.Join.OnRight.Name
+		} else if on, rest := takeJoinCondition(filter, stmt.TableName, stmt.Join.Table); on != nil {
+			// Implicit join: the WHERE equality between the tables becomes the join key
+			joinNode.LeftCol = on.Column
+			joinNode.RightCol = on.Right.Name
+			filter = rest
+		}
+
+		node = joinNode
+
+		if filter != nil {
+			node = &FilterNode{Input: node, Expr: filter, eval: p.eval()}
+		}
+		if node, err = p.planSemiJoins(node, ins); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// planWrite plans how UPDATE or DELETE finds the rows it changes.
+func (p *Planner) planWrite(table string, where *parser.WhereClause) (PlanNode, error) {
+	t, ok := p.Tables[table]
 	if !ok {
-		// Since Planner doesn't have storage access directly, we expect it to be passed in.
-		// However, in a full impl, we'd have a catalog.
-		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
+		return nil, fmt.Errorf("table not found: %s", table)
 	}
+	where, ins := splitIn(where)
+	return p.planSemiJoins(planAccess(t, where, p.eval()), ins)
+}
 
+// planAccess picks how to read table t: an index lookup or range scan when
+// the WHERE clause allows one and, for equality, the table statistics say
+// the index is cheaper; otherwise a full scan with the predicate.
+func planAccess(t *storage.Table, where *parser.WhereClause, eval evaluator) PlanNode {
 	var node PlanNode
 
 	// 1. Where Clause Optimization (Index Lookup)
 	useIndex := false
-	if stmt.Where != nil {
+	if where != nil {
 		// Only optimize simple "col = val" for now
-		if comp, ok := stmt.Where.Expr.(*parser.ComparisonExpression); ok {
-			if comp.Operator == "=" {
+		if comp, ok := where.Expr.(*parser.ComparisonExpression); ok {
+			if comp.Operator == "=" && comp.Right == nil && comp.RightExpr == nil && (comp.Table == "" || comp.Table == t.Def.Name) {
 				colDef, ok := t.Def.GetColumn(comp.Column)
-				if ok && (colDef.IsPrimary || colDef.IsUnique) {
+				// The index only finds the rows the comparison matches if it
+				// keys values under the same collation
+				indexColl, indexed := t.IndexCollation(comp.Column)
+				if ok && indexed && sameCollation(indexColl, comparisonCollation(comp, colDef.Collation)) &&
+					lookupPays(t.Stats(), comp.Column) {
 					node = &IndexScanNode{
 						Table:     t,
 						IndexName: comp.Column,
@@ -290,53 +701,206 @@ func (p *Planner) planSelect(stmt *parser.SelectStmt) (PlanNode, error) {
 					useIndex = true
 				}
 			}
+			// "col LIKE 'Ja%'" range-scans the index over ['Ja', 'Jb') and
+			// re-checks the full pattern on the rows it finds
+			if comp.Operator == "LIKE" && (comp.Table == "" || comp.Table == t.Def.Name) {
+				colDef, ok := t.Def.GetColumn(comp.Column)
+				prefix := likePrefix(comp.Value.Val.(string))
+				if ok && prefix != "" && t.HasRangeIndex(comp.Column) && comparisonCollation(comp, colDef.Collation).IsBinary() {
+					node = &FilterNode{
+						Input: &IndexRangeNode{
+							Table:     t,
+							IndexName: comp.Column,
+							From:      types.NewText(prefix),
+							To:        prefixEnd(prefix),
+						},
+						Expr: comp,
+						eval: eval,
+					}
+					useIndex = true
+				}
+			}
+		}
+	}
+
+	// "col > 100 AND col <= 200", "col BETWEEN 10 AND 20": range-scan the
+	// index and re-check the whole WHERE clause on the rows it finds
+	if !useIndex && where != nil {
+		if col, from, to, ok := indexRange(t, where.Expr); ok {
+			node = &FilterNode{
+				Input: &IndexRangeNode{Table: t, IndexName: col, From: from, To: to},
+				Expr:  where.Expr,
+				eval:  eval,
+			}
+			useIndex = true
 		}
 	}
 
 	if !useIndex {
 		// Full Scan with Predicate
-		node = &ScanNode{
+		scan := &ScanNode{
 			Table: t,
 			Predicate: func(r storage.Row) bool {
-				if stmt.Where == nil {
+				if where == nil {
 					return true
 				}
-				return Evaluate(stmt.Where.Expr, r, t.Def)
+				return eval.matches(where.Expr, r, t.Def)
 			},
 		}
+		if where != nil {
+			scan.Where = where.Expr
+		}
+		node = scan
 	}
+	return node
+}
 
-	// 2. Join
-	if stmt.Join != nil {
-		rightTable, ok := p.Tables[stmt.Join.Table]
-		if !ok {
-			return nil, fmt.Errorf("join table not found: %s", stmt.Join.Table)
-		}
+// Relative costs of producing one row. A scan reads the next row of a
+// snapshot; an index lookup probes a hash map for each match and sorts the
+// keys it finds, which costs more per row.
+const (
+	scanRowCost  = 1.0
+	indexRowCost = 2.0
+)
 
-		// Right Node (Scan for now)
-		rightNode := &ScanNode{Table: rightTable}
+// lookupPays reports whether finding "col = value" through the index on col
+// is expected to cost less than scanning the whole table. Rows are assumed
+// to be spread evenly over the column's distinct values, so an index on a
+// column with few distinct values (a status flag, say) loses to the scan.
+// A unique index matches at most one row and always pays.
+func lookupPays(stats storage.Stats, col string) bool {
+	distinct := stats.Distinct[col]
+	if distinct == 0 {
+		return true // Empty table; the lookup finds nothing at once
+	}
+	matches := float64(stats.RowCount) / float64(distinct)
+	return matches <= 1 || matches*indexRowCost < float64(stats.RowCount)*scanRowCost
+}
 
-		// Join Node
-		joinNode := &JoinNode{
-			Left:     node,
-			Right:    rightNode,
-			LeftCol:  stmt.Join.OnLeft, // e.g. "users.id" -> need to match column name in schema "id"
-			RightCol: stmt.Join.OnRight,
+// indexRange looks for comparisons of one indexed column against values in
+// the top-level AND chain of expr and returns the [from, to) range of index
+// keys that covers them. Inclusive upper bounds are widened to the next key,
+// so the range may include rows the comparisons reject; callers must still
+// filter. ok is false if no term can use an index.
+func indexRange(t *storage.Table, expr parser.Expression) (col string, from, to types.Value, ok bool) {
+	for _, term := range conjuncts(expr) {
+		comp, isComp := term.(*parser.ComparisonExpression)
+		if !isComp || comp.Left != nil || comp.Right != nil || comp.RightExpr != nil || comp.Value.IsNull() {
+			continue
+		}
+		if (comp.Table != "" && comp.Table != t.Def.Name) || (col != "" && comp.Column != col) {
+			continue
+		}
+		colDef, found := t.Def.GetColumn(comp.Column)
+		// Index keys are ordered byte-wise, so only binary collation matches
+		if !found || colDef.Type != comp.Value.Type || !t.HasRangeIndex(comp.Column) ||
+			!comparisonCollation(comp, colDef.Collation).IsBinary() {
+			continue
 		}
 
-		// Fix column names
-		joinNode.LeftCol = stripTablePrefix(joinNode.LeftCol)
-		joinNode.RightCol = stripTablePrefix(joinNode.RightCol)
+		lo, hi := types.Value{}, types.Value{}
+		switch comp.Operator {
+		case "=":
+			if !lookupPays(t.Stats(), comp.Column) {
+				continue
+			}
+			lo, hi = comp.Value, keyAfter(comp.Value)
+		case ">", ">=":
+			lo = comp.Value
+		case "<":
+			hi = comp.Value
+		case "<=":
+			hi = keyAfter(comp.Value)
+		default:
+			continue
+		}
+		col = comp.Column
+		// Keep the tightest bound on each side
+		if !lo.IsNull() && (from.IsNull() || compareSameType(lo, from) > 0) {
+			from = lo
+		}
+		if !hi.IsNull() && (to.IsNull() || compareSameType(hi, to) < 0) {
+			to = hi
+		}
+	}
+	return col, from, to, col != ""
+}
 
-		node = joinNode
+// sameCollation reports whether a and b compare TEXT alike, taking the empty
+// collation as binary.
+func sameCollation(a, b types.Collation) bool {
+	if a.IsBinary() || b.IsBinary() {
+		return a.IsBinary() && b.IsBinary()
 	}
+	return a == b
+}
 
-	return node, nil
+// compareSameType compares two non-NULL values of the column's type.
+func compareSameType(a, b types.Value) int {
+	cmp, _ := a.Compare(b)
+	return cmp
 }
 
-func stripTablePrefix(s string) string {
-	if idx := strings.Index(s, "."); idx != -1 {
-		return s[idx+1:]
+// keyAfter returns the smallest value greater than v, or NULL if there is none.
+func keyAfter(v types.Value) types.Value {
+	switch x := v.Val.(type) {
+	case int:
+		if x == math.MaxInt {
+			return types.NewNull(v.Type)
+		}
+		return types.NewInt(x + 1)
+	case string:
+		return types.NewText(x + "\x00")
+	}
+	return types.NewNull(v.Type)
+}
+
+// takeJoinCondition finds the first "left.col = right.col" equality in the
+// top-level AND chain of expr. It returns that comparison oriented so Column
+// belongs to left, plus the remaining conditions (nil if none remain).
+func takeJoinCondition(expr parser.Expression, left, right string) (*parser.ComparisonExpression, parser.Expression) {
+	terms := conjuncts(expr)
+	for i, term := range terms {
+		comp, ok := term.(*parser.ComparisonExpression)
+		if !ok || comp.Operator != "=" || comp.Right == nil {
+			continue
+		}
+		var on *parser.ComparisonExpression
+		switch {
+		case comp.Table == left && comp.Right.Table == right:
+			on = comp
+		case comp.Table == right && comp.Right.Table == left:
+			on = &parser.ComparisonExpression{
+				Table: comp.Right.Table, Column: comp.Right.Name, Operator: "=",
+				Right: &parser.ColumnRef{Table: comp.Table, Name: comp.Column},
+			}
+		default:
+			continue
+		}
+
+		var rest parser.Expression
+		for j, other := range terms {
+			if j == i {
+				continue
+			}
+			if rest == nil {
+				rest = other
+			} else {
+				rest = &parser.InfixExpression{Left: rest, Operator: "AND", Right: other}
+			}
+		}
+		return on, rest
+	}
+	return nil, expr
+}
+
+// conjuncts flattens a chain of ANDs into its terms.
+func conjuncts(expr parser.Expression) []parser.Expression {
+	if expr == nil {
+		return nil
+	}
+	if infix, ok := expr.(*parser.InfixExpression); ok && infix.Operator == "AND" {
+		return append(conjuncts(infix.Left), conjuncts(infix.Right)...)
 	}
-	return s
+	return []parser.Expression{expr}
 }
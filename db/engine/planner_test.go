@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/parser"
+	"testing"
+)
+
+func TestPlannerRejectsUnknownColumns(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tests := map[string]string{
+		"SELECT * FROM users WHERE nmae = 'Ann'":                                "unknown column: nmae",
+		"SELECT id, nmae FROM users":                                            "unknown column: nmae",
+		"SELECT LENGTH(nmae) FROM users":                                        "unknown column: nmae",
+		"SELECT * FROM users WHERE id = 1 AND NOT (nmae = 'x')":                 "unknown column: nmae",
+		"SELECT * FROM orders JOIN users ON orders.user = users.id":             "unknown column: orders.user",
+		"SELECT users.nmae FROM orders JOIN users ON orders.user_id = users.id": "unknown column: users.nmae",
+		"UPDATE users SET nmae = 'x' WHERE id = 1":                              "unknown column: nmae",
+		"DELETE FROM users WHERE nmae = 'Ann'":                                  "unknown column: nmae",
+		"SELECT * FROM nowhere":                                                 "table not found: nowhere",
+	}
+	for sql, want := range tests {
+		// Straight from the parser, without the engine's name resolution
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if _, err := NewPlanner(e.Tables).CreatePlan(stmt); err == nil || err.Error() != want {
+			t.Errorf("%s: expected %q, got %v", sql, want, err)
+		}
+	}
+
+	// Qualified and bare names of real columns still plan
+	for _, sql := range []string{
+		"SELECT users.name FROM users WHERE id = 1",
+		"SELECT name FROM orders JOIN users ON user_id = users.id WHERE orders.id > 0",
+	} {
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if _, err := NewPlanner(e.Tables).CreatePlan(stmt); err != nil {
+			t.Errorf("%s: %v", sql, err)
+		}
+	}
+}
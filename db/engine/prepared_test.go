@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestExecutePreparedStoresValuesVerbatim(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE users (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	text := func(s string) types.Value { return types.Value{Type: types.TypeText, Val: s} }
+	id := func(i int) types.Value { return types.Value{Type: types.TypeInt, Val: i} }
+
+	names := []string{"O'Brien", "'; DROP TABLE users; --", "?", "-- not a comment"}
+	for i, name := range names {
+		if _, err := e.ExecutePrepared(ctx, "INSERT INTO users VALUES (?, ?)", id(i+1), text(name)); err != nil {
+			t.Fatalf("Failed to insert %q: %v", name, err)
+		}
+	}
+
+	for i, name := range names {
+		res, err := e.ExecutePrepared(ctx, "SELECT id, name FROM users WHERE name = ?", text(name))
+		if err != nil {
+			t.Fatalf("Failed to select %q: %v", name, err)
+		}
+		if len(res.Rows) != 1 || res.Rows[0].Values[0].Val != i+1 || res.Rows[0].Values[1].Val != name {
+			t.Errorf("Expected row (%d, %q), got %v", i+1, name, res.Rows)
+		}
+	}
+
+	if _, err := e.ExecutePrepared(ctx, "UPDATE users SET name = ? WHERE id = ?", text("Ann 'The' Admin"), id(1)); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if row, _ := e.Tables["users"].GetRow(1); row.Values[1].Val != "Ann 'The' Admin" {
+		t.Errorf("Expected updated name, got %v", row.Values[1].Val)
+	}
+
+	if _, err := e.ExecutePrepared(ctx, "SELECT * FROM users WHERE id = ?"); err == nil {
+		t.Errorf("Expected error for a missing argument")
+	}
+	if _, err := e.ExecutePrepared(ctx, "SELECT * FROM users WHERE id = ?", id(1), id(2)); err == nil {
+		t.Errorf("Expected error for an extra argument")
+	}
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"strings"
+	"testing"
+)
+
+// setupRangeTable creates orders with n rows where amount (indexed) and raw
+// (not indexed) always hold the same value.
+func setupRangeTable(tb testing.TB, n int) *Engine {
+	tb.Helper()
+	e := NewEngineWithConfig(Config{DataDir: tb.TempDir()})
+	if _, err := e.Execute(context.Background(), "CREATE TABLE orders (id INT PRIMARY KEY, amount INT, raw INT, code TEXT UNIQUE, INDEX (amount))"); err != nil {
+		tb.Fatalf("Failed to create table: %v", err)
+	}
+	tbl := e.Tables["orders"]
+	for i := 1; i <= n; i++ {
+		amount := (i * 37) % 500
+		sql := fmt.Sprintf("INSERT INTO orders VALUES (%d, %d, %d, 'c%04d')", i, amount, amount, i)
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			tb.Fatalf("%s: %v", sql, err)
+		}
+		values, err := e.insertValues(tbl, stmt.(*parser.InsertStmt).Values)
+		if err != nil {
+			tb.Fatalf("%s: %v", sql, err)
+		}
+		if err := tbl.Insert(values); err != nil {
+			tb.Fatalf("%s: %v", sql, err)
+		}
+	}
+	return e
+}
+
+func TestRangeScanMatchesFullScan(t *testing.T) {
+	e := setupRangeTable(t, 300)
+	ctx := context.Background()
+
+	tests := []struct {
+		where     string // "col" is replaced by amount (indexed) or raw
+		wantRange bool
+	}{
+		{"col > 100", true},
+		{"col >= 100", true},
+		{"col < 50", true},
+		{"col <= 50", true},
+		{"col > 100 AND col <= 200", true},
+		{"col BETWEEN 120 AND 130", true},
+		{"col BETWEEN 130 AND 120", true},
+		{"col = 111 AND id > 10", true},
+		{"id > 10 AND col < 40", true},
+		{"col + 1 > 400", false},
+		{"code >= 'c0100' AND code < 'c0110' AND col > 0", true},
+	}
+	for _, tt := range tests {
+		indexed := "SELECT id FROM orders WHERE " + strings.ReplaceAll(tt.where, "col", "amount")
+		scanned := "SELECT id FROM orders WHERE " + strings.ReplaceAll(tt.where, "col", "raw")
+
+		stmt, err := parser.NewParser(parser.NewTokenizer(indexed)).ParseStatement()
+		if err != nil {
+			t.Fatalf("parse %s: %v", indexed, err)
+		}
+		if err := e.resolveSelect(stmt.(*parser.SelectStmt)); err != nil {
+			t.Fatalf("resolve %s: %v", indexed, err)
+		}
+		plan, err := NewPlanner(e.Tables).CreatePlan(stmt)
+		if err != nil {
+			t.Fatalf("plan %s: %v", indexed, err)
+		}
+		usedRange := false
+		if filter, ok := plan.(*FilterNode); ok {
+			_, usedRange = filter.Input.(*IndexRangeNode)
+		}
+		if usedRange != tt.wantRange {
+			t.Errorf("%s: planned %T, range scan = %v, want %v", indexed, plan, usedRange, tt.wantRange)
+		}
+
+		want, err := e.Execute(ctx, scanned)
+		if err != nil {
+			t.Fatalf("%s: %v", scanned, err)
+		}
+		got, err := e.Execute(ctx, indexed)
+		if err != nil {
+			t.Fatalf("%s: %v", indexed, err)
+		}
+		if fmt.Sprint(got.Rows) != fmt.Sprint(want.Rows) {
+			t.Errorf("%s: got %d rows, full scan found %d", indexed, len(got.Rows), len(want.Rows))
+		}
+	}
+}
+
+func TestRangeWritesMatchFullScan(t *testing.T) {
+	ctx := context.Background()
+	results := map[string]string{}
+	for _, col := range []string{"amount", "raw"} {
+		e := setupRangeTable(t, 300)
+		for _, sql := range []string{
+			"UPDATE orders SET amount = 0, raw = 0 WHERE col BETWEEN 100 AND 150",
+			"DELETE FROM orders WHERE col > 0 AND col < 60",
+		} {
+			sql = strings.ReplaceAll(sql, "col", col)
+			res, err := e.Execute(ctx, sql)
+			if err != nil {
+				t.Fatalf("%s: %v", sql, err)
+			}
+			if res.RowsAffected == 0 {
+				t.Errorf("%s: expected rows to change", sql)
+			}
+			results[col] += res.Message + "\n"
+		}
+		res, err := e.Execute(ctx, "SELECT id, amount FROM orders")
+		if err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		results[col] += fmt.Sprint(res.Rows)
+	}
+	if results["amount"] != results["raw"] {
+		t.Errorf("Indexed writes left a different table than scanned writes")
+	}
+}
+
+func BenchmarkRangeQuery(b *testing.B) {
+	e := setupRangeTable(b, 10000)
+	ctx := context.Background()
+
+	for _, col := range []string{"amount", "raw"} {
+		sql := "SELECT id FROM orders WHERE " + col + " BETWEEN 100 AND 104"
+		b.Run(col, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := e.Execute(ctx, sql); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"fmt"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/types"
+)
+
+// nameScope is the set of tables a statement's column references may name.
+type nameScope []schema.TableDef
+
+func newNameScope(defs ...schema.TableDef) nameScope {
+	return nameScope(defs)
+}
+
+// has reports whether table is in scope.
+func (s nameScope) has(table string) bool {
+	for _, def := range s {
+		if def.Name == table {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the table that owns column. A non-empty table must be in
+// scope; a bare column must exist in exactly one table.
+func (s nameScope) resolve(table, column string) (string, error) {
+	ref := &parser.ColumnRef{Table: table, Name: column}
+	owner := ""
+	knownTable := table == ""
+	for _, def := range s {
+		if table != "" && def.Name != table {
+			continue
+		}
+		knownTable = true
+		if _, ok := def.GetColumn(column); !ok {
+			continue
+		}
+		if owner != "" {
+			return "", fmt.Errorf("ambiguous column reference: %s", ref)
+		}
+		owner = def.Name
+	}
+	if !knownTable {
+		return "", fmt.Errorf("unknown table in column reference: %s", ref)
+	}
+	if owner == "" {
+		return "", fmt.Errorf("unknown column: %s", ref)
+	}
+	return owner, nil
+}
+
+// resolveExpr qualifies every column reference in expr with its source table.
+func (s nameScope) resolveExpr(expr parser.Expression) error {
+	switch e := expr.(type) {
+	case *parser.Star:
+		if e.Table != "" && !s.has(e.Table) {
+			return fmt.Errorf("unknown table in %s", e)
+		}
+	case *parser.ColumnRef:
+		table, err := s.resolve(e.Table, e.Name)
+		if err != nil {
+			return err
+		}
+		e.Table = table
+	case *parser.ComparisonExpression:
+		if e.Left != nil {
+			if containsAggregate(e.Left) {
+				return fmt.Errorf("aggregate functions are not allowed in WHERE: %s", e.Left)
+			}
+			if err := s.resolveExpr(e.Left); err != nil {
+				return err
+			}
+		} else {
+			table, err := s.resolve(e.Table, e.Column)
+			if err != nil {
+				return err
+			}
+			e.Table = table
+			if err := s.coerceDate(e); err != nil {
+				return err
+			}
+		}
+		if e.RightExpr != nil {
+			if containsAggregate(e.RightExpr) {
+				return fmt.Errorf("aggregate functions are not allowed in WHERE: %s", e.RightExpr)
+			}
+			if err := s.resolveExpr(e.RightExpr); err != nil {
+				return err
+			}
+		}
+		if e.Right != nil {
+			return s.resolveExpr(e.Right)
+		}
+	case *parser.InExpression:
+		// The subquery has its own scope; see resolveSubqueries
+		table, err := s.resolve(e.Table, e.Column)
+		if err != nil {
+			return err
+		}
+		e.Table = table
+	case *parser.InfixExpression:
+		if err := s.resolveExpr(e.Left); err != nil {
+			return err
+		}
+		return s.resolveExpr(e.Right)
+	case *parser.NotExpression:
+		return s.resolveExpr(e.Expr)
+	case *parser.FunctionCall:
+		if _, ok := scalarFunctions[e.Name]; !ok && !isAggregate(e) {
+			return fmt.Errorf("unknown function: %s", e.Name)
+		}
+		if e.Distinct && e.Name != "COUNT" {
+			return fmt.Errorf("DISTINCT is only supported in COUNT, not %s", e.Name)
+		}
+		for _, a := range e.Args {
+			if err := s.resolveExpr(a); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// coerceDate reads a TEXT value compared with a DATE column as a date, so
+// d = '2024-01-02' means d = DATE '2024-01-02'. Text that is not a date is
+// an error rather than a comparison that never holds. e must be resolved.
+func (s nameScope) coerceDate(e *parser.ComparisonExpression) error {
+	if e.Operator == "LIKE" || e.Value.Type != types.TypeText || e.Value.IsNull() {
+		return nil
+	}
+	for _, def := range s {
+		if def.Name != e.Table {
+			continue
+		}
+		if col, ok := def.GetColumn(e.Column); ok && col.Type == types.TypeDate {
+			date, err := types.ParseDate(e.Value.Val.(string))
+			if err != nil {
+				return fmt.Errorf("cannot compare DATE column %s: %w", &parser.ColumnRef{Table: e.Table, Name: e.Column}, err)
+			}
+			e.Value = date
+		}
+	}
+	return nil
+}
+
+// resolveSelect rewrites every column reference in a SELECT to its canonical
+// (table, column) form using the FROM and JOIN tables, so the planner and
+// projection never guess from bare or dotted names. The JOIN condition is
+// oriented so OnLeft belongs to the FROM table.
+func (e *Engine) resolveSelect(stmt *parser.SelectStmt) error {
+	scope := newNameScope()
+	if stmt.TableName != "" {
+		from, err := e.sourceDef(stmt.TableName)
+		if err != nil {
+			return fmt.Errorf("table not found: %s", stmt.TableName)
+		}
+		scope = append(scope, from)
+	}
+
+	if stmt.Join != nil {
+		joined, err := e.sourceDef(stmt.Join.Table)
+		if err != nil {
+			return fmt.Errorf("join table not found: %s", stmt.Join.Table)
+		}
+		scope = append(scope, joined)
+
+		if stmt.Join.OnLeft != nil {
+			if err := scope.resolveOn(stmt.TableName, stmt.Join); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range stmt.Fields {
+		if err := scope.resolveExpr(f); err != nil {
+			return err
+		}
+	}
+	for _, k := range stmt.GroupBy {
+		if err := scope.resolveExpr(k); err != nil {
+			return err
+		}
+	}
+	if stmt.Having != nil {
+		if err := scope.resolveHaving(stmt.Having); err != nil {
+			return err
+		}
+	}
+	if stmt.Where != nil {
+		if err := scope.resolveExpr(stmt.Where.Expr); err != nil {
+			return err
+		}
+		return e.resolveSubqueries(stmt.Where.Expr)
+	}
+	return nil
+}
+
+// resolveHaving is resolveExpr for a HAVING condition, whose comparisons
+// may call aggregates. IN subqueries are not supported there.
+func (s nameScope) resolveHaving(expr parser.Expression) error {
+	switch e := expr.(type) {
+	case *parser.ComparisonExpression:
+		if (e.Left != nil && containsAggregate(e.Left)) || (e.RightExpr != nil && containsAggregate(e.RightExpr)) {
+			if e.Left != nil {
+				if err := s.resolveExpr(e.Left); err != nil {
+					return err
+				}
+			} else {
+				table, err := s.resolve(e.Table, e.Column)
+				if err != nil {
+					return err
+				}
+				e.Table = table
+			}
+			if e.RightExpr != nil {
+				if err := s.resolveExpr(e.RightExpr); err != nil {
+					return err
+				}
+			}
+			if e.Right != nil {
+				return s.resolveExpr(e.Right)
+			}
+			return nil
+		}
+	case *parser.InfixExpression:
+		if !isArithmetic(e) {
+			if err := s.resolveHaving(e.Left); err != nil {
+				return err
+			}
+			return s.resolveHaving(e.Right)
+		}
+	case *parser.NotExpression:
+		return s.resolveHaving(e.Expr)
+	case *parser.InExpression:
+		return fmt.Errorf("IN subqueries are not supported in HAVING: %s", e)
+	}
+	return s.resolveExpr(expr)
+}
+
+// resolveSubqueries resolves each IN subquery in expr against its own FROM
+// tables. Subqueries cannot see the outer query's columns. The planner runs
+// them as semi-joins, so they may only appear in the top-level AND chain,
+// not under OR or NOT.
+func (e *Engine) resolveSubqueries(expr parser.Expression) error {
+	for _, term := range conjuncts(expr) {
+		x, ok := term.(*parser.InExpression)
+		if !ok {
+			if containsIn(term) {
+				return fmt.Errorf("IN subqueries cannot be combined with OR or NOT: %s", term)
+			}
+			continue
+		}
+		sub := x.Subquery
+		if len(sub.Fields) != 1 {
+			return fmt.Errorf("IN subquery must select exactly one column")
+		}
+		if _, star := sub.Fields[0].(*parser.Star); star {
+			return fmt.Errorf("IN subquery must select exactly one column")
+		}
+		if err := e.resolveSelect(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containsIn reports whether a condition contains an IN subquery.
+func containsIn(expr parser.Expression) bool {
+	switch e := expr.(type) {
+	case *parser.InExpression:
+		return true
+	case *parser.InfixExpression:
+		return containsIn(e.Left) || containsIn(e.Right)
+	case *parser.NotExpression:
+		return containsIn(e.Expr)
+	}
+	return false
+}
+
+// resolveOn resolves an explicit JOIN condition and orients it so OnLeft
+// belongs to the FROM table.
+func (s nameScope) resolveOn(from string, on *parser.JoinClause) error {
+	if err := s.resolveExpr(on.OnLeft); err != nil {
+		return err
+	}
+	if err := s.resolveExpr(on.OnRight); err != nil {
+		return err
+	}
+	if on.OnLeft.Table == on.Table && on.OnRight.Table == from {
+		on.OnLeft, on.OnRight = on.OnRight, on.OnLeft
+	}
+	if on.OnLeft.Table != from || on.OnRight.Table != on.Table {
+		return fmt.Errorf("JOIN condition must compare columns of %s and %s", from, on.Table)
+	}
+	return nil
+}
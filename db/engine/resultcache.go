@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"container/list"
+	"context"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// resultCache keeps the results of recent SELECTs, least recently used
+// first out, keyed by normalized SQL. Each entry remembers the tables it
+// read, and a write to any of them drops it. A nil cache caches nothing.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *cachedResult, most recently used at the front
+	entries map[string]*list.Element
+	// gen counts invalidations, so a result computed while a write ran is
+	// not stored after the write has already dropped its table's entries.
+	gen uint64
+}
+
+type cachedResult struct {
+	key    string
+	tables []string
+	result *ResultSet
+}
+
+func newResultCache(size int) *resultCache {
+	if size <= 0 {
+		return nil
+	}
+	return &resultCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns a copy of the cached result for key.
+func (c *resultCache) get(key string) (*ResultSet, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return copyResult(el.Value.(*cachedResult).result), true
+}
+
+// generation returns the invalidation count to pass to put.
+func (c *resultCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put stores a copy of res under key unless a write has invalidated
+// anything since gen was read, evicting the least recently used entry when
+// the cache is full.
+func (c *resultCache) put(key string, tables []string, res *ResultSet, gen uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+	}
+	entry := &cachedResult{key: key, tables: tables, result: copyResult(res)}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResult).key)
+	}
+}
+
+// invalidate drops every result that read table, or every result at all
+// if table is "".
+func (c *resultCache) invalidate(table string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if table == "" {
+		c.order.Init()
+		c.entries = make(map[string]*list.Element)
+		return
+	}
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*cachedResult)
+		for _, t := range entry.tables {
+			if t == table {
+				c.order.Remove(el)
+				delete(c.entries, entry.key)
+				break
+			}
+		}
+		el = next
+	}
+}
+
+// copyResult copies res down to each row's values, so callers of the cache
+// can neither change a cached entry nor see each other's changes.
+func copyResult(res *ResultSet) *ResultSet {
+	out := *res
+	out.Columns = append(res.Columns[:0:0], res.Columns...)
+	out.ColumnTypes = append(res.ColumnTypes[:0:0], res.ColumnTypes...)
+	out.Rows = make([]storage.Row, len(res.Rows))
+	for i, row := range res.Rows {
+		out.Rows[i] = storage.Row{Values: append(row.Values[:0:0], row.Values...)}
+	}
+	return &out
+}
+
+// execSelectCached runs a SELECT through the result cache, when there is
+// one and the query's result only depends on the tables it names.
+func (e *Engine) execSelectCached(ctx context.Context, s *parser.SelectStmt, sql string, args []types.Value) (*ResultSet, error) {
+	tables, ok := cacheableTables(s)
+	if e.results == nil || !ok {
+		return e.execSelect(ctx, s)
+	}
+	key := cacheKey(sql, args)
+	if res, ok := e.results.get(key); ok {
+		return res, nil
+	}
+	gen := e.results.generation()
+	res, err := e.execSelect(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	e.results.put(key, tables, res, gen)
+	return res, nil
+}
+
+// cacheableTables returns the tables a SELECT and its IN subqueries read.
+// ok is false for queries whose result can change without a write to
+// those tables: TABLESAMPLE draws a new sample each time, NOW() reads the
+// clock, and information_schema describes every table.
+func cacheableTables(s *parser.SelectStmt) (tables []string, ok bool) {
+	if s.Sample != nil {
+		return nil, false
+	}
+	exprs := append([]parser.Expression{s.Having}, s.Fields...)
+	if s.Where != nil {
+		exprs = append(exprs, s.Where.Expr)
+	}
+	for _, expr := range exprs {
+		if callsNow(expr) {
+			return nil, false
+		}
+	}
+	if s.TableName != "" {
+		tables = append(tables, s.TableName)
+	}
+	if s.Join != nil {
+		tables = append(tables, s.Join.Table)
+	}
+	for _, t := range tables {
+		if _, virtual := informationSchema[t]; virtual {
+			return nil, false
+		}
+	}
+	if s.Where == nil {
+		return tables, true
+	}
+	for _, sub := range subqueries(s.Where.Expr) {
+		more, ok := cacheableTables(sub)
+		if !ok {
+			return nil, false
+		}
+		tables = append(tables, more...)
+	}
+	return tables, true
+}
+
+// subqueries returns the IN subqueries anywhere in a condition.
+func subqueries(expr parser.Expression) []*parser.SelectStmt {
+	switch e := expr.(type) {
+	case *parser.InExpression:
+		return []*parser.SelectStmt{e.Subquery}
+	case *parser.InfixExpression:
+		return append(subqueries(e.Left), subqueries(e.Right)...)
+	case *parser.NotExpression:
+		return subqueries(e.Expr)
+	}
+	return nil
+}
+
+// callsNow reports whether expr calls NOW() at any depth.
+func callsNow(expr parser.Expression) bool {
+	switch e := expr.(type) {
+	case *parser.FunctionCall:
+		if e.Name == "NOW" {
+			return true
+		}
+		for _, a := range e.Args {
+			if callsNow(a) {
+				return true
+			}
+		}
+	case *parser.InfixExpression:
+		return callsNow(e.Left) || callsNow(e.Right)
+	case *parser.NotExpression:
+		return callsNow(e.Expr)
+	case *parser.ComparisonExpression:
+		return callsNow(e.Left) || callsNow(e.RightExpr)
+	}
+	return false
+}
+
+// cacheKey normalizes a query to its tokens, so spacing, comments and
+// keyword case do not matter, followed by its bound arguments. Each part is
+// length-prefixed so no two queries share a key.
+func cacheKey(sql string, args []types.Value) string {
+	var b strings.Builder
+	add := func(s string) {
+		b.WriteString(strconv.Itoa(len(s)))
+		b.WriteByte(':')
+		b.WriteString(s)
+	}
+	tok := parser.NewTokenizer(sql)
+	for t := tok.NextToken(); t.Type != parser.TokenEOF; t = tok.NextToken() {
+		lit := t.Literal
+		if t.Type != parser.TokenIdent && t.Type != parser.TokenString {
+			lit = strings.ToUpper(lit)
+		}
+		add(strconv.Itoa(int(t.Type)) + " " + lit)
+	}
+	for _, a := range args {
+		add(a.Hash())
+	}
+	return b.String()
+}
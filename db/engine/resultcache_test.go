@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestResultCacheInvalidatedByWrites(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir(), ResultCacheSize: 2})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	count := func(sql string) int {
+		t.Helper()
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		return len(res.Rows)
+	}
+
+	if got := count("SELECT * FROM users"); got != 1 {
+		t.Fatalf("Expected 1 row, got %d", got)
+	}
+	// A row added behind the engine's back shows whether the cache answered.
+	users, _ := e.Table("users")
+	if err := users.Insert([]types.Value{types.NewInt(2), types.NewText("Ben")}); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if got := count("select *   from users -- same query"); got != 1 {
+		t.Errorf("Expected the cached result for a reformatted query, got %d rows", got)
+	}
+
+	// A write to another table leaves the entry alone; one to users drops it.
+	if _, err := e.Execute(ctx, "INSERT INTO orders VALUES (1, 1)"); err != nil {
+		t.Fatalf("Failed to insert order: %v", err)
+	}
+	if got := count("SELECT * FROM users"); got != 1 {
+		t.Errorf("Expected the cached result after an unrelated write, got %d rows", got)
+	}
+	if _, err := e.Execute(ctx, "INSERT INTO users VALUES (3, 'Cy')"); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	if got := count("SELECT * FROM users"); got != 3 {
+		t.Errorf("Expected 3 rows after the insert invalidated the cache, got %d", got)
+	}
+
+	// Subqueries count as reads of their tables.
+	sub := "SELECT name FROM users WHERE id IN (SELECT user_id FROM orders)"
+	if got := count(sub); got != 1 {
+		t.Fatalf("Expected 1 row, got %d", got)
+	}
+	if _, err := e.Execute(ctx, "INSERT INTO orders VALUES (2, 3)"); err != nil {
+		t.Fatalf("Failed to insert order: %v", err)
+	}
+	if got := count(sub); got != 2 {
+		t.Errorf("Expected 2 rows after the subquery's table changed, got %d", got)
+	}
+
+	// Bound arguments are part of the key.
+	byID := func(id int) int {
+		res, err := e.ExecutePrepared(ctx, "SELECT * FROM users WHERE id > ?", types.NewInt(id))
+		if err != nil {
+			t.Fatalf("Failed to select: %v", err)
+		}
+		return len(res.Rows)
+	}
+	if byID(0) != 3 || byID(2) != 1 {
+		t.Errorf("Expected different results for different arguments")
+	}
+}
+
+func TestResultCacheHandsOutCopies(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir(), ResultCacheSize: 2})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// Changing a result, whether it was just cached or served from the
+	// cache, does not change what the next caller gets
+	for i := 0; i < 2; i++ {
+		res, err := e.Execute(ctx, "SELECT id, name FROM users")
+		if err != nil {
+			t.Fatalf("Failed to select: %v", err)
+		}
+		if got := res.Rows[0].Values[1].Val; got != "Ann" {
+			t.Fatalf("Select %d: expected Ann, got %v", i, got)
+		}
+		res.Rows[0].Values[1] = types.NewText("changed")
+		res.Columns[1] = "changed"
+	}
+	res, err := e.Execute(ctx, "SELECT id, name FROM users")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if got := res.Rows[0].Values[1].Val; got != "Ann" || res.Columns[1] != "name" {
+		t.Errorf("Expected the cached result to be unchanged, got %v in column %s", got, res.Columns[1])
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResultCache(2)
+	res := func(msg string) *ResultSet { return &ResultSet{Message: msg} }
+	c.put("a", nil, res("a"), c.generation())
+	c.put("b", nil, res("b"), c.generation())
+	c.get("a")
+	c.put("c", nil, res("c"), c.generation())
+	if _, ok := c.get("b"); ok {
+		t.Errorf("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if got, ok := c.get(key); !ok || got.Message != key {
+			t.Errorf("Expected %s to stay cached, got %v", key, got)
+		}
+	}
+
+	// A result computed across an invalidation is not stored.
+	gen := c.generation()
+	c.invalidate("users")
+	c.put("d", []string{"users"}, res("d"), gen)
+	if _, ok := c.get("d"); ok {
+		t.Errorf("Expected a result from before an invalidation to be dropped")
+	}
+	if newResultCache(0) != nil {
+		t.Errorf("Expected size 0 to disable the cache")
+	}
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/types"
+	"strings"
+	"testing"
+)
+
+func TestResultSetColumnTypes(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE orders (id INT PRIMARY KEY, note TEXT, amount INT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// No rows yet: the shape must still be reported.
+	res, err := e.Execute(ctx, "SELECT amount, note FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != 0 {
+		t.Fatalf("Expected empty result, got %d rows", len(res.Rows))
+	}
+	wantCols := []string{"amount", "note"}
+	wantTypes := []types.DataType{types.TypeInt, types.TypeText}
+	if len(res.Columns) != 2 || len(res.ColumnTypes) != 2 {
+		t.Fatalf("Expected 2 columns and types, got %v / %v", res.Columns, res.ColumnTypes)
+	}
+	for i := range wantCols {
+		if res.Columns[i] != wantCols[i] || res.ColumnTypes[i] != wantTypes[i] {
+			t.Errorf("Column %d: expected %s %s, got %s %s", i, wantCols[i], wantTypes[i], res.Columns[i], res.ColumnTypes[i])
+		}
+	}
+
+	res, err = e.Execute(ctx, "SELECT * FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	wantTypes = []types.DataType{types.TypeInt, types.TypeText, types.TypeInt}
+	for i, want := range wantTypes {
+		if res.ColumnTypes[i] != want {
+			t.Errorf("SELECT * column %d: expected %s, got %s", i, want, res.ColumnTypes[i])
+		}
+	}
+}
+
+func TestSelectNullLiteralWithAlias(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Alice')",
+		"INSERT INTO users VALUES (2, 'Bob')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SELECT id, NULL AS note, name AS who FROM users")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Columns) != 3 || res.Columns[1] != "note" || res.Columns[2] != "who" {
+		t.Fatalf("Expected columns id, note, who; got %v", res.Columns)
+	}
+	if len(res.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(res.Rows))
+	}
+	for i, row := range res.Rows {
+		if !row.Values[1].IsNull() {
+			t.Errorf("Row %d: expected NULL note, got %v", i, row.Values[1])
+		}
+	}
+
+	if _, err := e.Execute(ctx, "SELECT * AS everything FROM users"); err == nil {
+		t.Errorf("Expected an error aliasing *")
+	}
+}
+
+func TestSelectWithoutFrom(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	tests := []struct {
+		sql     string
+		columns string
+		row     string
+	}{
+		{"SELECT 1 + 1", "1 + 1", "[2]"},
+		{"SELECT 'ok'", "'ok'", "[ok]"},
+		{"SELECT 'ok' AS status, LENGTH('héllo'), NULL", "status,LENGTH('héllo'),NULL", "[ok 5 NULL]"},
+	}
+	for _, tt := range tests {
+		res, err := e.Execute(ctx, tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got := strings.Join(res.Columns, ","); got != tt.columns {
+			t.Errorf("%s: columns %s, want %s", tt.sql, got, tt.columns)
+		}
+		if len(res.Rows) != 1 || fmt.Sprint(res.Rows[0].Values) != tt.row {
+			t.Errorf("%s: rows %v, want one row %s", tt.sql, res.Rows, tt.row)
+		}
+	}
+
+	for _, sql := range []string{"SELECT *", "SELECT id", "SELECT 1 WHERE 1 = 1"} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func TestResultSetToJSON(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE people (zip INT PRIMARY KEY, name TEXT, age INT)",
+		`INSERT INTO people VALUES (9, 'Ann "A"', 30)`,
+		"INSERT INTO people VALUES (-1, '7', 0)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	// Keys keep the select-list order, not alphabetical; INT stays a number
+	// and TEXT that looks like a number stays a string.
+	res, err := e.Execute(ctx, "SELECT zip, name, age, NULL AS missing FROM people")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	data, err := res.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	want := `[{"zip":-1,"name":"7","age":0,"missing":null},{"zip":9,"name":"Ann \"A\"","age":30,"missing":null}]`
+	if string(data) != want {
+		t.Errorf("Unexpected JSON:\n got %s\nwant %s", data, want)
+	}
+
+	empty, err := (&ResultSet{Columns: []string{"a"}}).ToJSON()
+	if err != nil || string(empty) != "[]" {
+		t.Errorf("Expected [] for no rows, got %s (%v)", empty, err)
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func TestTableSampleReproducibleWithSeed(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir(), SampleSeed: 42})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE events (id INT PRIMARY KEY, kind TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i := 1; i <= 200; i++ {
+		if err := e.Tables["events"].Insert(intText(i, "click")); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	first, err := e.Execute(ctx, "SELECT * FROM events TABLESAMPLE (10 PERCENT)")
+	if err != nil {
+		t.Fatalf("Failed to sample: %v", err)
+	}
+	second, err := e.Execute(ctx, "SELECT * FROM events TABLESAMPLE (10 PERCENT)")
+	if err != nil {
+		t.Fatalf("Failed to sample: %v", err)
+	}
+
+	if len(first.Rows) != len(second.Rows) {
+		t.Fatalf("Seeded samples differ in size: %d vs %d", len(first.Rows), len(second.Rows))
+	}
+	for i := range first.Rows {
+		if first.Rows[i].Values[0] != second.Rows[i].Values[0] {
+			t.Errorf("Seeded samples differ at %d", i)
+		}
+	}
+	// Roughly 10% of 200; generous bounds keep this from depending on the RNG algorithm.
+	if n := len(first.Rows); n < 5 || n > 40 {
+		t.Errorf("Sample size %d is implausible for 10%% of 200", n)
+	}
+
+	for _, tc := range []struct {
+		percent, want int
+	}{{0, 0}, {100, 200}} {
+		res, err := e.Execute(ctx, fmt.Sprintf("SELECT id FROM events TABLESAMPLE (%d PERCENT)", tc.percent))
+		if err != nil {
+			t.Fatalf("Failed to sample: %v", err)
+		}
+		if len(res.Rows) != tc.want {
+			t.Errorf("%d PERCENT: expected %d rows, got %d", tc.percent, tc.want, len(res.Rows))
+		}
+	}
+
+	if _, err := e.Execute(ctx, "SELECT * FROM events TABLESAMPLE (150 PERCENT)"); err == nil {
+		t.Errorf("Expected error for percent above 100")
+	}
+}
+
+// intText builds an (INT, TEXT) row for direct table inserts in tests.
+func intText(i int, s string) []types.Value {
+	return []types.Value{{Type: types.TypeInt, Val: i}, {Type: types.TypeText, Val: s}}
+}
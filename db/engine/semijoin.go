@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+)
+
+// SemiJoinNode keeps the rows of Input whose Column equals a value returned
+// by Subquery: "col IN (SELECT ...)". The subquery runs once per Open. With
+// Hash set its values go into a hash set that each row probes; otherwise each
+// row is compared against every value in turn.
+type SemiJoinNode struct {
+	Input     PlanNode
+	In        *parser.InExpression
+	Column    int // index of In's column in Input's schema
+	Collation types.Collation
+	Subquery  PlanNode
+	// Field computes the IN value from a subquery row. It is nil when the
+	// subquery is an aggregate, whose rows already hold the value.
+	Field parser.Expression
+	Hash  bool
+
+	eval evaluator
+}
+
+func (n *SemiJoinNode) Open(ctx context.Context) RowIterator {
+	values, err := n.subqueryValues(ctx)
+	if err != nil {
+		return iterate(func() (storage.Row, bool, error) {
+			return storage.Row{}, false, err
+		})
+	}
+
+	match := func(v types.Value) bool {
+		for _, other := range values {
+			if cmp, err := v.CompareCollated(other, n.Collation); err == nil && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if n.Hash {
+		set := make(map[string]bool, len(values))
+		for _, v := range values {
+			set[v.Hash()] = true
+		}
+		match = func(v types.Value) bool { return set[v.Hash()] }
+	}
+
+	input := n.Input.Open(ctx)
+	return iterate(func() (storage.Row, bool, error) {
+		for {
+			row, ok := input.Next()
+			if !ok {
+				return storage.Row{}, false, input.Err()
+			}
+			// NULL is never IN anything
+			if v := row.Values[n.Column]; !v.IsNull() && match(v) {
+				return row, true, nil
+			}
+		}
+	})
+}
+
+func (n *SemiJoinNode) Schema() schema.TableDef { return n.Input.Schema() }
+
+// subqueryValues runs the subquery and returns its non-NULL values.
+func (n *SemiJoinNode) subqueryValues(ctx context.Context) ([]types.Value, error) {
+	rows, err := materialize(ctx, n.Subquery)
+	if err != nil {
+		return nil, err
+	}
+	def := n.Subquery.Schema()
+	values := make([]types.Value, 0, len(rows))
+	for _, row := range rows {
+		v := row.Values[0]
+		if n.Field != nil {
+			if v, err = n.eval.scalar(n.Field, row, def); err != nil {
+				return nil, err
+			}
+		}
+		if !v.IsNull() {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// splitIn separates the IN subquery terms of a WHERE clause's top-level AND
+// chain from the rest, which is nil if nothing else remains.
+func splitIn(where *parser.WhereClause) (*parser.WhereClause, []*parser.InExpression) {
+	if where == nil {
+		return nil, nil
+	}
+	var ins []*parser.InExpression
+	var rest parser.Expression
+	for _, term := range conjuncts(where.Expr) {
+		if in, ok := term.(*parser.InExpression); ok {
+			ins = append(ins, in)
+			continue
+		}
+		if rest == nil {
+			rest = term
+		} else {
+			rest = &parser.InfixExpression{Left: rest, Operator: "AND", Right: term}
+		}
+	}
+	if rest == nil {
+		return nil, ins
+	}
+	return &parser.WhereClause{Expr: rest}, ins
+}
+
+// planSemiJoins wraps node in a SemiJoinNode for each IN subquery. Hash
+// lookups match raw values, so columns with a non-binary collation compare
+// value by value even when p.HashSemiJoin is set.
+func (p *Planner) planSemiJoins(node PlanNode, ins []*parser.InExpression) (PlanNode, error) {
+	for _, in := range ins {
+		def := node.Schema()
+		idx, err := def.ResolveColumn(in.Table, in.Column)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := p.CreatePlan(in.Subquery)
+		if err != nil {
+			return nil, fmt.Errorf("IN subquery: %w", err)
+		}
+		semi := &SemiJoinNode{
+			Input:     node,
+			In:        in,
+			Column:    idx,
+			Collation: def.Columns[idx].Collation,
+			Subquery:  sub,
+			eval:      p.eval(),
+		}
+		if !hasAggregates(in.Subquery.Fields) && !isGrouped(in.Subquery) {
+			semi.Field = in.Subquery.Fields[0]
+		}
+		semi.Hash = p.HashSemiJoin && semi.Collation.IsBinary()
+		node = semi
+	}
+	return node, nil
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/storage"
+	"testing"
+)
+
+// setupSemiJoinTables creates users and orders, where order i belongs to user
+// i % users + 1 and users with an even id are named 'even'.
+func setupSemiJoinTables(tb testing.TB, users, orders int) *Engine {
+	tb.Helper()
+	e := NewEngineWithConfig(Config{DataDir: tb.TempDir()})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT, email TEXT COLLATE NOCASE)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, amount INT, email TEXT COLLATE NOCASE)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			tb.Fatalf("%s: %v", sql, err)
+		}
+	}
+	insert := func(table string, sql string) {
+		stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+		if err != nil {
+			tb.Fatalf("%s: %v", sql, err)
+		}
+		values, err := e.insertValues(e.Tables[table], stmt.(*parser.InsertStmt).Values)
+		if err != nil {
+			tb.Fatalf("%s: %v", sql, err)
+		}
+		if err := e.Tables[table].Insert(values); err != nil {
+			tb.Fatalf("%s: %v", sql, err)
+		}
+	}
+	for i := 1; i <= users; i++ {
+		name := "odd"
+		if i%2 == 0 {
+			name = "even"
+		}
+		insert("users", fmt.Sprintf("INSERT INTO users VALUES (%d, '%s', 'U%d@X.COM')", i, name, i))
+	}
+	for i := 1; i <= orders; i++ {
+		user := i%users + 1
+		insert("orders", fmt.Sprintf("INSERT INTO orders VALUES (%d, %d, %d, 'u%d@x.com')", i, user, i%50, user))
+	}
+	return e
+}
+
+// runWithSemiJoin plans sql with hash semi-joins on or off and returns the
+// rows it produces.
+func runWithSemiJoin(tb testing.TB, e *Engine, sql string, hash bool) []storage.Row {
+	tb.Helper()
+	stmt, err := parser.NewParser(parser.NewTokenizer(sql)).ParseStatement()
+	if err != nil {
+		tb.Fatalf("%s: %v", sql, err)
+	}
+	sel := stmt.(*parser.SelectStmt)
+	if err := e.resolveSelect(sel); err != nil {
+		tb.Fatalf("%s: %v", sql, err)
+	}
+	planner := NewPlanner(e.Tables)
+	planner.HashSemiJoin = hash
+	plan, err := planner.CreatePlan(sel)
+	if err != nil {
+		tb.Fatalf("%s: %v", sql, err)
+	}
+	rows, err := materialize(context.Background(), plan)
+	if err != nil {
+		tb.Fatalf("%s: %v", sql, err)
+	}
+	return rows
+}
+
+func TestInSubqueryHashMatchesNaive(t *testing.T) {
+	e := setupSemiJoinTables(t, 40, 500)
+
+	tests := []struct {
+		sql  string
+		want int
+	}{
+		{"SELECT * FROM orders WHERE user_id IN (SELECT id FROM users WHERE name = 'even')", 250},
+		{"SELECT * FROM orders WHERE user_id IN (SELECT id FROM users WHERE name = 'even') AND amount < 10", -1},
+		{"SELECT * FROM orders WHERE user_id IN (SELECT id + 1 FROM users WHERE id < 5)", -1},
+		{"SELECT * FROM orders WHERE user_id IN (SELECT SUM(id) FROM users WHERE id < 3)", -1},
+		{"SELECT * FROM orders WHERE user_id IN (SELECT id FROM users WHERE id > 1000)", 0},
+		{"SELECT * FROM orders WHERE user_id IN (SELECT name FROM users)", 0},
+		{"SELECT * FROM orders WHERE email IN (SELECT email FROM users WHERE id < 3)", -1},
+		{"SELECT * FROM orders JOIN users ON orders.user_id = users.id WHERE orders.user_id IN (SELECT id FROM users WHERE name = 'odd')", -1},
+	}
+	for _, tt := range tests {
+		naive := runWithSemiJoin(t, e, tt.sql, false)
+		hashed := runWithSemiJoin(t, e, tt.sql, true)
+		if fmt.Sprint(naive) != fmt.Sprint(hashed) {
+			t.Errorf("%s: hash semi-join gave %d rows, naive gave %d", tt.sql, len(hashed), len(naive))
+		}
+		if tt.want >= 0 && len(hashed) != tt.want {
+			t.Errorf("%s: expected %d rows, got %d", tt.sql, tt.want, len(hashed))
+		}
+	}
+
+	// NOCASE columns compare value by value even with hashing on; orders
+	// hold lower-cased copies of their user's upper-cased email
+	if rows := runWithSemiJoin(t, e, tests[6].sql, true); len(rows) == 0 {
+		t.Errorf("Expected NOCASE IN to match case-insensitively")
+	}
+}
+
+func TestInSubqueryThroughExecute(t *testing.T) {
+	e := setupSemiJoinTables(t, 4, 20)
+	ctx := context.Background()
+
+	res, err := e.Execute(ctx, "SELECT id FROM orders WHERE user_id IN (SELECT id FROM users WHERE name = 'even') LIMIT 3")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if fmt.Sprint(res.Rows) != "[{[1]} {[3]} {[5]}]" {
+		t.Errorf("Unexpected rows: %v", res.Rows)
+	}
+
+	res, err = e.Execute(ctx, "DELETE FROM orders WHERE user_id IN (SELECT id FROM users WHERE name = 'odd')")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if res.RowsAffected != 10 {
+		t.Errorf("Expected 10 rows deleted, got %d", res.RowsAffected)
+	}
+
+	for _, sql := range []string{
+		"SELECT * FROM orders WHERE user_id IN (SELECT * FROM users)",
+		"SELECT * FROM orders WHERE user_id IN (SELECT id, name FROM users)",
+		"SELECT * FROM orders WHERE user_id IN (SELECT amount FROM users)",
+		"SELECT * FROM orders WHERE user_id IN (SELECT id FROM nowhere)",
+	} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func BenchmarkInSubquery(b *testing.B) {
+	e := setupSemiJoinTables(b, 2000, 5000)
+	sql := "SELECT * FROM orders WHERE user_id IN (SELECT id FROM users WHERE name = 'even')"
+
+	for _, hash := range []bool{false, true} {
+		b.Run(fmt.Sprintf("hash=%v", hash), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				runWithSemiJoin(b, e, sql, hash)
+			}
+		})
+	}
+}
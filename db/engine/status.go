@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"fmt"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"strconv"
+	"time"
+)
+
+// execShowStatus lists the engine's settings, every Config field but Clock,
+// and runtime stats as name/value rows. An empty storage_format means new
+// tables are saved as JSON and loaded ones keep their format. Loading every
+// table to count rows is part of the cost.
+func (e *Engine) execShowStatus() (*ResultSet, error) {
+	if err := e.loadAllTables(); err != nil {
+		return nil, err
+	}
+	tables := e.loadedTables()
+	totalRows := 0
+	for _, t := range tables {
+		totalRows += t.RowCount()
+	}
+	e.dirtyMu.Lock()
+	dirty := len(e.dirty)
+	e.dirtyMu.Unlock()
+
+	cfg := e.config
+	status := [][2]string{
+		{"data_dir", cfg.DataDir},
+		{"defer_writes", strconv.FormatBool(cfg.DeferWrites)},
+		{"flush_interval", cfg.FlushInterval.String()},
+		{"allow_drop_database", strconv.FormatBool(cfg.AllowDropDatabase)},
+		{"rowid_column", cfg.RowIDColumn},
+		{"row_locks", strconv.FormatBool(cfg.RowLocks)},
+		{"result_cache_size", strconv.Itoa(cfg.ResultCacheSize)},
+		{"max_result_rows", strconv.Itoa(cfg.MaxResultRows)},
+		{"storage_format", string(cfg.StorageFormat)},
+		{"sample_seed", strconv.FormatInt(cfg.SampleSeed, 10)},
+		{"int_overflow", string(cfg.IntOverflow)},
+		{"table_count", strconv.Itoa(len(tables))},
+		{"total_rows", strconv.Itoa(totalRows)},
+		{"dirty_tables", strconv.Itoa(dirty)},
+		{"uptime", cfg.Clock().Sub(e.started).Round(time.Second).String()},
+	}
+
+	rows := make([]storage.Row, len(status))
+	for i, kv := range status {
+		rows[i] = storage.Row{Values: []types.Value{types.NewText(kv[0]), types.NewText(kv[1])}}
+	}
+	return &ResultSet{
+		Columns:     []string{"name", "value"},
+		ColumnTypes: []types.DataType{types.TypeText, types.TypeText},
+		Rows:        rows,
+	}, nil
+}
+
+// execShowStats lists each column of a table with its min, max and distinct
+// count, computed by scanning the table. Min and max are shown as text so
+// INT and TEXT columns fit in one result; both are NULL for an empty table.
+func (e *Engine) execShowStats(tableName string) (*ResultSet, error) {
+	table, err := e.getTable(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+	text := func(v types.Value) types.Value {
+		if v.IsNull() {
+			return types.NewNull(types.TypeText)
+		}
+		return types.NewText(v.String())
+	}
+	var rows []storage.Row
+	for _, c := range table.ColumnStats() {
+		rows = append(rows, storage.Row{Values: []types.Value{
+			types.NewText(c.Name), text(c.Min), text(c.Max), types.NewInt(c.Distinct),
+		}})
+	}
+	return &ResultSet{
+		Columns:     []string{"column_name", "min", "max", "distinct_count"},
+		ColumnTypes: []types.DataType{types.TypeText, types.TypeText, types.TypeText, types.TypeInt},
+		Rows:        rows,
+	}, nil
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/storage"
+	"testing"
+	"time"
+)
+
+func TestShowStatus(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(1000, 0)
+	e := NewEngineWithConfig(Config{
+		DataDir:       dir,
+		DeferWrites:   true,
+		FlushInterval: time.Minute,
+		Clock:         func() time.Time { return now },
+		RowLocks:      true,
+		MaxResultRows: 500,
+		StorageFormat: storage.FormatGob,
+		SampleSeed:    42,
+	})
+	defer e.Close()
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+		"INSERT INTO users VALUES (2, 'Ben')",
+		"INSERT INTO orders VALUES (1, 100)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	now = now.Add(90 * time.Second)
+
+	res, err := e.Execute(ctx, "show status")
+	if err != nil {
+		t.Fatalf("SHOW STATUS failed: %v", err)
+	}
+	if len(res.Columns) != 2 || res.Columns[0] != "name" || res.Columns[1] != "value" {
+		t.Fatalf("Unexpected columns: %v", res.Columns)
+	}
+	got := make(map[string]string)
+	for _, row := range res.Rows {
+		got[row.Values[0].String()] = row.Values[1].String()
+	}
+	want := map[string]string{
+		"data_dir":            dir,
+		"defer_writes":        "true",
+		"flush_interval":      "1m0s",
+		"allow_drop_database": "false",
+		"rowid_column":        "rowid",
+		"row_locks":           "true",
+		"result_cache_size":   "0",
+		"max_result_rows":     "500",
+		"storage_format":      "gob",
+		"sample_seed":         "42",
+		"int_overflow":        "error",
+		"table_count":         "2",
+		"total_rows":          "3",
+		"dirty_tables":        "2",
+		"uptime":              "1m30s",
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+	// Nothing unexpected either: a new setting must be added here too
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("unexpected status %s", name)
+		}
+	}
+	if len(res.Rows) != len(want) {
+		t.Errorf("got %d status rows, want %d", len(res.Rows), len(want))
+	}
+
+	if _, err := e.Execute(ctx, "SHOW TABLES"); err == nil {
+		t.Errorf("Expected error for SHOW without STATUS")
+	}
+}
+
+func TestShowStats(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, amount INT, note TEXT)",
+		"INSERT INTO orders VALUES (1, 250, 'a')",
+		"INSERT INTO orders VALUES (2, -30, 'b')",
+		"INSERT INTO orders VALUES (3, 250, 'a')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "SHOW STATS orders")
+	if err != nil {
+		t.Fatalf("SHOW STATS failed: %v", err)
+	}
+	want := []string{"[id 1 3 3]", "[amount -30 250 2]", "[note a b 2]"}
+	if len(res.Rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %v", len(want), res.Rows)
+	}
+	for i, row := range res.Rows {
+		if got := fmt.Sprint(row.Values); got != want[i] {
+			t.Errorf("Row %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+
+	if _, err := e.Execute(ctx, "SHOW STATS missing"); err == nil {
+		t.Errorf("Expected an error for a missing table")
+	}
+}
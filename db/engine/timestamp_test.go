@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"mini-rdbms/db/types"
+	"testing"
+	"time"
+)
+
+func TestOnUpdateCurrentTimestamp(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	e := NewEngineWithConfig(Config{DataDir: dir, Clock: func() time.Time { return now }})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, status TEXT, updated_at TEXT ON UPDATE CURRENT_TIMESTAMP, version INT ON UPDATE CURRENT_TIMESTAMP)",
+		"INSERT INTO orders VALUES (1, 'open', 'never', 0)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	now = now.Add(90 * time.Second)
+	if _, err := e.Execute(ctx, "UPDATE orders SET status = 'shipped' WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	row, _ := e.Tables["orders"].GetRow(1)
+	if got := row.Values[2].Val; got != "2024-05-01 09:31:30" {
+		t.Errorf("Expected TEXT timestamp 2024-05-01 09:31:30, got %v", got)
+	}
+	if got := row.Values[3].Val; got != int(now.Unix()) {
+		t.Errorf("Expected INT timestamp %d, got %v", now.Unix(), got)
+	}
+
+	// An explicit assignment wins over the clock.
+	now = now.Add(time.Hour)
+	if _, err := e.Execute(ctx, "UPDATE orders SET updated_at = 'manual' WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	row, _ = e.Tables["orders"].GetRow(1)
+	if got := row.Values[2].Val; got != "manual" {
+		t.Errorf("Expected explicit value to be kept, got %v", got)
+	}
+	if got := row.Values[3].Val; got != int(now.Unix()) {
+		t.Errorf("Expected INT timestamp %d, got %v", now.Unix(), got)
+	}
+
+	if _, err := e.Execute(ctx, "CREATE TABLE bad (id INT PRIMARY KEY ON UPDATE CURRENT_TIMESTAMP)"); err == nil {
+		t.Errorf("Expected error for ON UPDATE on a primary key")
+	}
+}
+
+func TestInsertNow(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE orders (id INT PRIMARY KEY AUTO_INCREMENT, note TEXT, created_at INT, created TEXT, day DATE)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	before := time.Now().UTC()
+	for _, sql := range []string{
+		"INSERT INTO orders VALUES (1, 'a', NOW(), CURRENT_TIMESTAMP, now())",
+		// Without the AUTO_INCREMENT key the positions still line up
+		"INSERT INTO orders VALUES ('b', CURRENT_TIMESTAMP(), NOW(), NOW())",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	after := time.Now().UTC()
+
+	for _, id := range []int{1, 2} {
+		row, ok := e.Tables["orders"].GetRow(id)
+		if !ok {
+			t.Fatalf("Row %d not found", id)
+		}
+		unix, _ := row.Values[2].AsInt()
+		if unix < int(before.Unix()) || unix > int(after.Unix()) {
+			t.Errorf("Row %d: INT timestamp %d outside [%d, %d]", id, unix, before.Unix(), after.Unix())
+		}
+		text, _ := row.Values[3].AsText()
+		stamp, err := time.Parse(timestampLayout, text)
+		if err != nil || stamp.Before(before.Truncate(time.Second)) || stamp.After(after) {
+			t.Errorf("Row %d: TEXT timestamp %q outside [%s, %s] (%v)", id, text, before, after, err)
+		}
+		if day := row.Values[4].String(); day != before.Format("2006-01-02") && day != after.Format("2006-01-02") {
+			t.Errorf("Row %d: expected today's date, got %s", id, day)
+		}
+	}
+
+	// NOW() is read when the statement runs, not when it is parsed
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	e = NewEngineWithConfig(Config{DataDir: t.TempDir(), Clock: func() time.Time { return now }})
+	if _, err := e.Execute(ctx, "CREATE TABLE logs (id INT PRIMARY KEY, at TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for id := 1; id <= 2; id++ {
+		if _, err := e.ExecutePrepared(ctx, "INSERT INTO logs VALUES (?, NOW())", types.NewInt(id)); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+		now = now.Add(time.Minute)
+	}
+	for id, want := range map[int]string{1: "2030-01-02 03:04:05", 2: "2030-01-02 03:05:05"} {
+		if row, _ := e.Tables["logs"].GetRow(id); row.Values[1].Val != want {
+			t.Errorf("Row %d: expected %s, got %v", id, want, row.Values[1].Val)
+		}
+	}
+
+	if _, err := e.Execute(ctx, "INSERT INTO logs VALUES (3, NOW(1))"); err == nil {
+		t.Errorf("Expected NOW with an argument to fail")
+	}
+}
+
+func TestNowExpression(t *testing.T) {
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir(), ResultCacheSize: 8, Clock: func() time.Time { return now }})
+	ctx := context.Background()
+
+	for _, sql := range []string{"SELECT NOW()", "SELECT CURRENT_TIMESTAMP", "SELECT current_timestamp()"} {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		if got := fmt.Sprint(res.Rows[0].Values); got != "[2030-01-02 03:04:05]" {
+			t.Errorf("%s: got %s", sql, got)
+		}
+	}
+
+	for _, sql := range []string{
+		"CREATE TABLE events (id INT PRIMARY KEY, created TEXT)",
+		"INSERT INTO events VALUES (1, '2030-01-02 03:00:00')",
+		"INSERT INTO events VALUES (2, '2030-01-02 03:10:00')",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	past := func() string {
+		res, err := e.Execute(ctx, "SELECT id FROM events WHERE created < NOW()")
+		if err != nil {
+			t.Fatalf("Failed to select: %v", err)
+		}
+		return fmt.Sprint(res.Rows)
+	}
+	if got := past(); got != "[{[1]}]" {
+		t.Errorf("Expected only event 1 in the past, got %s", got)
+	}
+	// The result cache must not keep a result that depends on the clock
+	now = now.Add(time.Hour)
+	if got := past(); got != "[{[1]} {[2]}]" {
+		t.Errorf("Expected both events in the past an hour later, got %s", got)
+	}
+
+	if _, err := e.Execute(ctx, "SELECT NOW(1)"); err == nil {
+		t.Errorf("Expected NOW with an argument to fail")
+	}
+}
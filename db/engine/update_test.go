@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUpdateAllRows(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE orders (id INT PRIMARY KEY, ref TEXT UNIQUE, status TEXT)",
+		"INSERT INTO orders VALUES (1, 'A-1', 'open')",
+		"INSERT INTO orders VALUES (2, 'A-2', 'shipped')",
+		"INSERT INTO orders VALUES (3, 'A-3', 'open')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	res, err := e.Execute(ctx, "UPDATE orders SET status = 'archived'")
+	if err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if res.Message != "Updated 3 rows" {
+		t.Errorf("Unexpected message: %s", res.Message)
+	}
+
+	res, err = e.Execute(ctx, "SELECT status FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	if len(res.Rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(res.Rows))
+	}
+	for i, row := range res.Rows {
+		if row.Values[0].Val != "archived" {
+			t.Errorf("Row %d: expected archived, got %v", i, row.Values[0].Val)
+		}
+	}
+
+	// Setting a unique column on every row is rejected before anything changes.
+	if _, err := e.Execute(ctx, "UPDATE orders SET ref = 'X'"); err == nil {
+		t.Fatalf("Expected unique violation")
+	}
+	res, err = e.Execute(ctx, "SELECT ref FROM orders")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	for i, want := range []string{"A-1", "A-2", "A-3"} {
+		if got := res.Rows[i].Values[0].Val; got != want {
+			t.Errorf("Row %d: expected ref %s, got %v", i, want, got)
+		}
+	}
+}
+
+func TestUpdatePrimaryKey(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, email TEXT UNIQUE, team TEXT, INDEX (team))",
+		"CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, FOREIGN KEY (user_id) REFERENCES users(id))",
+		"INSERT INTO users VALUES (1, 'ann@x.com', 'red')",
+		"INSERT INTO users VALUES (2, 'ben@x.com', 'red')",
+		"INSERT INTO users VALUES (3, 'cy@x.com', 'blue')",
+		"INSERT INTO orders VALUES (100, 2)",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	ids := func(e *Engine, sql string) []interface{} {
+		res, err := e.Execute(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		var out []interface{}
+		for _, row := range res.Rows {
+			out = append(out, row.Values[0].Val)
+		}
+		return out
+	}
+
+	res, err := e.Execute(ctx, "UPDATE users SET id = 10 WHERE id = 1")
+	if err != nil {
+		t.Fatalf("Failed to change primary key: %v", err)
+	}
+	if res.RowsAffected != 1 {
+		t.Errorf("Expected 1 row updated, got %d", res.RowsAffected)
+	}
+	// The row is found under its new key through every index
+	for _, sql := range []string{
+		"SELECT id FROM users WHERE id = 10",
+		"SELECT id FROM users WHERE email = 'ann@x.com'",
+		"SELECT id FROM users WHERE team = 'red' AND email = 'ann@x.com'",
+	} {
+		if got := ids(e, sql); len(got) != 1 || got[0] != 10 {
+			t.Errorf("%s: expected [10], got %v", sql, got)
+		}
+	}
+	if got := ids(e, "SELECT id FROM users WHERE id = 1"); len(got) != 0 {
+		t.Errorf("Expected no row under the old key, got %v", got)
+	}
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"UPDATE users SET id = 3 WHERE id = 10", "duplicate primary key: 3"},
+		{"UPDATE users SET id = 20 WHERE team = 'red'", "duplicate primary key: 20"},
+		{"UPDATE users SET id = 20 WHERE id = 2", "cannot change primary key 2 of users: still referenced by orders.user_id"},
+	}
+	for _, tt := range tests {
+		_, err := e.Execute(ctx, tt.sql)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: expected %q, got %v", tt.sql, tt.want, err)
+		}
+	}
+
+	// The new key is saved
+	reloaded := NewEngineWithConfig(Config{DataDir: dir})
+	if got := ids(reloaded, "SELECT id FROM users"); len(got) != 3 || got[0] != 2 || got[1] != 3 || got[2] != 10 {
+		t.Errorf("After reload: expected [2 3 10], got %v", got)
+	}
+}
+
+func TestUpdateRejectsNullAndMismatchedTypes(t *testing.T) {
+	for _, rowLocks := range []bool{false, true} {
+		e := NewEngineWithConfig(Config{DataDir: t.TempDir(), RowLocks: rowLocks})
+		ctx := context.Background()
+		for _, sql := range []string{
+			"CREATE TABLE u (id INT PRIMARY KEY, name TEXT, n INT)",
+			"INSERT INTO u VALUES (1, 'Ann', 5)",
+		} {
+			if _, err := e.Execute(ctx, sql); err != nil {
+				t.Fatalf("%s: %v", sql, err)
+			}
+		}
+
+		tests := []struct {
+			sql  string
+			want string
+		}{
+			{"UPDATE u SET name = NULL WHERE id = 1", "NULL not allowed for column name"},
+			{"UPDATE u SET n = NULL", "NULL not allowed for column n"},
+			{"UPDATE u SET n = 'five' WHERE id = 1", "type mismatch for column n: expected INT, got TEXT"},
+		}
+		for _, tt := range tests {
+			_, err := e.Execute(ctx, tt.sql)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("row locks %v: %s: expected %q, got %v", rowLocks, tt.sql, tt.want, err)
+			}
+		}
+
+		res, err := e.Execute(ctx, "SELECT name, n FROM u")
+		if err != nil {
+			t.Fatalf("Failed to select: %v", err)
+		}
+		if got := res.Rows[0]; got.Values[0].Val != "Ann" || got.Values[1].Val != 5 {
+			t.Errorf("row locks %v: expected the row unchanged, got %v", rowLocks, got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestInsertOnConflict(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, email TEXT UNIQUE, visits INT CHECK (visits >= 0))",
+		"INSERT INTO users VALUES (1, 'ann@x.com', 1)",
+		"INSERT INTO users VALUES (2, 'ben@x.com', 1)",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	rows := func() string {
+		res, err := e.Execute(ctx, "SELECT id, email, visits FROM users")
+		if err != nil {
+			t.Fatalf("Failed to select: %v", err)
+		}
+		var out []string
+		for _, r := range res.Rows {
+			out = append(out, fmt.Sprint(r.Values[0].Val, " ", r.Values[1].Val, " ", r.Values[2].Val))
+		}
+		return fmt.Sprint(out)
+	}
+
+	// No conflict: a plain insert
+	res, err := e.Execute(ctx, "INSERT INTO users VALUES (3, 'cy@x.com', 1) ON CONFLICT (id) DO UPDATE SET visits = 9")
+	if err != nil {
+		t.Fatalf("Failed to upsert new row: %v", err)
+	}
+	if res.Message != "Insert successful" || res.RowsAffected != 1 || res.LastInsertID.Val != 3 {
+		t.Errorf("Unexpected insert result: %+v", res)
+	}
+
+	// Conflict on the key: the existing row is updated, the new one dropped
+	res, err = e.Execute(ctx, "INSERT INTO users VALUES (1, 'new@x.com', 1) ON CONFLICT (id) DO UPDATE SET visits = 2")
+	if err != nil {
+		t.Fatalf("Failed to upsert existing row: %v", err)
+	}
+	if res.Message != "Updated 1 rows" || res.RowsAffected != 1 || res.LastInsertID.Val != 1 {
+		t.Errorf("Unexpected update result: %+v", res)
+	}
+
+	// Conflict on a UNIQUE column updates the row that holds the value
+	if _, err := e.Execute(ctx, "INSERT INTO users VALUES (7, 'ben@x.com', 1) ON CONFLICT (email) DO UPDATE SET visits = 5"); err != nil {
+		t.Fatalf("Failed to upsert on email: %v", err)
+	}
+	want := "[1 ann@x.com 2 2 ben@x.com 5 3 cy@x.com 1]"
+	if got := rows(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	for _, tt := range []struct {
+		sql, want string
+	}{
+		// The update branch keeps UNIQUE and CHECK constraints
+		{"INSERT INTO users VALUES (1, 'x@x.com', 1) ON CONFLICT (id) DO UPDATE SET email = 'ben@x.com'", "duplicate unique value for column email: ben@x.com"},
+		{"INSERT INTO users VALUES (1, 'x@x.com', 1) ON CONFLICT (id) DO UPDATE SET visits = -1", "CHECK constraint failed for column visits: visits >= 0"},
+		// A clash on a column other than the conflict target is still an error
+		{"INSERT INTO users VALUES (9, 'ann@x.com', 1) ON CONFLICT (id) DO UPDATE SET visits = 0", "duplicate unique value for column email: ann@x.com"},
+		{"INSERT INTO users VALUES (1, 'x@x.com', 1) ON CONFLICT (visits) DO UPDATE SET visits = 0", "ON CONFLICT column visits is not a primary key or unique column"},
+		{"INSERT INTO users VALUES (1, 'x@x.com', 1) ON CONFLICT (id) DO UPDATE SET id = 4", "ON CONFLICT DO UPDATE cannot change the primary key id"},
+	} {
+		_, err := e.Execute(ctx, tt.sql)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: expected %q, got %v", tt.sql, tt.want, err)
+		}
+	}
+	if got := rows(); got != want {
+		t.Errorf("Failed upserts changed rows: got %s, want %s", got, want)
+	}
+
+	// The result survives a reload
+	reloaded := NewEngineWithConfig(Config{DataDir: dir})
+	res, err = reloaded.Execute(ctx, "SELECT visits FROM users WHERE id = 1")
+	if err != nil || len(res.Rows) != 1 || res.Rows[0].Values[0].Val != 2 {
+		t.Errorf("Expected visits 2 after reload, got %v (err %v)", res, err)
+	}
+}
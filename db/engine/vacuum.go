@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"mini-rdbms/db/parser"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"sort"
+)
+
+// execVacuum runs VACUUM [table]: each table's rows and indexes are rebuilt
+// in memory to release space left by deletes, and its file is rewritten
+// without indentation and stays compact on later saves. With deferred
+// writes this also saves any pending changes to the table. It returns one
+// row per table with the file size before and after, in bytes.
+func (e *Engine) execVacuum(stmt *parser.VacuumStmt) (*ResultSet, error) {
+	var tables []*storage.Table
+	if stmt.TableName != "" {
+		t, err := e.getTable(stmt.TableName)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	} else {
+		if err := e.loadAllTables(); err != nil {
+			return nil, err
+		}
+		for _, t := range e.loadedTables() {
+			tables = append(tables, t)
+		}
+		sort.Slice(tables, func(i, j int) bool { return tables[i].Def.Name < tables[j].Def.Name })
+	}
+
+	res := &ResultSet{
+		Columns:     []string{"table_name", "bytes_before", "bytes_after", "bytes_reclaimed"},
+		ColumnTypes: []types.DataType{types.TypeText, types.TypeInt, types.TypeInt, types.TypeInt},
+	}
+	for _, t := range tables {
+		// The rewrite saves everything in memory, so the table is no longer
+		// dirty; put it back if the save fails
+		e.dirtyMu.Lock()
+		_, wasDirty := e.dirty[t.Def.Name]
+		delete(e.dirty, t.Def.Name)
+		e.dirtyMu.Unlock()
+
+		before, after, err := storage.VacuumTable(e.config.DataDir, t)
+		if err != nil {
+			if wasDirty {
+				e.persist(t)
+			}
+			return nil, err
+		}
+		res.Rows = append(res.Rows, storage.Row{Values: []types.Value{
+			types.NewText(t.Def.Name),
+			types.NewInt(int(before)),
+			types.NewInt(int(after)),
+			types.NewInt(int(before - after)),
+		}})
+	}
+	return res, nil
+}
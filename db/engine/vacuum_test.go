@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVacuumCompactsFile(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir})
+	ctx := context.Background()
+
+	if _, err := e.Execute(ctx, "CREATE TABLE users (id INT PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i := 1; i <= 20; i++ {
+		if _, err := e.Execute(ctx, fmt.Sprintf("INSERT INTO users VALUES (%d, 'user %d')", i, i)); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	// Pad the file out with whitespace, as a hand edit might
+	file := filepath.Join(dir, "users.json")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read table file: %v", err)
+	}
+	var inflated bytes.Buffer
+	if err := json.Indent(&inflated, data, "", "                "); err != nil {
+		t.Fatalf("Failed to indent: %v", err)
+	}
+	if err := os.WriteFile(file, inflated.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write table file: %v", err)
+	}
+
+	e = NewEngineWithConfig(Config{DataDir: dir})
+	res, err := e.Execute(ctx, "VACUUM users")
+	if err != nil {
+		t.Fatalf("Failed to vacuum: %v", err)
+	}
+	if len(res.Rows) != 1 {
+		t.Fatalf("Expected one row, got %v", res.Rows)
+	}
+	before, _ := res.Rows[0].Values[1].AsInt()
+	after, _ := res.Rows[0].Values[2].AsInt()
+	reclaimed, _ := res.Rows[0].Values[3].AsInt()
+	if before != inflated.Len() || after >= len(data) || reclaimed != before-after {
+		t.Errorf("Unexpected sizes: before %d, after %d, reclaimed %d (original %d)", before, after, reclaimed, len(data))
+	}
+	if info, _ := os.Stat(file); info.Size() != int64(after) {
+		t.Errorf("Expected file of %d bytes, got %d", after, info.Size())
+	}
+
+	// Data survives, and later writes keep the file compact
+	if _, err := e.Execute(ctx, "INSERT INTO users VALUES (21, 'user 21')"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if n := diskRows(t, dir, "users"); n != 21 {
+		t.Errorf("Expected 21 rows on disk, got %d", n)
+	}
+	if data, _ := os.ReadFile(file); bytes.Contains(data, []byte("\n  ")) {
+		t.Errorf("Expected the file to stay compact after an insert")
+	}
+	if problems := e.VerifyPersistence(); len(problems) != 0 {
+		t.Errorf("Unexpected differences after vacuum: %v", problems)
+	}
+
+	// Without a name every table is vacuumed
+	if _, err := e.Execute(ctx, "CREATE TABLE tags (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	res, err = e.Execute(ctx, "VACUUM")
+	if err != nil {
+		t.Fatalf("Failed to vacuum: %v", err)
+	}
+	if len(res.Rows) != 2 || res.Rows[0].Values[0].Val != "tags" || res.Rows[1].Values[0].Val != "users" {
+		t.Errorf("Expected a row for each table, got %v", res.Rows)
+	}
+
+	for _, sql := range []string{"VACUUM nowhere", "VACUUM users users"} {
+		if _, err := e.Execute(ctx, sql); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func TestVacuumSavesDeferredWrites(t *testing.T) {
+	dir := t.TempDir()
+	e := NewEngineWithConfig(Config{DataDir: dir, DeferWrites: true})
+	ctx := context.Background()
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+		"VACUUM users",
+	} {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	if n := diskRows(t, dir, "users"); n != 1 {
+		t.Errorf("Expected the pending insert on disk, got %d rows", n)
+	}
+	e.dirtyMu.Lock()
+	dirty := len(e.dirty)
+	e.dirtyMu.Unlock()
+	if dirty != 0 {
+		t.Errorf("Expected no dirty tables after vacuum, got %d", dirty)
+	}
+}
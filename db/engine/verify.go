@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"fmt"
+	"mini-rdbms/db/storage"
+	"mini-rdbms/db/types"
+	"sort"
+)
+
+// VerifyPersistence compares every loaded table with what its file on disk
+// holds and returns one error per discrepancy: a row missing on either side
+// or a value that differs. A nil result means memory and disk agree.
+func (e *Engine) VerifyPersistence() []error {
+	tables := e.loadedTables()
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		problems = append(problems, e.verifyTable(tables[name])...)
+	}
+	return problems
+}
+
+func (e *Engine) verifyTable(mem *storage.Table) []error {
+	name := mem.Def.Name
+	disk, err := storage.ReadTable(e.config.DataDir, name)
+	if err != nil {
+		return []error{fmt.Errorf("table %s: %w", name, err)}
+	}
+
+	var problems []error
+	memKeys, memRows := mem.SortedSnapshot()
+	for i, pk := range memKeys {
+		diskRow, ok := disk.GetRow(pk)
+		if !ok {
+			problems = append(problems, fmt.Errorf("table %s: row %v missing on disk", name, pk))
+			continue
+		}
+		for c, col := range mem.Def.Columns {
+			m := memRows[i].Values[c]
+			if c >= len(diskRow.Values) {
+				problems = append(problems, fmt.Errorf("table %s: row %v column %s missing on disk", name, pk, col.Name))
+				continue
+			}
+			if d := diskRow.Values[c]; !sameValue(m, d) {
+				problems = append(problems, fmt.Errorf("table %s: row %v column %s is %s in memory but %s on disk", name, pk, col.Name, m, d))
+			}
+		}
+	}
+
+	diskKeys, _ := disk.SortedSnapshot()
+	for _, pk := range diskKeys {
+		if _, ok := mem.GetRow(pk); !ok {
+			problems = append(problems, fmt.Errorf("table %s: row %v on disk but not in memory", name, pk))
+		}
+	}
+	return problems
+}
+
+// sameValue reports whether two values are identical, including type.
+func sameValue(a, b types.Value) bool {
+	return a.Type == b.Type && a.Val == b.Val
+}
+
+// execVerify runs VERIFY, returning one row per discrepancy.
+func (e *Engine) execVerify() (*ResultSet, error) {
+	problems := e.VerifyPersistence()
+	if len(problems) == 0 {
+		return &ResultSet{Message: "All tables match their files on disk"}, nil
+	}
+	res := &ResultSet{
+		Columns:     []string{"problem"},
+		ColumnTypes: []types.DataType{types.TypeText},
+	}
+	for _, p := range problems {
+		res.Rows = append(res.Rows, storage.Row{Values: []types.Value{types.NewText(p.Error())}})
+	}
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"context"
+	"mini-rdbms/db/types"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPersistence(t *testing.T) {
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	ctx := context.Background()
+
+	stmts := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann')",
+		"INSERT INTO users VALUES (2, 'Bob')",
+		"INSERT INTO users VALUES (3, 'Cy')",
+	}
+	for _, sql := range stmts {
+		if _, err := e.Execute(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	if problems := e.VerifyPersistence(); len(problems) != 0 {
+		t.Fatalf("Expected saved tables to verify, got %v", problems)
+	}
+
+	// Change the in-memory table directly, skipping the save.
+	users := e.Tables["users"]
+	if err := users.Insert([]types.Value{types.NewInt(4), types.NewText("Dee")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Update(types.NewInt(2), []types.Value{types.NewInt(2), types.NewText("Bobby")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(types.NewInt(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	problems := e.VerifyPersistence()
+	want := []string{
+		"table users: row 2 column name is Bobby in memory but Bob on disk",
+		"table users: row 4 missing on disk",
+		"table users: row 3 on disk but not in memory",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Expected %d problems, got %v", len(want), problems)
+	}
+	for i, w := range want {
+		if problems[i].Error() != w {
+			t.Errorf("Problem %d: expected %q, got %q", i, w, problems[i])
+		}
+	}
+
+	res, err := e.Execute(ctx, "VERIFY")
+	if err != nil {
+		t.Fatalf("VERIFY: %v", err)
+	}
+	if len(res.Rows) != len(want) || !strings.Contains(res.Rows[0].Values[0].String(), "Bobby") {
+		t.Errorf("Unexpected VERIFY result: %v", res.Rows)
+	}
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// whereIDs runs SELECT id FROM users WHERE cond and returns the ids found.
+func whereIDs(t *testing.T, e *Engine, cond string) string {
+	t.Helper()
+	res, err := e.Execute(context.Background(), "SELECT id FROM users WHERE "+cond)
+	if err != nil {
+		t.Fatalf("%s: %v", cond, err)
+	}
+	ids := make([]interface{}, len(res.Rows))
+	for i, row := range res.Rows {
+		ids[i] = row.Values[0].Val
+	}
+	return fmt.Sprint(ids)
+}
+
+func setupWhereUsers(t *testing.T) *Engine {
+	t.Helper()
+	e := NewEngineWithConfig(Config{DataDir: t.TempDir()})
+	for _, sql := range []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name TEXT, age INT)",
+		"INSERT INTO users VALUES (1, 'Ann', 30)",
+		"INSERT INTO users VALUES (2, 'Ben', 25)",
+		"INSERT INTO users VALUES (3, 'Cy', 30)",
+		"INSERT INTO users VALUES (4, 'Di', 40)",
+	} {
+		if _, err := e.Execute(context.Background(), sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	return e
+}
+
+func TestWhereNotAndOr(t *testing.T) {
+	e := setupWhereUsers(t)
+
+	tests := []struct {
+		cond string
+		want string
+	}{
+		{"NOT (id = 1)", "[2 3 4]"},
+		{"NOT id = 1", "[2 3 4]"},
+		{"NOT (age = 30) AND id > 1", "[2 4]"},
+		{"id = 1 OR id = 4", "[1 4]"},
+		{"age = 30 AND id > 1 OR name = 'Ben'", "[2 3]"}, // AND binds tighter
+		{"name = 'Ben' OR age = 30 AND id > 1", "[2 3]"}, // on either side
+		{"NOT (id = 1 OR id = 2)", "[3 4]"},
+		{"NOT (age = 30 AND id = 1) AND NOT (id = 4)", "[2 3]"},
+		{"NOT NOT (id = 2)", "[2]"},
+	}
+	for _, tt := range tests {
+		if got := whereIDs(t, e, tt.cond); got != tt.want {
+			t.Errorf("WHERE %s: got %s, want %s", tt.cond, got, tt.want)
+		}
+	}
+
+	// NOT and OR work in UPDATE and DELETE too
+	res, err := e.Execute(context.Background(), "DELETE FROM users WHERE NOT (age = 30 OR age = 25)")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if res.RowsAffected != 1 {
+		t.Errorf("Expected 1 row deleted, got %d", res.RowsAffected)
+	}
+
+	if _, err := e.Execute(context.Background(), "SELECT id FROM users WHERE NOT (nope = 1)"); err == nil {
+		t.Errorf("Expected error for an unknown column under NOT")
+	}
+	if _, err := e.Execute(context.Background(), "SELECT id FROM users WHERE NOT (id IN (SELECT id FROM users))"); err == nil {
+		t.Errorf("Expected error for an IN subquery under NOT")
+	}
+}
+
+func TestWhereNotUnknown(t *testing.T) {
+	e := setupWhereUsers(t)
+
+	// A comparison with NULL or between mismatched types is unknown, and so
+	// is its negation: neither selects a row.
+	tests := []struct {
+		cond string
+		want string
+	}{
+		{"age = NULL", "[]"},
+		{"NOT (age = NULL)", "[]"},
+		{"NOT (age != NULL)", "[]"},
+		{"name = 5", "[]"},
+		{"NOT (name = 5)", "[]"},
+		{"NOT NOT (name = 5)", "[]"},
+		{"NOT (name = 5) OR id = 1", "[1]"},
+		{"NOT (name = 5 AND id = 1)", "[2 3 4]"},
+		{"NOT (name = 5 OR id = 1)", "[]"},
+		{"NOT (age LIKE '3%')", "[]"},
+	}
+	for _, tt := range tests {
+		if got := whereIDs(t, e, tt.cond); got != tt.want {
+			t.Errorf("WHERE %s: got %s, want %s", tt.cond, got, tt.want)
+		}
+	}
+
+	res, err := e.Execute(context.Background(), "DELETE FROM users WHERE NOT (name = 5)")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if res.RowsAffected != 0 {
+		t.Errorf("Expected no rows deleted, got %d", res.RowsAffected)
+	}
+}
+
+func TestWhereParentheses(t *testing.T) {
+	e := setupWhereUsers(t)
+
+	tests := []struct {
+		cond string
+		want string
+	}{
+		// Grouping overrides AND binding tighter than OR
+		{"id = 1 OR id = 2 AND age = 30", "[1]"},
+		{"(id = 1 OR id = 2) AND age = 30", "[1]"},
+		{"age = 30 AND id = 3 OR id = 2", "[2 3]"},
+		{"age = 30 AND (id = 3 OR id = 2)", "[3]"},
+		{"(age = 25 OR age = 40) AND NOT (id = 4)", "[2]"},
+		{"((id = 1))", "[1]"},
+		{"(id = 1 OR (id = 2 OR id = 3)) AND age > 25", "[1 3]"},
+		// A parenthesized operand is still arithmetic
+		{"(age + 5) * 2 = 70", "[1 3]"},
+		{"(age) = 40 OR (id = 2)", "[2 4]"},
+	}
+	for _, tt := range tests {
+		if got := whereIDs(t, e, tt.cond); got != tt.want {
+			t.Errorf("WHERE %s: got %s, want %s", tt.cond, got, tt.want)
+		}
+	}
+
+	for _, cond := range []string{"(id = 1", "(id = 1 OR) AND age = 30", "()"} {
+		if _, err := e.Execute(context.Background(), "SELECT id FROM users WHERE "+cond); err == nil {
+			t.Errorf("WHERE %s: expected error", cond)
+		}
+	}
+}
+
+func TestWhereNotEqual(t *testing.T) {
+	e := setupWhereUsers(t)
+
+	for _, op := range []string{"!=", "<>"} {
+		tests := []struct {
+			cond string
+			want string
+		}{
+			{"age " + op + " 30", "[2 4]"},
+			{"name " + op + " 'Ben'", "[1 3 4]"},
+			{"name " + op + " 'ben' COLLATE NOCASE", "[1 3 4]"},
+			{"NOT (age " + op + " 30)", "[1 3]"},
+			{"id " + op + " age", "[1 2 3 4]"},
+			{"LENGTH(name) " + op + " 2", "[1 2]"},
+		}
+		for _, tt := range tests {
+			if got := whereIDs(t, e, tt.cond); got != tt.want {
+				t.Errorf("WHERE %s: got %s, want %s", tt.cond, got, tt.want)
+			}
+		}
+	}
+
+	res, err := e.Execute(context.Background(), "UPDATE users SET age = 0 WHERE name <> 'Ann'")
+	if err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if res.RowsAffected != 3 {
+		t.Errorf("Expected 3 rows updated, got %d", res.RowsAffected)
+	}
+}
@@ -5,39 +5,60 @@ import (
 )
 
 // HashIndex implements a simple hash map for indexing unique values.
-// It maps a Value (Value.Val) to a Primary Key.
+// It maps a Value (by Value.Hash) to a Primary Key.
 // Since we only support Unique / Primary Key indices in this requirement scope,
 // 1-to-1 mapping is sufficient.
 type HashIndex struct {
 	// Map from index key value to Primary Key of the row
-	// Key is the raw value (int or string)
-	Data map[interface{}]interface{}
+	// Key is the value's Hash, so 2 and a JSON-decoded 2.0 share an entry
+	Data map[string]interface{}
+
+	keys sortedKeys // Data's keys in order, for Range
 }
 
 // NewHashIndex creates an empty index.
 func NewHashIndex() *HashIndex {
 	return &HashIndex{
-		Data: make(map[interface{}]interface{}),
+		Data: make(map[string]interface{}),
 	}
 }
 
 // Get returns the Primary Key associated with the value.
 func (idx *HashIndex) Get(val types.Value) (interface{}, bool) {
-	pk, ok := idx.Data[val.Val]
+	pk, ok := idx.Data[val.Hash()]
 	return pk, ok
 }
 
 // Set inserts or updates the key-pk pair.
 func (idx *HashIndex) Set(val types.Value, pk interface{}) {
-	idx.Data[val.Val] = pk
+	idx.Data[val.Hash()] = pk
+	idx.keys.insert(val)
 }
 
 // Delete removes the key.
 func (idx *HashIndex) Delete(val types.Value) {
-	delete(idx.Data, val.Val)
+	delete(idx.Data, val.Hash())
+	idx.keys.remove(val)
 }
 
-// Rebuild clears and rebuilds the index (placeholder if needed).
+// Clear removes every entry from the index.
 func (idx *HashIndex) Clear() {
-	idx.Data = make(map[interface{}]interface{})
+	idx.Data = make(map[string]interface{})
+	idx.keys = sortedKeys{}
+}
+
+// Range returns the Primary Keys of values in [lo, hi), ordered by value.
+// A NULL hi means no upper bound.
+func (idx *HashIndex) Range(lo, hi types.Value) []interface{} {
+	keys := idx.keys.between(lo, hi)
+	pks := make([]interface{}, len(keys))
+	for i, k := range keys {
+		pks[i] = idx.Data[k.Hash()]
+	}
+	return pks
+}
+
+// Bounds returns the smallest and largest values in the index.
+func (idx *HashIndex) Bounds() (lo, hi types.Value, ok bool) {
+	return idx.keys.bounds()
 }
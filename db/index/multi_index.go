@@ -0,0 +1,80 @@
+package index
+
+import (
+	"mini-rdbms/db/types"
+)
+
+// MultiIndex is a hash index for non-unique columns.
+// It maps a Value (by Value.Hash) to the set of Primary Keys of rows holding it.
+type MultiIndex struct {
+	// Map from index key value to a set of Primary Keys
+	Data map[string]map[interface{}]struct{}
+
+	keys sortedKeys // Data's keys in order, for Range
+}
+
+// NewMultiIndex creates an empty non-unique index.
+func NewMultiIndex() *MultiIndex {
+	return &MultiIndex{
+		Data: make(map[string]map[interface{}]struct{}),
+	}
+}
+
+// Get returns the Primary Keys associated with the value, in no particular order.
+func (idx *MultiIndex) Get(val types.Value) []interface{} {
+	set := idx.Data[val.Hash()]
+	pks := make([]interface{}, 0, len(set))
+	for pk := range set {
+		pks = append(pks, pk)
+	}
+	return pks
+}
+
+// Add records that the row with pk holds val.
+func (idx *MultiIndex) Add(val types.Value, pk interface{}) {
+	key := val.Hash()
+	set, ok := idx.Data[key]
+	if !ok {
+		set = make(map[interface{}]struct{})
+		idx.Data[key] = set
+		idx.keys.insert(val)
+	}
+	set[pk] = struct{}{}
+}
+
+// Remove forgets that the row with pk holds val.
+func (idx *MultiIndex) Remove(val types.Value, pk interface{}) {
+	key := val.Hash()
+	set, ok := idx.Data[key]
+	if !ok {
+		return
+	}
+	delete(set, pk)
+	if len(set) == 0 {
+		delete(idx.Data, key)
+		idx.keys.remove(val)
+	}
+}
+
+// Clear removes every entry from the index.
+func (idx *MultiIndex) Clear() {
+	idx.Data = make(map[string]map[interface{}]struct{})
+	idx.keys = sortedKeys{}
+}
+
+// Range returns the Primary Keys of rows whose value is in [lo, hi), in no
+// particular order. A NULL hi means no upper bound.
+func (idx *MultiIndex) Range(lo, hi types.Value) []interface{} {
+	var pks []interface{}
+	for _, k := range idx.keys.between(lo, hi) {
+		for pk := range idx.Data[k.Hash()] {
+			pks = append(pks, pk)
+		}
+	}
+	return pks
+}
+
+// Bounds returns the smallest and largest values in the index.
+func (idx *MultiIndex) Bounds() (lo, hi types.Value, ok bool) {
+	return idx.keys.bounds()
+}
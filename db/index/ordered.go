@@ -0,0 +1,89 @@
+package index
+
+import (
+	"mini-rdbms/db/types"
+	"sort"
+)
+
+// RangeIndex is an index that keeps its keys ordered, so it can return the
+// Primary Keys of every value in [lo, hi) without visiting other entries.
+// A NULL lo or hi leaves that end of the range open.
+type RangeIndex interface {
+	Range(lo, hi types.Value) []interface{}
+	// Bounds returns the smallest and largest keys; ok is false if the
+	// index is empty.
+	Bounds() (lo, hi types.Value, ok bool)
+}
+
+var (
+	_ RangeIndex = (*HashIndex)(nil)
+	_ RangeIndex = (*MultiIndex)(nil)
+)
+
+// sortedKeys keeps an index's distinct keys in ascending order so a range of
+// keys can be found by binary search instead of visiting every entry.
+// Inserting and removing keys shifts the slice, which is fine at this
+// project's table sizes.
+type sortedKeys struct {
+	keys []types.Value
+}
+
+// keyLess orders index keys: INTs numerically, TEXT byte-wise, DATEs
+// chronologically, and INTs before TEXT before DATEs so mixed keys still
+// have a total order.
+func keyLess(a, b types.Value) bool {
+	if a.Type != b.Type {
+		return typeRank[a.Type] < typeRank[b.Type]
+	}
+	cmp, err := a.Compare(b)
+	return err == nil && cmp < 0
+}
+
+var typeRank = map[types.DataType]int{types.TypeInt: 0, types.TypeText: 1, types.TypeDate: 2}
+
+// search returns the position of the first key not less than k.
+func (s *sortedKeys) search(k types.Value) int {
+	return sort.Search(len(s.keys), func(i int) bool { return !keyLess(s.keys[i], k) })
+}
+
+func (s *sortedKeys) insert(k types.Value) {
+	i := s.search(k)
+	if i < len(s.keys) && s.keys[i].Hash() == k.Hash() {
+		return
+	}
+	s.keys = append(s.keys, types.Value{})
+	copy(s.keys[i+1:], s.keys[i:])
+	s.keys[i] = k
+}
+
+func (s *sortedKeys) remove(k types.Value) {
+	i := s.search(k)
+	if i < len(s.keys) && s.keys[i].Hash() == k.Hash() {
+		s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	}
+}
+
+// between returns the keys in [lo, hi). A NULL lo or hi leaves that end of
+// the range open.
+func (s *sortedKeys) between(lo, hi types.Value) []types.Value {
+	start := 0
+	if !lo.IsNull() {
+		start = s.search(lo)
+	}
+	end := len(s.keys)
+	if !hi.IsNull() {
+		end = s.search(hi)
+	}
+	if start >= end {
+		return nil
+	}
+	return s.keys[start:end]
+}
+
+// bounds returns the first and last keys; ok is false if there are none.
+func (s *sortedKeys) bounds() (lo, hi types.Value, ok bool) {
+	if len(s.keys) == 0 {
+		return types.Value{}, types.Value{}, false
+	}
+	return s.keys[0], s.keys[len(s.keys)-1], true
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"mini-rdbms/db/schema"
 	"mini-rdbms/db/types"
+	"strings"
 )
 
 // ASTRoot interfaces
@@ -14,27 +15,82 @@ type Statement interface {
 type CreateTableStmt struct {
 	TableName string
 	Columns   []schema.ColumnDef
+	Indexes   []string // Columns named in table-level INDEX (col) clauses
+	// ForeignKeys come from FOREIGN KEY (col) REFERENCES table(col) clauses.
+	ForeignKeys []schema.ForeignKeyDef
+	// InsertionOrder is set by a trailing INSERTION_ORDER table option.
+	InsertionOrder bool
 }
 
 func (s *CreateTableStmt) statementNode() {}
 
 type InsertStmt struct {
 	TableName string
-	Values    []types.Value
+	// Values are evaluated when the statement runs, so NOW() reads the
+	// clock each time.
+	Values []Expression // *Literal, *FunctionCall or arithmetic *InfixExpression
+	// OnConflict, when set, turns the insert into an upsert.
+	OnConflict *ConflictClause
 }
 
 func (s *InsertStmt) statementNode() {}
 
 type SelectStmt struct {
-	Fields    []string // empty/asterisk means all
+	Fields    []Expression // *Star, *ColumnRef, *Literal, *FunctionCall or arithmetic *InfixExpression
+	Aliases   []string     // Parallel to Fields; the AS name, or "" if none
 	TableName string
+	Sample    *SampleClause
 	Join      *JoinClause
 	Where     *WhereClause
+	GroupBy   []*ColumnRef
+	Having    Expression // Condition on each group; may call aggregates
 	Limit     int
 }
 
 func (s *SelectStmt) statementNode() {}
 
+// String renders the statement as SQL that the parser accepts again.
+func (s *SelectStmt) String() string {
+	fields := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		fields[i] = f.String()
+		if s.Aliases[i] != "" {
+			fields[i] += " AS " + schema.QuoteIdent(s.Aliases[i])
+		}
+	}
+	sql := "SELECT " + strings.Join(fields, ", ")
+	if s.TableName == "" {
+		return sql
+	}
+	sql += " FROM " + schema.QuoteIdent(s.TableName)
+	if s.Join != nil && s.Join.OnLeft == nil {
+		sql += ", " + schema.QuoteIdent(s.Join.Table)
+	}
+	if s.Sample != nil {
+		sql += fmt.Sprintf(" TABLESAMPLE (%d PERCENT)", s.Sample.Percent)
+	}
+	if s.Join != nil && s.Join.OnLeft != nil {
+		sql += " JOIN " + schema.QuoteIdent(s.Join.Table) + " ON " + s.Join.OnLeft.String() + " = " + s.Join.OnRight.String()
+	}
+	if s.Where != nil {
+		sql += " WHERE " + s.Where.Expr.String()
+	}
+	if len(s.GroupBy) > 0 {
+		keys := make([]string, len(s.GroupBy))
+		for i, k := range s.GroupBy {
+			keys[i] = k.String()
+		}
+		sql += " GROUP BY " + strings.Join(keys, ", ")
+	}
+	if s.Having != nil {
+		sql += " HAVING " + s.Having.String()
+	}
+	if s.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", s.Limit)
+	}
+	return sql
+}
+
 type UpdateStmt struct {
 	TableName string
 	Set       map[string]types.Value
@@ -50,6 +106,45 @@ type DeleteStmt struct {
 
 func (s *DeleteStmt) statementNode() {}
 
+// DropDatabaseStmt is DROP DATABASE: remove every table in memory and on disk.
+type DropDatabaseStmt struct{}
+
+func (s *DropDatabaseStmt) statementNode() {}
+
+// VerifyStmt is VERIFY: compare every loaded table with its file on disk.
+type VerifyStmt struct{}
+
+func (s *VerifyStmt) statementNode() {}
+
+// ShowStatusStmt is SHOW STATUS: list the engine's settings and runtime stats.
+type ShowStatusStmt struct{}
+
+func (s *ShowStatusStmt) statementNode() {}
+
+// ShowStatsStmt is SHOW STATS table: list each column's min, max and
+// distinct count.
+type ShowStatsStmt struct {
+	TableName string
+}
+
+func (s *ShowStatsStmt) statementNode() {}
+
+// VacuumStmt is VACUUM [table]: rewrite table files in compact form. An
+// empty TableName means every table.
+type VacuumStmt struct {
+	TableName string
+}
+
+func (s *VacuumStmt) statementNode() {}
+
+// ExplainStmt is EXPLAIN followed by a SELECT, UPDATE or DELETE, which is
+// planned but not run.
+type ExplainStmt struct {
+	Stmt Statement
+}
+
+func (s *ExplainStmt) statementNode() {}
+
 // Clauses
 
 // Expressions
@@ -64,26 +159,184 @@ type InfixExpression struct {
 	Right    Expression
 }
 
+// String renders the expression as SQL that the parser accepts again.
 func (e *InfixExpression) String() string {
-	return "(" + e.Left.String() + " " + e.Operator + " " + e.Right.String() + ")"
+	left, right := e.Left.String(), e.Right.String()
+	if prec, ok := arithmeticOps[e.Operator]; ok {
+		// Parenthesize operands that would otherwise regroup: a looser
+		// operator on either side, or an equal one on the right.
+		if l, ok := e.Left.(*InfixExpression); ok && arithmeticOps[l.Operator] != 0 && arithmeticOps[l.Operator] < prec {
+			left = "(" + left + ")"
+		}
+		if r, ok := e.Right.(*InfixExpression); ok && arithmeticOps[r.Operator] != 0 && arithmeticOps[r.Operator] <= prec {
+			right = "(" + right + ")"
+		}
+	}
+	if prec, ok := logicalOps[e.Operator]; ok {
+		// Both are associative; only an OR under an AND needs parentheses
+		if l, ok := e.Left.(*InfixExpression); ok && logicalOps[l.Operator] != 0 && logicalOps[l.Operator] < prec {
+			left = "(" + left + ")"
+		}
+		if r, ok := e.Right.(*InfixExpression); ok && logicalOps[r.Operator] != 0 && logicalOps[r.Operator] < prec {
+			right = "(" + right + ")"
+		}
+	}
+	return left + " " + e.Operator + " " + right
+}
+
+// logicalOps maps AND and OR to their binding precedence.
+var logicalOps = map[string]int{"OR": OR, "AND": AND}
+
+// arithmeticOps maps arithmetic operators to their binding precedence.
+var arithmeticOps = map[string]int{"+": SUM, "-": SUM, "*": PRODUCT, "/": PRODUCT}
+
+// NotExpression is NOT applied to a condition.
+type NotExpression struct {
+	Expr Expression
+}
+
+func (e *NotExpression) String() string {
+	return "NOT (" + e.Expr.String() + ")"
+}
+
+// Star is * in a SELECT list: every column of the input, or with Table set
+// (users.*) every column from that table.
+type Star struct {
+	Table string
+}
+
+func (e *Star) String() string {
+	if e.Table != "" {
+		return schema.QuoteIdent(e.Table) + ".*"
+	}
+	return "*"
+}
+
+// ColumnRef reads a column. Table is empty for a bare name until the engine
+// resolves it to the column's source table.
+type ColumnRef struct {
+	Table string
+	Name  string
+}
+
+func (e *ColumnRef) String() string { return qualified(e.Table, e.Name) }
+
+// Literal is a constant value.
+type Literal struct {
+	Value types.Value
+}
+
+func (e *Literal) String() string { return literal(e.Value) }
+
+// FunctionCall is a scalar function applied to arguments, e.g. GREATEST(a, 100).
+// Name is upper-cased.
+type FunctionCall struct {
+	Name     string
+	Args     []Expression
+	Distinct bool // COUNT(DISTINCT col): only count each value once
+}
+
+func (e *FunctionCall) String() string {
+	args := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = a.String()
+	}
+	if e.Distinct {
+		return e.Name + "(DISTINCT " + strings.Join(args, ", ") + ")"
+	}
+	return e.Name + "(" + strings.Join(args, ", ") + ")"
 }
 
 type ComparisonExpression struct {
-	Column   string // For now, left side is always column
-	Operator string // =
+	Table    string     // Source table of Column; empty for a bare, unresolved name
+	Column   string     // Left side, unless Left is set
+	Left     Expression // When set, compare this scalar expression instead of Column, e.g. LENGTH(email)
+	Operator string     // =, !=, <, >, <=, >=
 	Value    types.Value
+	Right    *ColumnRef // When set, compare against this column instead of Value
+	// RightExpr, when set, is a scalar expression compared against instead
+	// of Value, e.g. the 2 * 50 in n > 2 * 50
+	RightExpr Expression
+	// Collation overrides the column's collation for this comparison
+	// (e.g. name = 'alice' COLLATE NOCASE). Empty means use the column's.
+	Collation types.Collation
 }
 
 func (e *ComparisonExpression) String() string {
-	return fmt.Sprintf("%s %s %v", e.Column, e.Operator, e.Value)
+	right := literal(e.Value)
+	if e.Right != nil {
+		right = e.Right.String()
+	}
+	if e.RightExpr != nil {
+		right = e.RightExpr.String()
+	}
+	left := qualified(e.Table, e.Column)
+	if e.Left != nil {
+		left = e.Left.String()
+	}
+	if e.Collation != "" {
+		right += " COLLATE " + string(e.Collation)
+	}
+	return fmt.Sprintf("%s %s %s", left, e.Operator, right)
+}
+
+// InExpression is "col IN (SELECT ...)": true when the column equals a value
+// the subquery returns. The subquery must select exactly one column and may
+// not refer to the outer query.
+type InExpression struct {
+	Table    string // Source table of Column; empty for a bare, unresolved name
+	Column   string
+	Subquery *SelectStmt
+}
+
+func (e *InExpression) String() string {
+	return qualified(e.Table, e.Column) + " IN (" + e.Subquery.String() + ")"
+}
+
+// qualified renders a column name with its table prefix, if any, quoting
+// either where needed.
+func qualified(table, column string) string {
+	if table == "" {
+		return schema.QuoteIdent(column)
+	}
+	return schema.QuoteIdent(table) + "." + schema.QuoteIdent(column)
+}
+
+// literal renders a value as a SQL literal.
+func literal(v types.Value) string {
+	if v.IsNull() {
+		return "NULL"
+	}
+	switch v.Type {
+	case types.TypeText:
+		return "'" + v.String() + "'"
+	case types.TypeDate:
+		return "DATE '" + v.String() + "'"
+	}
+	return v.String()
+}
+
+// ConflictClause is ON CONFLICT (Column) DO UPDATE SET ...: when the new
+// row's Column value already belongs to a row, that row gets Set instead.
+type ConflictClause struct {
+	Column string
+	Set    map[string]types.Value
 }
 
 type WhereClause struct {
 	Expr Expression
 }
 
+// SampleClause is TABLESAMPLE (Percent PERCENT).
+type SampleClause struct {
+	Percent int
+}
+
+// JoinClause is JOIN Table ON OnLeft = OnRight. After name resolution OnLeft
+// belongs to the FROM table and OnRight to the joined one. An implicit join
+// (FROM a, b) has no ON columns; the planner takes them from WHERE if it can.
 type JoinClause struct {
 	Table   string
-	OnLeft  string // table.col
-	OnRight string // table.col
+	OnLeft  *ColumnRef
+	OnRight *ColumnRef
 }
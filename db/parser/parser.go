@@ -1,17 +1,26 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"mini-rdbms/db/schema"
 	"mini-rdbms/db/types"
 	"strconv"
+	"strings"
 )
 
+// ErrEmptyStatement is returned when the input holds no statement,
+// e.g. only whitespace or comments.
+var ErrEmptyStatement = errors.New("empty statement")
+
 type Parser struct {
 	l         *Tokenizer
 	curToken  Token
 	peekToken Token
 	errors    []string
+
+	args   []types.Value // values for ? placeholders
+	argPos int           // next unused entry of args
 }
 
 func NewParser(l *Tokenizer) *Parser {
@@ -44,11 +53,40 @@ func (p *Parser) expectPeek(t TokenType) bool {
 }
 
 func (p *Parser) peekError(t TokenType) {
-	msg := fmt.Sprintf("expected next token to be %d, got %d ('%s') instead", t, p.peekToken.Type, p.peekToken.Literal)
+	msg := fmt.Sprintf("line %d, column %d: expected %s, got %s",
+		p.peekToken.Line, p.peekToken.Column, t, describe(p.peekToken))
 	p.errors = append(p.errors, msg)
 }
 
+// describe names a token for an error message, quoting what was written
+// when the type's name alone does not say it, e.g. identifier 'users'.
+func describe(tok Token) string {
+	name := tok.Type.String()
+	if tok.Literal == "" || strings.EqualFold(tok.Literal, name) {
+		return name
+	}
+	return fmt.Sprintf("%s '%s'", name, tok.Literal)
+}
+
+// Bind supplies values for ? placeholders, used left to right. They go
+// straight into the AST and are never spliced into SQL text.
+func (p *Parser) Bind(args ...types.Value) {
+	p.args = args
+	p.argPos = 0
+}
+
 func (p *Parser) ParseStatement() (Statement, error) {
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if p.argPos != len(p.args) {
+		return nil, fmt.Errorf("statement has %d placeholders, got %d arguments", p.argPos, len(p.args))
+	}
+	return stmt, nil
+}
+
+func (p *Parser) parseStatement() (Statement, error) {
 	switch p.curToken.Type {
 	case TokenCreate:
 		return p.parseCreate()
@@ -60,12 +98,70 @@ func (p *Parser) ParseStatement() (Statement, error) {
 		return p.parseUpdate()
 	case TokenDelete:
 		return p.parseDelete()
+	case TokenDrop:
+		return p.parseDrop()
+	case TokenVerify:
+		return &VerifyStmt{}, nil
+	case TokenExplain:
+		return p.parseExplain()
+	case TokenShow:
+		return p.parseShow()
+	case TokenVacuum:
+		return p.parseVacuum()
+	case TokenEOF:
+		return nil, ErrEmptyStatement
+	default:
+		return nil, fmt.Errorf("unexpected %s", describe(p.curToken))
+	}
+}
+
+// EXPLAIN SELECT ... | UPDATE ... | DELETE ...
+func (p *Parser) parseExplain() (*ExplainStmt, error) {
+	p.nextToken() // EXPLAIN
+	switch p.curToken.Type {
+	case TokenSelect, TokenUpdate, TokenDelete:
 	default:
-		return nil, fmt.Errorf("unexpected token: %s", p.curToken.Literal)
+		return nil, fmt.Errorf("EXPLAIN supports SELECT, UPDATE and DELETE, got %s", describe(p.curToken))
+	}
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	return &ExplainStmt{Stmt: stmt}, nil
+}
+
+// SHOW STATUS | SHOW STATS table
+func (p *Parser) parseShow() (Statement, error) {
+	// STATUS and STATS are not keywords, so columns may still use the names
+	if p.peekTokenIs(TokenIdent) && strings.EqualFold(p.peekToken.Literal, "STATUS") {
+		p.nextToken()
+		return &ShowStatusStmt{}, nil
+	}
+	if !p.peekTokenIs(TokenIdent) || !strings.EqualFold(p.peekToken.Literal, "STATS") {
+		return nil, fmt.Errorf("expected STATUS or STATS after SHOW, got %s", describe(p.peekToken))
+	}
+	p.nextToken()
+	if !p.expectPeek(TokenIdent) {
+		return nil, p.lastError()
+	}
+	return &ShowStatsStmt{TableName: p.curToken.Literal}, nil
+}
+
+// VACUUM [table]
+func (p *Parser) parseVacuum() (*VacuumStmt, error) {
+	stmt := &VacuumStmt{}
+	if p.peekTokenIs(TokenIdent) {
+		p.nextToken()
+		stmt.TableName = p.curToken.Literal
+	}
+	// Anything else is an error rather than vacuuming every table
+	if !p.peekTokenIs(TokenEOF) {
+		return nil, fmt.Errorf("unexpected %s after VACUUM", describe(p.peekToken))
 	}
+	return stmt, nil
 }
 
-// CREATE TABLE name (col type [options], ...)
+// CREATE TABLE name (col type [PRIMARY KEY [AUTO_INCREMENT] | UNIQUE] [ON UPDATE CURRENT_TIMESTAMP] [CHECK (expr)] [COLLATE name], ..., [INDEX (col), ...])
 func (p *Parser) parseCreate() (*CreateTableStmt, error) {
 	if !p.expectPeek(TokenTable) {
 		return nil, fmt.Errorf(p.errors[len(p.errors)-1])
@@ -98,6 +194,45 @@ func (p *Parser) parseCreate() (*CreateTableStmt, error) {
 			break
 		}
 
+		// Table-level INDEX (col)
+		if p.curTokenIs(TokenIndex) {
+			if !p.expectPeek(TokenLParen) {
+				return nil, p.lastError()
+			}
+			if !p.expectPeek(TokenIdent) {
+				return nil, p.lastError()
+			}
+			stmt.Indexes = append(stmt.Indexes, p.curToken.Literal)
+			if !p.expectPeek(TokenRParen) {
+				return nil, p.lastError()
+			}
+			if p.peekTokenIs(TokenRParen) {
+				p.nextToken() // consume the table's closing paren
+				break
+			}
+			if !p.expectPeek(TokenComma) {
+				return nil, fmt.Errorf("expected , or ), got %s", describe(p.peekToken))
+			}
+			continue
+		}
+
+		// Table-level FOREIGN KEY (col) REFERENCES table(col)
+		if p.curTokenIs(TokenForeign) {
+			fk, err := p.parseForeignKey()
+			if err != nil {
+				return nil, err
+			}
+			stmt.ForeignKeys = append(stmt.ForeignKeys, fk)
+			if p.peekTokenIs(TokenRParen) {
+				p.nextToken() // consume the table's closing paren
+				break
+			}
+			if !p.expectPeek(TokenComma) {
+				return nil, fmt.Errorf("expected , or ), got %s", describe(p.peekToken))
+			}
+			continue
+		}
+
 		// Column Name
 		if p.curToken.Type != TokenIdent {
 			return nil, fmt.Errorf("expected column name")
@@ -112,12 +247,30 @@ func (p *Parser) parseCreate() (*CreateTableStmt, error) {
 			colType = types.TypeInt
 		case TokenTextType:
 			colType = types.TypeText
+		case TokenDate:
+			colType = types.TypeDate
 		default:
 			return nil, fmt.Errorf("invalid column type: %s", p.curToken.Literal)
 		}
 
 		col := schema.ColumnDef{Name: colName, Type: colType}
 
+		// Optional length bound: VARCHAR(n)
+		if strings.EqualFold(p.curToken.Literal, "VARCHAR") && p.peekTokenIs(TokenLParen) {
+			p.nextToken() // (
+			if !p.expectPeek(TokenNumber) {
+				return nil, p.lastError()
+			}
+			n, err := strconv.Atoi(p.curToken.Literal)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid VARCHAR length for %s: %s", colName, p.curToken.Literal)
+			}
+			if !p.expectPeek(TokenRParen) {
+				return nil, p.lastError()
+			}
+			col.MaxLength = n
+		}
+
 		// Options (PRIMARY KEY, UNIQUE)
 		if p.peekTokenIs(TokenPrimary) {
 			p.nextToken() // PRIMARY
@@ -130,19 +283,101 @@ func (p *Parser) parseCreate() (*CreateTableStmt, error) {
 			col.IsUnique = true
 		}
 
-		stmt.Columns = append(stmt.Columns, col)
+		// Optional AUTO_INCREMENT (INT primary keys only)
+		if p.peekTokenIs(TokenAutoIncrement) {
+			p.nextToken()
+			if !col.IsPrimary || colType != types.TypeInt {
+				return nil, fmt.Errorf("AUTO_INCREMENT requires an INT PRIMARY KEY: %s", colName)
+			}
+			col.AutoIncrement = true
+		}
 
-		if !p.peekTokenIs(TokenComma) && !p.peekTokenIs(TokenRParen) {
-			return nil, fmt.Errorf("expected comma or rparen, got %s", p.peekToken.Literal)
+		// Optional ON UPDATE CURRENT_TIMESTAMP
+		if p.peekTokenIs(TokenOn) {
+			p.nextToken() // ON
+			if !p.expectPeek(TokenUpdate) || !p.expectPeek(TokenCurrentTimestamp) {
+				return nil, fmt.Errorf("expected ON UPDATE CURRENT_TIMESTAMP on %s", colName)
+			}
+			if col.IsPrimary {
+				return nil, fmt.Errorf("ON UPDATE CURRENT_TIMESTAMP is not allowed on a primary key: %s", colName)
+			}
+			col.OnUpdateNow = true
 		}
-		if p.peekTokenIs(TokenComma) {
+
+		// Optional CHECK (expr), stored as SQL text and re-parsed when enforced
+		if p.peekTokenIs(TokenCheck) {
+			p.nextToken() // CHECK
+			if !p.expectPeek(TokenLParen) {
+				return nil, p.lastError()
+			}
 			p.nextToken()
+			expr, err := p.parseExpression(LOWEST)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CHECK on %s: %w", colName, err)
+			}
+			if !p.expectPeek(TokenRParen) {
+				return nil, p.lastError()
+			}
+			col.Check = expr.String()
+		}
+
+		// Optional COLLATE name (TEXT columns only)
+		if p.peekTokenIs(TokenCollate) {
+			p.nextToken() // COLLATE
+			if !p.expectPeek(TokenIdent) {
+				return nil, fmt.Errorf("expected collation name after COLLATE")
+			}
+			if colType != types.TypeText {
+				return nil, fmt.Errorf("COLLATE is only valid on TEXT columns: %s", colName)
+			}
+			coll, err := types.ParseCollation(p.curToken.Literal)
+			if err != nil {
+				return nil, err
+			}
+			col.Collation = coll
+		}
+
+		stmt.Columns = append(stmt.Columns, col)
+
+		if p.peekTokenIs(TokenRParen) {
+			p.nextToken() // consume the table's closing paren
+			break
+		}
+		if !p.expectPeek(TokenComma) {
+			return nil, fmt.Errorf("expected , or ), got %s", describe(p.peekToken))
 		}
 	}
 
+	// Optional table option: scans return rows in the order they were inserted
+	if p.peekTokenIs(TokenInsertionOrder) {
+		p.nextToken()
+		stmt.InsertionOrder = true
+	}
+
 	return stmt, nil
 }
 
+// FOREIGN KEY (col) REFERENCES table(col); curToken is FOREIGN.
+func (p *Parser) parseForeignKey() (schema.ForeignKeyDef, error) {
+	var fk schema.ForeignKeyDef
+	if !p.expectPeek(TokenKey) || !p.expectPeek(TokenLParen) || !p.expectPeek(TokenIdent) {
+		return fk, fmt.Errorf("expected FOREIGN KEY (column)")
+	}
+	fk.Column = p.curToken.Literal
+	if !p.expectPeek(TokenRParen) || !p.expectPeek(TokenReferences) || !p.expectPeek(TokenIdent) {
+		return fk, fmt.Errorf("expected REFERENCES table(column) after FOREIGN KEY (%s)", fk.Column)
+	}
+	fk.RefTable = p.curToken.Literal
+	if !p.expectPeek(TokenLParen) || !p.expectPeek(TokenIdent) {
+		return fk, fmt.Errorf("expected (column) after REFERENCES %s", fk.RefTable)
+	}
+	fk.RefColumn = p.curToken.Literal
+	if !p.expectPeek(TokenRParen) {
+		return fk, p.lastError()
+	}
+	return fk, nil
+}
+
 // INSERT INTO table VALUES (val, ...)
 func (p *Parser) parseInsert() (*InsertStmt, error) {
 	if !p.expectPeek(TokenInto) {
@@ -167,7 +402,7 @@ func (p *Parser) parseInsert() (*InsertStmt, error) {
 			break
 		}
 
-		val, err := p.parseValue()
+		val, err := p.parseScalar()
 		if err != nil {
 			return nil, err
 		}
@@ -177,31 +412,81 @@ func (p *Parser) parseInsert() (*InsertStmt, error) {
 			p.nextToken()
 		}
 	}
+
+	if p.peekTokenIs(TokenOn) {
+		p.nextToken()
+		conflict, err := p.parseConflict()
+		if err != nil {
+			return nil, err
+		}
+		stmt.OnConflict = conflict
+	}
 	return stmt, nil
 }
 
-// SELECT col1, col2 FROM table [JOIN table2 ON c1=c2] [WHERE col=val]
+// ON CONFLICT (col) DO UPDATE SET col = val [, ...]
+func (p *Parser) parseConflict() (*ConflictClause, error) {
+	if !p.expectPeek(TokenConflict) {
+		return nil, p.lastError()
+	}
+	if !p.expectPeek(TokenLParen) {
+		return nil, p.lastError()
+	}
+	if !p.expectPeek(TokenIdent) {
+		return nil, p.lastError()
+	}
+	clause := &ConflictClause{Column: p.curToken.Literal, Set: make(map[string]types.Value)}
+	if !p.expectPeek(TokenRParen) {
+		return nil, p.lastError()
+	}
+	if !p.expectPeek(TokenDo) {
+		return nil, p.lastError()
+	}
+	if !p.expectPeek(TokenUpdate) {
+		return nil, p.lastError()
+	}
+	if !p.expectPeek(TokenSet) {
+		return nil, p.lastError()
+	}
+	if err := p.parseAssignments(clause.Set); err != nil {
+		return nil, err
+	}
+	return clause, nil
+}
+
+// SELECT col1, col2 [FROM table[, table2] [TABLESAMPLE (n PERCENT)] [JOIN table2 ON c1=c2] [WHERE col=val]]
 func (p *Parser) parseSelect() (*SelectStmt, error) {
 	stmt := &SelectStmt{}
 	// Fields
 	p.nextToken() // skip SELECT
 	for {
 		if p.curTokenIs(TokenAsterisk) {
-			// * mean all fields, represented as nil or empty? Let's use empty slice implies all, or specific convention.
-			// But we need to support specific fields.
-			// Let's store "*" as a field name for now or handle in executor.
-			stmt.Fields = append(stmt.Fields, "*")
-		} else if p.curToken.Type == TokenIdent {
-			stmt.Fields = append(stmt.Fields, p.curToken.Literal)
-			// Handle table.column? Tokenizer splits `.`? No, tokenizer `readIdentifier` only alpha+digits+_.
-			// If we want table.col, we need `TokenDot`. For now requirement uses `users.name` which contains dot.
-			// My Tokenizer `readIdentifier` doesn't include dot.
-			// Oops. I need to fix Tokenizer or handle qualified names.
-			// "users.name" -> IDENT DOT IDENT.
-			// Or simpler: include dot in identifier for now.
+			stmt.Fields = append(stmt.Fields, &Star{})
+		} else if p.curTokenIs(TokenIdent) && strings.HasSuffix(p.curToken.Literal, ".") && p.peekTokenIs(TokenAsterisk) {
+			// users.* reads as the name "users." followed by *
+			stmt.Fields = append(stmt.Fields, &Star{Table: strings.TrimSuffix(p.curToken.Literal, ".")})
+			p.nextToken()
 		} else {
-			return nil, fmt.Errorf("expected field name, got %s", p.curToken.Literal)
+			field, err := p.parseScalar()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Fields = append(stmt.Fields, field)
+		}
+
+		// Optional AS name for the output column
+		alias := ""
+		if p.peekTokenIs(TokenAs) {
+			p.nextToken() // AS
+			if !p.expectPeek(TokenIdent) {
+				return nil, fmt.Errorf("expected column name after AS, got %s", describe(p.peekToken))
+			}
+			if _, ok := stmt.Fields[len(stmt.Fields)-1].(*Star); ok {
+				return nil, fmt.Errorf("* cannot be given a name with AS")
+			}
+			alias = p.curToken.Literal
 		}
+		stmt.Aliases = append(stmt.Aliases, alias)
 
 		if p.peekTokenIs(TokenComma) {
 			p.nextToken()
@@ -211,6 +496,16 @@ func (p *Parser) parseSelect() (*SelectStmt, error) {
 		}
 	}
 
+	// Without FROM the fields are computed once, e.g. SELECT 1 + 1
+	if p.peekTokenIs(TokenEOF) {
+		for _, f := range stmt.Fields {
+			if _, ok := f.(*Star); ok {
+				return nil, fmt.Errorf("SELECT * needs a FROM clause")
+			}
+		}
+		return stmt, nil
+	}
+
 	if !p.expectPeek(TokenFrom) {
 		return nil, p.lastError()
 	}
@@ -219,8 +514,48 @@ func (p *Parser) parseSelect() (*SelectStmt, error) {
 	}
 	stmt.TableName = p.curToken.Literal
 
+	// Implicit join: FROM a, b
+	if p.peekTokenIs(TokenComma) {
+		p.nextToken()
+		if !p.expectPeek(TokenIdent) {
+			return nil, p.lastError()
+		}
+		stmt.Join = &JoinClause{Table: p.curToken.Literal}
+		if p.peekTokenIs(TokenComma) {
+			return nil, fmt.Errorf("FROM supports at most two tables")
+		}
+	}
+
+	// TABLESAMPLE (n PERCENT)
+	if p.peekTokenIs(TokenTablesample) {
+		p.nextToken() // TABLESAMPLE
+		if !p.expectPeek(TokenLParen) {
+			return nil, p.lastError()
+		}
+		if !p.expectPeek(TokenNumber) {
+			return nil, p.lastError()
+		}
+		percent, err := strconv.Atoi(p.curToken.Literal)
+		if err != nil {
+			return nil, err
+		}
+		if percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("TABLESAMPLE percent must be between 0 and 100, got %d", percent)
+		}
+		if !p.expectPeek(TokenPercent) {
+			return nil, p.lastError()
+		}
+		if !p.expectPeek(TokenRParen) {
+			return nil, p.lastError()
+		}
+		stmt.Sample = &SampleClause{Percent: percent}
+	}
+
 	// JOIN
 	if p.peekTokenIs(TokenJoin) {
+		if stmt.Join != nil {
+			return nil, fmt.Errorf("cannot combine JOIN with a comma-separated FROM list")
+		}
 		p.nextToken() // JOIN
 		if !p.expectPeek(TokenIdent) {
 			return nil, p.lastError()
@@ -232,13 +567,17 @@ func (p *Parser) parseSelect() (*SelectStmt, error) {
 		}
 
 		// ON left = right
-		p.nextToken()
-		left := p.curToken.Literal // Assuming simple identifier, maybe qualified
+		if !p.expectPeek(TokenIdent) {
+			return nil, p.lastError()
+		}
+		left := p.columnRef()
 		if !p.expectPeek(TokenEqual) {
 			return nil, p.lastError()
 		}
-		p.nextToken()
-		right := p.curToken.Literal
+		if !p.expectPeek(TokenIdent) {
+			return nil, p.lastError()
+		}
+		right := p.columnRef()
 
 		stmt.Join = &JoinClause{
 			Table:   joinTable,
@@ -257,6 +596,34 @@ func (p *Parser) parseSelect() (*SelectStmt, error) {
 		stmt.Where = where
 	}
 
+	// GROUP BY col [, col ...]
+	if p.peekTokenIs(TokenGroup) {
+		p.nextToken()
+		if !p.expectPeek(TokenBy) {
+			return nil, p.lastError()
+		}
+		for {
+			if !p.expectPeek(TokenIdent) {
+				return nil, p.lastError()
+			}
+			stmt.GroupBy = append(stmt.GroupBy, p.columnRef())
+			if !p.peekTokenIs(TokenComma) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
+	// HAVING condition
+	if p.peekTokenIs(TokenHaving) {
+		p.nextToken()
+		having, err := p.parseWhere()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Having = having.Expr
+	}
+
 	// LIMIT
 	if p.peekTokenIs(TokenLimit) {
 		p.nextToken()
@@ -274,7 +641,7 @@ func (p *Parser) parseSelect() (*SelectStmt, error) {
 }
 
 func (p *Parser) parseUpdate() (*UpdateStmt, error) {
-	// UPDATE table SET col = val WHERE ...
+	// UPDATE table SET col = val [, ...] [WHERE ...]
 	if !p.expectPeek(TokenIdent) {
 		return nil, p.lastError()
 	}
@@ -284,30 +651,16 @@ func (p *Parser) parseUpdate() (*UpdateStmt, error) {
 		return nil, p.lastError()
 	}
 
-	// col = val. Only one for now? "UPDATE users SET name = 'Bob' WHERE id = 1"
-	p.nextToken() // SET
-	if p.curToken.Type != TokenIdent {
-		return nil, fmt.Errorf("expected col name")
-	}
-	col := p.curToken.Literal
-
-	if !p.expectPeek(TokenEqual) {
-		return nil, p.lastError()
-	}
-	p.nextToken()
-
-	val, err := p.parseValue()
-	if err != nil {
+	if err := p.parseAssignments(stmt.Set); err != nil {
 		return nil, err
 	}
 
-	stmt.Set[col] = val
-
-	// Check for comma for multiple sets? Requirements say "UPDATE users SET name = 'Bob'..." (singular).
-	// Let's stick to singular or loop.
-
+	// No WHERE updates every row; as with DELETE, other trailing tokens are an error.
+	if p.peekTokenIs(TokenEOF) {
+		return stmt, nil
+	}
 	if !p.expectPeek(TokenWhere) {
-		return nil, fmt.Errorf("UPDATE requires WHERE")
+		return nil, fmt.Errorf("unexpected %s after UPDATE SET", describe(p.peekToken))
 	}
 	where, err := p.parseWhere()
 	if err != nil {
@@ -318,6 +671,36 @@ func (p *Parser) parseUpdate() (*UpdateStmt, error) {
 	return stmt, nil
 }
 
+// parseAssignments reads col = val [, col = val ...] after SET into set.
+func (p *Parser) parseAssignments(set map[string]types.Value) error {
+	for {
+		p.nextToken() // SET or ,
+		if p.curToken.Type != TokenIdent {
+			return fmt.Errorf("expected col name")
+		}
+		col := p.curToken.Literal
+		if _, dup := set[col]; dup {
+			return fmt.Errorf("column %s assigned more than once", col)
+		}
+
+		if !p.expectPeek(TokenEqual) {
+			return p.lastError()
+		}
+		p.nextToken()
+
+		val, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		set[col] = val
+
+		if !p.peekTokenIs(TokenComma) {
+			return nil
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseDelete() (*DeleteStmt, error) {
 	if !p.expectPeek(TokenFrom) {
 		return nil, p.lastError()
@@ -327,8 +710,13 @@ func (p *Parser) parseDelete() (*DeleteStmt, error) {
 	}
 	stmt := &DeleteStmt{TableName: p.curToken.Literal}
 
+	// No WHERE deletes every row. Anything else after the table name is an
+	// error rather than a silent delete-all, so a typo like WHER cannot clear it.
+	if p.peekTokenIs(TokenEOF) {
+		return stmt, nil
+	}
 	if !p.expectPeek(TokenWhere) {
-		return nil, fmt.Errorf("DELETE requires WHERE")
+		return nil, fmt.Errorf("unexpected %s after DELETE FROM %s", describe(p.peekToken), stmt.TableName)
 	}
 	where, err := p.parseWhere()
 	if err != nil {
@@ -339,17 +727,24 @@ func (p *Parser) parseDelete() (*DeleteStmt, error) {
 	return stmt, nil
 }
 
+// DROP DATABASE
+func (p *Parser) parseDrop() (Statement, error) {
+	if !p.expectPeek(TokenDatabase) {
+		return nil, p.lastError()
+	}
+	return &DropDatabaseStmt{}, nil
+}
+
 const (
 	_ int = iota
 	LOWEST
-	SUM     // +
-	PRODUCT // * -- not supporting math yet but standard precedence
-	EQUALS  // =
-	ANDOR   // AND OR -- usually AND > OR, but for simplified we can group or use levels
+	OR      // OR
+	AND     // AND
+	SUM     // + -
+	PRODUCT // * /
 )
 
-// Precedence table?
-// For now, simple:
+// parseWhere parses the condition after WHERE.
 func (p *Parser) parseWhere() (*WhereClause, error) {
 	p.nextToken() // WHERE
 
@@ -361,62 +756,369 @@ func (p *Parser) parseWhere() (*WhereClause, error) {
 	return &WhereClause{Expr: expr}, nil
 }
 
-func (p *Parser) parseExpression(precedence int) (Expression, error) {
-	// Prefix ? We don't have prefix ops like - or ! yet.
-	// We expect Identifier (Column)
+// logicalPrecedence binds AND tighter than OR.
+var logicalPrecedence = map[TokenType]int{
+	TokenOr:  OR,
+	TokenAnd: AND,
+}
 
-	left, err := p.parseComparison()
+// parseExpression parses conditions joined by AND and OR that bind tighter
+// than precedence. Operators of equal precedence group to the left.
+func (p *Parser) parseExpression(precedence int) (Expression, error) {
+	left, err := p.parseCondition()
 	if err != nil {
 		return nil, err
 	}
+	for {
+		prec, ok := logicalPrecedence[p.peekToken.Type]
+		if !ok || prec <= precedence {
+			return left, nil
+		}
+		p.nextToken()
+		op := strings.ToUpper(p.curToken.Literal)
+		p.nextToken()
+		right, err := p.parseExpression(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &InfixExpression{Left: left, Operator: op, Right: right}
+	}
+}
 
-	// Infix
-	for p.peekTokenIs(TokenAnd) || p.peekTokenIs(TokenEqual) { // Equal is handled in comparison?
-		// Wait, "col = val" IS the comparison.
-		// "col = val AND col = val"
-		// parseComparison parses "col = val" fully.
-		// So we look for AND / OR.
+// parseCondition parses one comparison, a parenthesized condition, or NOT
+// applied to either. NOT binds tighter than AND and OR.
+func (p *Parser) parseCondition() (Expression, error) {
+	if p.curTokenIs(TokenNot) {
+		p.nextToken() // NOT
+		expr, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpression{Expr: expr}, nil
+	}
+	if !p.curTokenIs(TokenLParen) {
+		return p.parseComparison()
+	}
 
-		// If we see AND
-		if p.peekTokenIs(TokenAnd) {
-			p.nextToken()
-			op := p.curToken.Literal // AND
+	// "(" opens either a grouped condition, (a = 1 OR a = 2), or an
+	// arithmetic operand, (a + 1) > 3. Try the group first and parse again
+	// as a comparison if that fails.
+	start := p.mark()
+	p.nextToken() // (
+	expr, err := p.parseExpression(LOWEST)
+	if err == nil && !p.expectPeek(TokenRParen) {
+		err = fmt.Errorf("expected ) to close condition, got %s", describe(p.peekToken))
+	}
+	if err == nil {
+		return expr, nil
+	}
+	p.reset(start)
+	cmp, cmpErr := p.parseComparison()
+	if cmpErr != nil {
+		return nil, err
+	}
+	return cmp, nil
+}
 
-			// Recursively parse right
-			right, err := p.parseExpression(EQUALS) // Tightness?
-			if err != nil {
-				return nil, err
-			}
+// parserState is a position in the token stream to backtrack to.
+type parserState struct {
+	l         Tokenizer
+	curToken  Token
+	peekToken Token
+	errors    int
+	argPos    int
+}
 
-			left = &InfixExpression{Left: left, Operator: op, Right: right}
-			continue
+func (p *Parser) mark() parserState {
+	return parserState{l: *p.l, curToken: p.curToken, peekToken: p.peekToken, errors: len(p.errors), argPos: p.argPos}
+}
+
+func (p *Parser) reset(s parserState) {
+	*p.l = s.l
+	p.curToken, p.peekToken = s.curToken, s.peekToken
+	p.errors = p.errors[:s.errors]
+	p.argPos = s.argPos
+}
+
+func (p *Parser) parseComparison() (Expression, error) {
+	// Expect: IDENT = VALUE, or a scalar expression such as LENGTH(email) = 0
+	var cmp ComparisonExpression
+	var col string
+	if p.curTokenIs(TokenIdent) && !p.peekTokenIs(TokenLParen) && !isArithmeticOp(p.peekToken.Type) {
+		ref := p.columnRef()
+		cmp.Table, cmp.Column = ref.Table, ref.Name
+		col = ref.String()
+	} else {
+		if !p.curTokenIs(TokenIdent) && !p.curTokenIs(TokenLParen) && !p.curTokenIs(TokenCurrentTimestamp) {
+			return nil, fmt.Errorf("expected column name, got %s", describe(p.curToken))
+		}
+		left, err := p.parseScalar()
+		if err != nil {
+			return nil, err
 		}
-		break
+		cmp.Left = left
+		col = left.String()
 	}
 
-	return left, nil
+	if p.peekTokenIs(TokenBetween) {
+		return p.parseBetween(cmp, col)
+	}
+	if p.peekTokenIs(TokenIn) {
+		if cmp.Left != nil {
+			return nil, fmt.Errorf("IN needs a column on the left, got %s", col)
+		}
+		return p.parseIn(cmp.Table, cmp.Column)
+	}
+	if p.peekTokenIs(TokenLike) {
+		p.nextToken()
+		p.nextToken()
+		if !p.curTokenIs(TokenString) && !p.curTokenIs(TokenParam) {
+			return nil, fmt.Errorf("expected pattern string after LIKE, got %s", describe(p.curToken))
+		}
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if val.Type != types.TypeText || val.IsNull() {
+			return nil, fmt.Errorf("LIKE pattern for %s must be TEXT", col)
+		}
+		cmp.Operator, cmp.Value = "LIKE", val
+		if err := p.parseCollateSuffix(&cmp); err != nil {
+			return nil, err
+		}
+		return &cmp, nil
+	}
+	if !p.peekTokenIs(TokenEqual) && !p.peekTokenIs(TokenNotEqual) && !p.peekTokenIs(TokenLT) &&
+		!p.peekTokenIs(TokenGT) && !p.peekTokenIs(TokenLTE) && !p.peekTokenIs(TokenGTE) {
+		return nil, fmt.Errorf("expected comparison operator after %s, got %s", col, describe(p.peekToken))
+	}
+	p.nextToken()
+	// curToken is now the operator
+	op := p.curToken.Literal
+	if p.curTokenIs(TokenNotEqual) {
+		op = "!=" // <> is the same operator
+	}
+
+	cmp.Operator = op
+
+	// The right side is a column, a value, or a scalar expression such
+	// as 2 * 50; parseScalar takes all of it, so no operator is left over
+	p.nextToken()
+	if !p.curTokenIs(TokenIdent) && !p.curTokenIs(TokenLParen) && !p.curTokenIs(TokenCurrentTimestamp) && !isValueStart(p.curToken.Type) {
+		return nil, fmt.Errorf("expected a value, got %s", describe(p.curToken))
+	}
+	right, err := p.parseScalar()
+	if err != nil {
+		return nil, err
+	}
+	switch r := right.(type) {
+	case *ColumnRef:
+		cmp.Right = r
+	case *Literal:
+		cmp.Value = r.Value
+	default:
+		cmp.RightExpr = right
+	}
+	if err := p.parseCollateSuffix(&cmp); err != nil {
+		return nil, err
+	}
+	return &cmp, nil
 }
 
-func (p *Parser) parseComparison() (Expression, error) {
-	// Expect: IDENT = VALUE
-	if p.curToken.Type != TokenIdent {
-		return nil, fmt.Errorf("expected column name, got %s", p.curToken.Literal)
+// parseBetween parses "BETWEEN lo AND hi" after the left side in cmp and
+// returns the equivalent "left >= lo AND left <= hi".
+func (p *Parser) parseBetween(cmp ComparisonExpression, col string) (Expression, error) {
+	p.nextToken() // BETWEEN
+	p.nextToken()
+	lo, err := p.parseValue()
+	if err != nil {
+		return nil, err
 	}
-	col := p.curToken.Literal
+	if !p.expectPeek(TokenAnd) {
+		return nil, fmt.Errorf("expected AND in BETWEEN for %s, got %s", col, describe(p.peekToken))
+	}
+	p.nextToken()
+	hi, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	low, high := cmp, cmp
+	low.Operator, low.Value = ">=", lo
+	high.Operator, high.Value = "<=", hi
+	return &InfixExpression{Left: &low, Operator: "AND", Right: &high}, nil
+}
 
-	if !p.expectPeek(TokenEqual) {
+// parseIn parses "IN (SELECT ...)" after the column table.column.
+func (p *Parser) parseIn(table, column string) (Expression, error) {
+	p.nextToken() // IN
+	if !p.expectPeek(TokenLParen) {
+		return nil, p.lastError()
+	}
+	if !p.expectPeek(TokenSelect) {
+		return nil, fmt.Errorf("expected SELECT after IN (, got %s", describe(p.peekToken))
+	}
+	sub, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectPeek(TokenRParen) {
 		return nil, p.lastError()
 	}
-	// curToken is now =
-	op := "="
+	return &InExpression{Table: table, Column: column, Subquery: sub}, nil
+}
 
-	p.nextToken()
-	val, err := p.parseValue()
+// parseCollateSuffix reads an optional trailing COLLATE name on a comparison.
+func (p *Parser) parseCollateSuffix(cmp *ComparisonExpression) error {
+	if !p.peekTokenIs(TokenCollate) {
+		return nil
+	}
+	p.nextToken() // COLLATE
+	if !p.expectPeek(TokenIdent) {
+		return fmt.Errorf("expected collation name after COLLATE")
+	}
+	coll, err := types.ParseCollation(p.curToken.Literal)
+	if err != nil {
+		return err
+	}
+	cmp.Collation = coll
+	return nil
+}
+
+// parseScalar parses a value-producing expression: a column, a literal or
+// a function call such as GREATEST(amount, 100), combined with + - * /.
+func (p *Parser) parseScalar() (Expression, error) {
+	return p.parseArithmetic(LOWEST)
+}
+
+// arithmeticPrecedence binds * and / tighter than + and -.
+var arithmeticPrecedence = map[TokenType]int{
+	TokenPlus:     SUM,
+	TokenMinus:    SUM,
+	TokenAsterisk: PRODUCT,
+	TokenSlash:    PRODUCT,
+}
+
+func isArithmeticOp(t TokenType) bool {
+	_, ok := arithmeticPrecedence[t]
+	return ok
+}
+
+// parseArithmetic parses operands joined by operators that bind tighter than
+// precedence. Operators of equal precedence group to the left.
+func (p *Parser) parseArithmetic(precedence int) (Expression, error) {
+	left, err := p.parseOperand()
 	if err != nil {
 		return nil, err
 	}
+	for {
+		prec, ok := arithmeticPrecedence[p.peekToken.Type]
+		if !ok || prec <= precedence {
+			return left, nil
+		}
+		p.nextToken()
+		op := p.curToken.Literal
+		p.nextToken()
+		right, err := p.parseArithmetic(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &InfixExpression{Left: left, Operator: op, Right: right}
+	}
+}
+
+// parseOperand parses a single column, literal, function call or
+// parenthesized arithmetic expression.
+func (p *Parser) parseOperand() (Expression, error) {
+	switch p.curToken.Type {
+	case TokenIdent:
+		if p.peekTokenIs(TokenLParen) {
+			return p.parseFunctionCall()
+		}
+		return p.columnRef(), nil
+	case TokenCurrentTimestamp:
+		// CURRENT_TIMESTAMP, with or without (), is another name for NOW()
+		if p.peekTokenIs(TokenLParen) {
+			p.nextToken()
+			if !p.expectPeek(TokenRParen) {
+				return nil, fmt.Errorf("expected ) after CURRENT_TIMESTAMP(, got %s", describe(p.peekToken))
+			}
+		}
+		return &FunctionCall{Name: "NOW"}, nil
+	case TokenNumber, TokenMinus, TokenString, TokenDate, TokenNull, TokenParam: // see isValueStart
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &Literal{Value: val}, nil
+	case TokenLParen:
+		p.nextToken()
+		expr, err := p.parseArithmetic(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		if !p.expectPeek(TokenRParen) {
+			return nil, fmt.Errorf("expected ), got %s", describe(p.peekToken))
+		}
+		return expr, nil
+	default:
+		return nil, fmt.Errorf("expected field name, got %s", describe(p.curToken))
+	}
+}
+
+// isValueStart reports whether a token of type t can begin a literal value.
+func isValueStart(t TokenType) bool {
+	switch t {
+	case TokenNumber, TokenMinus, TokenString, TokenDate, TokenNull, TokenParam:
+		return true
+	}
+	return false
+}
 
-	return &ComparisonExpression{Column: col, Operator: op, Value: val}, nil
+// columnRef builds a reference from the current identifier, splitting a
+// "table.col" qualifier off the column name.
+func (p *Parser) columnRef() *ColumnRef {
+	ident := p.curToken.Literal
+	if dot := strings.Index(ident, "."); dot != -1 {
+		return &ColumnRef{Table: ident[:dot], Name: ident[dot+1:]}
+	}
+	return &ColumnRef{Name: ident}
+}
+
+// name(arg, ...); curToken is the name.
+func (p *Parser) parseFunctionCall() (*FunctionCall, error) {
+	fn := &FunctionCall{Name: strings.ToUpper(p.curToken.Literal)}
+	p.nextToken() // (
+	if p.peekTokenIs(TokenRParen) {
+		p.nextToken()
+		return fn, nil
+	}
+	// COUNT(DISTINCT col); the engine rejects DISTINCT on other calls
+	if p.peekTokenIs(TokenDistinct) {
+		p.nextToken()
+		fn.Distinct = true
+	}
+	for {
+		p.nextToken()
+		if p.curTokenIs(TokenAsterisk) {
+			// COUNT(*); other functions reject a * argument when planned
+			fn.Args = append(fn.Args, &Star{})
+		} else {
+			arg, err := p.parseScalar()
+			if err != nil {
+				return nil, err
+			}
+			fn.Args = append(fn.Args, arg)
+		}
+
+		if p.peekTokenIs(TokenComma) {
+			p.nextToken()
+			continue
+		}
+		if !p.expectPeek(TokenRParen) {
+			return nil, fmt.Errorf("expected , or ) in call to %s, got %s", fn.Name, describe(p.peekToken))
+		}
+		return fn, nil
+	}
 }
 
 func (p *Parser) parseValue() (types.Value, error) {
@@ -427,12 +1129,51 @@ func (p *Parser) parseValue() (types.Value, error) {
 		if err != nil {
 			return types.Value{}, err
 		}
-		return types.Value{Type: types.TypeInt, Val: i}, nil
+		return types.NewInt(i), nil
+	case TokenMinus:
+		// A value is expected here, so - can only be a sign, not subtraction
+		if !p.expectPeek(TokenNumber) {
+			return types.Value{}, fmt.Errorf("expected a number after -, got %s", describe(p.peekToken))
+		}
+		i, err := strconv.Atoi("-" + p.curToken.Literal)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return types.NewInt(i), nil
 	case TokenString:
-		return types.Value{Type: types.TypeText, Val: p.curToken.Literal}, nil
+		return types.NewText(p.curToken.Literal), nil
+	case TokenDate:
+		// DATE 'YYYY-MM-DD'
+		if !p.expectPeek(TokenString) {
+			return types.Value{}, fmt.Errorf("expected 'YYYY-MM-DD' after DATE, got %s", describe(p.peekToken))
+		}
+		return types.ParseDate(p.curToken.Literal)
+	case TokenNull:
+		// Untyped NULL; the column it lands in decides what it means
+		return types.NewNull(""), nil
+	case TokenParam:
+		if p.argPos >= len(p.args) {
+			return types.Value{}, fmt.Errorf("no argument bound for placeholder %d", p.argPos+1)
+		}
+		val := p.args[p.argPos]
+		p.argPos++
+		return val, nil
 	default:
-		return types.Value{}, fmt.Errorf("unexpected value type: %s", p.curToken.Literal)
+		return types.Value{}, fmt.Errorf("expected a value, got %s", describe(p.curToken))
+	}
+}
+
+// ParseExpression parses a standalone boolean expression such as a stored CHECK.
+func ParseExpression(src string) (Expression, error) {
+	p := NewParser(NewTokenizer(src))
+	expr, err := p.parseExpression(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekTokenIs(TokenEOF) {
+		return nil, fmt.Errorf("unexpected %s after expression", describe(p.peekToken))
 	}
+	return expr, nil
 }
 
 func (p *Parser) lastError() error {
@@ -0,0 +1,685 @@
+package parser
+
+import (
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/types"
+	"reflect"
+	"testing"
+)
+
+func parse(t *testing.T, sql string) Statement {
+	t.Helper()
+	stmt, err := NewParser(NewTokenizer(sql)).ParseStatement()
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	return stmt
+}
+
+func TestParseComparisonOperators(t *testing.T) {
+	for _, op := range []string{"=", "<", ">", "<=", ">="} {
+		stmt := parse(t, "SELECT * FROM nums WHERE id "+op+" 3").(*SelectStmt)
+		cmp, ok := stmt.Where.Expr.(*ComparisonExpression)
+		if !ok || cmp.Column != "id" || cmp.Operator != op || cmp.Value.Val != 3 {
+			t.Errorf("%s: unexpected condition %#v", op, stmt.Where.Expr)
+		}
+	}
+
+	// Both sides of an AND are parsed, not just the first
+	stmt := parse(t, "SELECT * FROM nums WHERE id > 1 AND id <= 5").(*SelectStmt)
+	and, ok := stmt.Where.Expr.(*InfixExpression)
+	if !ok || and.Operator != "AND" {
+		t.Fatalf("Expected an AND, got %#v", stmt.Where.Expr)
+	}
+	left, _ := and.Left.(*ComparisonExpression)
+	right, _ := and.Right.(*ComparisonExpression)
+	if left == nil || left.Operator != ">" || right == nil || right.Operator != "<=" || right.Value.Val != 5 {
+		t.Errorf("Unexpected AND operands: %#v, %#v", and.Left, and.Right)
+	}
+
+	if _, err := NewParser(NewTokenizer("SELECT * FROM nums WHERE id ! 3")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for an unknown operator")
+	}
+}
+
+func TestParseCreateWithIndex(t *testing.T) {
+	stmt := parse(t, "CREATE TABLE orders (id INT PRIMARY KEY, user_id INT, INDEX (user_id), amount INT)")
+	create, ok := stmt.(*CreateTableStmt)
+	if !ok {
+		t.Fatalf("Expected *CreateTableStmt, got %T", stmt)
+	}
+	if len(create.Columns) != 3 {
+		t.Errorf("Expected 3 columns, got %d", len(create.Columns))
+	}
+	if len(create.Indexes) != 1 || create.Indexes[0] != "user_id" {
+		t.Errorf("Expected index on user_id, got %v", create.Indexes)
+	}
+
+	if _, err := NewParser(NewTokenizer("CREATE TABLE t (id INT PRIMARY KEY, INDEX id)")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for INDEX without parentheses")
+	}
+}
+
+func TestParseDeleteWithoutWhere(t *testing.T) {
+	stmt := parse(t, "DELETE FROM orders")
+	del, ok := stmt.(*DeleteStmt)
+	if !ok {
+		t.Fatalf("Expected *DeleteStmt, got %T", stmt)
+	}
+	if del.TableName != "orders" || del.Where != nil {
+		t.Errorf("Expected unfiltered delete from orders, got %+v", del)
+	}
+
+	if _, err := NewParser(NewTokenizer("DELETE FROM orders WHER id = 1")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for trailing tokens instead of a delete-all")
+	}
+}
+
+func TestParseCreateTypeAliases(t *testing.T) {
+	stmt := parse(t, "CREATE TABLE t (id INTEGER PRIMARY KEY, a int, b text, c STRING, d varchar, e VARCHAR(20))")
+	create := stmt.(*CreateTableStmt)
+
+	want := []struct {
+		typ    types.DataType
+		maxLen int
+	}{
+		{types.TypeInt, 0},
+		{types.TypeInt, 0},
+		{types.TypeText, 0},
+		{types.TypeText, 0},
+		{types.TypeText, 0},
+		{types.TypeText, 20},
+	}
+	if len(create.Columns) != len(want) {
+		t.Fatalf("Expected %d columns, got %d", len(want), len(create.Columns))
+	}
+	for i, w := range want {
+		col := create.Columns[i]
+		if col.Type != w.typ || col.MaxLength != w.maxLen {
+			t.Errorf("Column %s: expected %s(%d), got %s(%d)", col.Name, w.typ, w.maxLen, col.Type, col.MaxLength)
+		}
+	}
+
+	for _, sql := range []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, name VARCHAR(0))",
+		"CREATE TABLE t (id INT PRIMARY KEY, name VARCHAR(x))",
+		"CREATE TABLE t (id INT PRIMARY KEY, name VARCHAR(10)",
+	} {
+		if _, err := NewParser(NewTokenizer(sql)).ParseStatement(); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func TestParseUpdateWithoutWhere(t *testing.T) {
+	stmt := parse(t, "UPDATE orders SET status = 'archived'")
+	upd, ok := stmt.(*UpdateStmt)
+	if !ok {
+		t.Fatalf("Expected *UpdateStmt, got %T", stmt)
+	}
+	if upd.Where != nil || upd.Set["status"].Val != "archived" {
+		t.Errorf("Expected unfiltered update of status, got %+v", upd)
+	}
+
+	if _, err := NewParser(NewTokenizer("UPDATE orders SET status = 'x' id = 1")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for trailing tokens instead of an update-all")
+	}
+}
+
+func TestParseSelectFunctionCall(t *testing.T) {
+	stmt := parse(t, "SELECT id, greatest(amount, 100, NULL), LEAST(a, GREATEST(b, 'x')) FROM orders")
+	sel := stmt.(*SelectStmt)
+	if len(sel.Fields) != 3 {
+		t.Fatalf("Expected 3 fields, got %d", len(sel.Fields))
+	}
+	if _, ok := sel.Fields[0].(*ColumnRef); !ok {
+		t.Errorf("Expected *ColumnRef, got %T", sel.Fields[0])
+	}
+	fn, ok := sel.Fields[1].(*FunctionCall)
+	if !ok {
+		t.Fatalf("Expected *FunctionCall, got %T", sel.Fields[1])
+	}
+	if fn.Name != "GREATEST" || len(fn.Args) != 3 {
+		t.Errorf("Expected GREATEST with 3 args, got %s with %d", fn.Name, len(fn.Args))
+	}
+	if got := sel.Fields[2].String(); got != "LEAST(a, GREATEST(b, 'x'))" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+
+	if _, err := NewParser(NewTokenizer("SELECT GREATEST(a, b FROM t")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for unterminated call")
+	}
+}
+
+func TestParseQualifiedNames(t *testing.T) {
+	stmt := parse(t, "SELECT users.name, id FROM orders JOIN users ON orders.user_id = users.id WHERE orders.amount > 5")
+	sel := stmt.(*SelectStmt)
+
+	name := sel.Fields[0].(*ColumnRef)
+	if name.Table != "users" || name.Name != "name" {
+		t.Errorf("Expected users/name, got %s/%s", name.Table, name.Name)
+	}
+	if id := sel.Fields[1].(*ColumnRef); id.Table != "" || id.Name != "id" {
+		t.Errorf("Expected bare id, got %s/%s", id.Table, id.Name)
+	}
+	if on := sel.Join.OnLeft; on.Table != "orders" || on.Name != "user_id" {
+		t.Errorf("Expected orders/user_id, got %s/%s", on.Table, on.Name)
+	}
+	comp := sel.Where.Expr.(*ComparisonExpression)
+	if comp.Table != "orders" || comp.Column != "amount" {
+		t.Errorf("Expected orders/amount, got %s/%s", comp.Table, comp.Column)
+	}
+	if got := comp.String(); got != "orders.amount > 5" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+}
+
+func TestParseUpdateMultipleAssignments(t *testing.T) {
+	upd := parse(t, "UPDATE users SET name = 'Ann', email = 'a@x.com' WHERE id = 1").(*UpdateStmt)
+	if len(upd.Set) != 2 || upd.Set["name"].Val != "Ann" || upd.Set["email"].Val != "a@x.com" {
+		t.Errorf("Unexpected assignments: %v", upd.Set)
+	}
+	if _, err := NewParser(NewTokenizer("UPDATE users SET name = 'a', name = 'b'")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for a column assigned twice")
+	}
+}
+
+func TestParseImplicitJoin(t *testing.T) {
+	sel := parse(t, "SELECT * FROM orders, users WHERE orders.user_id = users.id").(*SelectStmt)
+	if sel.TableName != "orders" || sel.Join == nil || sel.Join.Table != "users" || sel.Join.OnLeft != nil {
+		t.Fatalf("Expected implicit join of orders and users, got %+v", sel)
+	}
+	comp := sel.Where.Expr.(*ComparisonExpression)
+	if comp.Right == nil || comp.Right.Table != "users" || comp.Right.Name != "id" {
+		t.Errorf("Expected column comparison against users.id, got %+v", comp)
+	}
+	if got := comp.String(); got != "orders.user_id = users.id" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+
+	for _, sql := range []string{
+		"SELECT * FROM a, b, c",
+		"SELECT * FROM a, b JOIN c ON a.id = c.id",
+	} {
+		if _, err := NewParser(NewTokenizer(sql)).ParseStatement(); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func TestParseLike(t *testing.T) {
+	sel := parse(t, "SELECT * FROM users WHERE name LIKE 'Ja%'").(*SelectStmt)
+	comp := sel.Where.Expr.(*ComparisonExpression)
+	if comp.Operator != "LIKE" || comp.Value.Val != "Ja%" {
+		t.Fatalf("Expected name LIKE 'Ja%%', got %+v", comp)
+	}
+	if got := comp.String(); got != "name LIKE 'Ja%'" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+
+	for _, sql := range []string{
+		"SELECT * FROM users WHERE name LIKE 5",
+		"SELECT * FROM users WHERE name LIKE other",
+	} {
+		if _, err := NewParser(NewTokenizer(sql)).ParseStatement(); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func TestParseWhereFunction(t *testing.T) {
+	del := parse(t, "DELETE FROM users WHERE length(email) = 0 AND id > 1").(*DeleteStmt)
+	and := del.Where.Expr.(*InfixExpression)
+	comp := and.Left.(*ComparisonExpression)
+	if _, ok := comp.Left.(*FunctionCall); !ok || comp.Column != "" {
+		t.Fatalf("Expected a function call on the left, got %+v", comp)
+	}
+	if got := del.Where.Expr.String(); got != "LENGTH(email) = 0 AND id > 1" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+}
+
+func TestParseCollateComparison(t *testing.T) {
+	sel := parse(t, "SELECT * FROM users WHERE name = 'alice' collate nocase").(*SelectStmt)
+	comp := sel.Where.Expr.(*ComparisonExpression)
+	if comp.Collation != types.CollationNoCase {
+		t.Fatalf("Expected NOCASE, got %q", comp.Collation)
+	}
+	if got := comp.String(); got != "name = 'alice' COLLATE NOCASE" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+	if _, err := NewParser(NewTokenizer("SELECT * FROM users WHERE name = 'a' COLLATE bogus")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for an unknown collation")
+	}
+}
+
+func TestParseBetween(t *testing.T) {
+	sel := parse(t, "SELECT * FROM orders WHERE amount BETWEEN 10 AND 20 AND id > 1").(*SelectStmt)
+	if got := sel.Where.Expr.String(); got != "amount >= 10 AND amount <= 20 AND id > 1" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+	if _, err := NewParser(NewTokenizer("SELECT * FROM orders WHERE amount BETWEEN 10 OR 20")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for BETWEEN without AND")
+	}
+}
+
+func TestParseInSubquery(t *testing.T) {
+	sql := "SELECT * FROM orders WHERE user_id IN (SELECT id FROM users WHERE name LIKE 'A%' LIMIT 5) AND amount > 10"
+	sel := parse(t, sql).(*SelectStmt)
+	and := sel.Where.Expr.(*InfixExpression)
+	in, ok := and.Left.(*InExpression)
+	if !ok {
+		t.Fatalf("Expected InExpression, got %T", and.Left)
+	}
+	if in.Column != "user_id" || in.Subquery.TableName != "users" || in.Subquery.Limit != 5 {
+		t.Errorf("Unexpected IN: %+v", in)
+	}
+	if got := sel.Where.Expr.String(); got != "user_id IN (SELECT id FROM users WHERE name LIKE 'A%' LIMIT 5) AND amount > 10" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+
+	for _, bad := range []string{
+		"SELECT * FROM orders WHERE user_id IN (1, 2)",
+		"SELECT * FROM orders WHERE user_id IN (SELECT id FROM users",
+		"SELECT * FROM orders WHERE amount + 1 IN (SELECT id FROM users)",
+	} {
+		if _, err := NewParser(NewTokenizer(bad)).ParseStatement(); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestParseSelectWithoutFrom(t *testing.T) {
+	sel := parse(t, "SELECT 1 + 1, 'ok' AS status").(*SelectStmt)
+	if sel.TableName != "" || len(sel.Fields) != 2 || sel.Aliases[1] != "status" {
+		t.Errorf("Unexpected statement: %+v", sel)
+	}
+	if got := sel.String(); got != "SELECT 1 + 1, 'ok' AS status" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+	for _, sql := range []string{"SELECT *", "SELECT 1 LIMIT 1"} {
+		if _, err := NewParser(NewTokenizer(sql)).ParseStatement(); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func TestParseNotAndOr(t *testing.T) {
+	tests := []struct {
+		where string
+		want  string
+	}{
+		{"NOT (id = 1)", "NOT (id = 1)"},
+		{"NOT id = 1 AND b = 2", "NOT (id = 1) AND b = 2"},
+		{"a = 1 or b = 2 and c = 3", "a = 1 OR b = 2 AND c = 3"},
+		{"NOT (a = 1 OR b = 2) AND c = 3", "NOT (a = 1 OR b = 2) AND c = 3"},
+	}
+	for _, tt := range tests {
+		sel := parse(t, "SELECT * FROM t WHERE "+tt.where).(*SelectStmt)
+		if got := sel.Where.Expr.String(); got != tt.want {
+			t.Errorf("%s: rendered as %q, want %q", tt.where, got, tt.want)
+		}
+	}
+
+	// AND binds tighter than OR
+	sel := parse(t, "SELECT * FROM t WHERE a = 1 OR b = 2 AND c = 3").(*SelectStmt)
+	or := sel.Where.Expr.(*InfixExpression)
+	if or.Operator != "OR" {
+		t.Fatalf("Expected OR at the top, got %s", or.Operator)
+	}
+	if and, ok := or.Right.(*InfixExpression); !ok || and.Operator != "AND" {
+		t.Errorf("Expected AND under OR, got %s", or.Right)
+	}
+
+	for _, bad := range []string{
+		"SELECT * FROM t WHERE NOT",
+		"SELECT * FROM t WHERE NOT (a = 1",
+		"SELECT * FROM t WHERE a = 1 OR",
+	} {
+		if _, err := NewParser(NewTokenizer(bad)).ParseStatement(); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestParseGroupedConditions(t *testing.T) {
+	for _, where := range []string{
+		"(a = 1 OR b = 2) AND c = 3",
+		"a = 1 AND (b = 2 OR c = 3)",
+		"NOT (a = 1 OR b = 2) AND (c = 3 OR d = 4)",
+	} {
+		sel := parse(t, "SELECT * FROM t WHERE "+where).(*SelectStmt)
+		if got := sel.Where.Expr.String(); got != where {
+			t.Errorf("%s: rendered as %q", where, got)
+		}
+		// The rendering parses back to the same tree
+		again := parse(t, "SELECT * FROM t WHERE "+sel.Where.Expr.String()).(*SelectStmt)
+		if !reflect.DeepEqual(again.Where, sel.Where) {
+			t.Errorf("%s: round trip changed the tree", where)
+		}
+	}
+
+	// Placeholders consumed by an abandoned group attempt are handed out again
+	p := NewParser(NewTokenizer("SELECT * FROM t WHERE (a + ?) * 2 = ? OR b = ?"))
+	p.Bind(types.NewInt(1), types.NewInt(2), types.NewInt(3))
+	stmt, err := p.ParseStatement()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if got := stmt.(*SelectStmt).Where.Expr.String(); got != "(a + 1) * 2 = 2 OR b = 3" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+}
+
+func TestParseCountDistinct(t *testing.T) {
+	sel := parse(t, "SELECT count(distinct user_id) FROM orders").(*SelectStmt)
+	fn := sel.Fields[0].(*FunctionCall)
+	if !fn.Distinct || fn.Name != "COUNT" || len(fn.Args) != 1 {
+		t.Fatalf("Expected COUNT(DISTINCT user_id), got %+v", fn)
+	}
+	if got := fn.String(); got != "COUNT(DISTINCT user_id)" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+}
+
+func TestParseQualifiedStar(t *testing.T) {
+	sel := parse(t, "SELECT orders.*, users.name FROM orders JOIN users ON orders.user_id = users.id").(*SelectStmt)
+	star, ok := sel.Fields[0].(*Star)
+	if !ok || star.Table != "orders" {
+		t.Fatalf("Expected orders.*, got %#v", sel.Fields[0])
+	}
+	if got := star.String(); got != "orders.*" {
+		t.Errorf("Unexpected rendering: %s", got)
+	}
+	if _, ok := sel.Fields[1].(*ColumnRef); !ok {
+		t.Errorf("Expected a column after orders.*, got %#v", sel.Fields[1])
+	}
+}
+
+func TestParseVacuum(t *testing.T) {
+	if stmt := parse(t, "VACUUM").(*VacuumStmt); stmt.TableName != "" {
+		t.Errorf("Expected no table name, got %q", stmt.TableName)
+	}
+	if stmt := parse(t, "vacuum users").(*VacuumStmt); stmt.TableName != "users" {
+		t.Errorf("Expected table users, got %q", stmt.TableName)
+	}
+	if _, err := NewParser(NewTokenizer("VACUUM users WHERE id = 1")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for trailing tokens")
+	}
+}
+
+func TestParseNegativeNumbers(t *testing.T) {
+	ins := parse(t, "INSERT INTO t VALUES (-5, - 7, 'x')").(*InsertStmt)
+	if ins.Values[0].(*Literal).Value.Val != -5 || ins.Values[1].(*Literal).Value.Val != -7 {
+		t.Errorf("Expected -5 and -7, got %v", ins.Values)
+	}
+
+	cmp := parse(t, "SELECT * FROM t WHERE balance = -100").(*SelectStmt).Where.Expr.(*ComparisonExpression)
+	if cmp.Value.Val != -100 {
+		t.Errorf("Expected -100, got %v", cmp.Value)
+	}
+
+	// After an operand, - is subtraction; after an operator, a sign
+	sel := parse(t, "SELECT a-1, 3 - -2, -2 * a FROM t").(*SelectStmt)
+	for i, want := range []string{"a - 1", "3 - -2", "-2 * a"} {
+		if got := sel.Fields[i].String(); got != want {
+			t.Errorf("Field %d: expected %s, got %s", i, want, got)
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT * FROM t WHERE a = -b",
+		"SELECT * FROM t LIMIT -1",
+		"INSERT INTO t VALUES (-'x')",
+	} {
+		if _, err := NewParser(NewTokenizer(sql)).ParseStatement(); err == nil {
+			t.Errorf("%s: expected error", sql)
+		}
+	}
+}
+
+func TestParseArithmetic(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT a + b * 2 FROM t", "a + b * 2"},
+		{"SELECT (a + b) * 2 FROM t", "(a + b) * 2"},
+		{"SELECT a - b - c FROM t", "a - b - c"},
+		{"SELECT a - (b - c) FROM t", "a - (b - c)"},
+		{"SELECT SUM(amount * 1000000) FROM t", "SUM(amount * 1000000)"},
+	}
+	for _, tt := range tests {
+		sel := parse(t, tt.sql).(*SelectStmt)
+		if got := sel.Fields[0].String(); got != tt.want {
+			t.Errorf("%s: rendered as %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+
+	sel := parse(t, "SELECT a + b * 2 FROM t").(*SelectStmt)
+	top := sel.Fields[0].(*InfixExpression)
+	if top.Operator != "+" {
+		t.Fatalf("Expected + at the root, got %s", top.Operator)
+	}
+	if mul, ok := top.Right.(*InfixExpression); !ok || mul.Operator != "*" {
+		t.Errorf("Expected b * 2 to bind tighter, got %v", top.Right)
+	}
+
+	if _, err := NewParser(NewTokenizer("SELECT (a + b FROM t")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for an unclosed parenthesis")
+	}
+}
+
+func TestParseComparisonRightArithmetic(t *testing.T) {
+	sel := parse(t, "SELECT * FROM t WHERE n > 2 * 50").(*SelectStmt)
+	cmp := sel.Where.Expr.(*ComparisonExpression)
+	if cmp.RightExpr == nil || cmp.RightExpr.String() != "2 * 50" {
+		t.Fatalf("Expected 2 * 50 on the right, got %v", cmp)
+	}
+	if got := cmp.String(); got != "n > 2 * 50" {
+		t.Errorf("rendered as %q, want %q", got, "n > 2 * 50")
+	}
+
+	sel = parse(t, "SELECT * FROM t WHERE n = m + 1 AND k = 3").(*SelectStmt)
+	and := sel.Where.Expr.(*InfixExpression)
+	if left := and.Left.(*ComparisonExpression); left.RightExpr == nil || left.RightExpr.String() != "m + 1" {
+		t.Errorf("Expected m + 1 on the right, got %v", left)
+	}
+	if right := and.Right.(*ComparisonExpression); right.RightExpr != nil || right.Value.Val != 3 {
+		t.Errorf("Expected the literal 3 on the right, got %v", right)
+	}
+
+	if _, err := NewParser(NewTokenizer("SELECT * FROM t WHERE n > 2 *")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for a dangling operator")
+	}
+}
+
+func TestParseCreateInsertionOrder(t *testing.T) {
+	stmt := parse(t, "CREATE TABLE logs (id INT PRIMARY KEY, msg TEXT) INSERTION_ORDER").(*CreateTableStmt)
+	if !stmt.InsertionOrder {
+		t.Errorf("Expected INSERTION_ORDER to be set")
+	}
+	stmt = parse(t, "CREATE TABLE logs (id INT PRIMARY KEY, msg TEXT)").(*CreateTableStmt)
+	if stmt.InsertionOrder {
+		t.Errorf("Expected INSERTION_ORDER to default to off")
+	}
+}
+
+func TestTableDefDDLRoundTrip(t *testing.T) {
+	def := schema.TableDef{
+		Name: "orders",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true, AutoIncrement: true},
+			{Name: "code", Type: types.TypeText, IsUnique: true, MaxLength: 12, Collation: types.CollationNoCase},
+			{Name: "user_id", Type: types.TypeInt},
+			{Name: "amount", Type: types.TypeInt, Check: "amount > 0 AND amount <= 1000"},
+			{Name: "updated_at", Type: types.TypeText, OnUpdateNow: true},
+		},
+		ForeignKeys:    []schema.ForeignKeyDef{{Column: "user_id", RefTable: "users", RefColumn: "id"}},
+		Indexes:        []string{"user_id"},
+		InsertionOrder: true,
+	}
+
+	ddl := def.DDL()
+	stmt, ok := parse(t, ddl).(*CreateTableStmt)
+	if !ok {
+		t.Fatalf("Expected CREATE TABLE from %s", ddl)
+	}
+	got := schema.TableDef{
+		Name:           stmt.TableName,
+		Columns:        stmt.Columns,
+		ForeignKeys:    stmt.ForeignKeys,
+		Indexes:        stmt.Indexes,
+		InsertionOrder: stmt.InsertionOrder,
+	}
+	if !reflect.DeepEqual(got, def) {
+		t.Errorf("Round trip mismatch for %s\n got: %+v\nwant: %+v", ddl, got, def)
+	}
+}
+
+func TestQuotedNamesRoundTrip(t *testing.T) {
+	def := schema.TableDef{
+		Name: "select",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "limit", Type: types.TypeInt, Check: `"limit" > 0 AND "my ""col""" < "limit"`},
+			{Name: `my "col"`, Type: types.TypeInt},
+			{Name: "key", Type: types.TypeInt},
+		},
+		ForeignKeys: []schema.ForeignKeyDef{{Column: "key", RefTable: "order", RefColumn: "from"}},
+		Indexes:     []string{"limit"},
+	}
+
+	ddl := def.DDL()
+	stmt, ok := parse(t, ddl).(*CreateTableStmt)
+	if !ok {
+		t.Fatalf("Expected CREATE TABLE from %s", ddl)
+	}
+	got := schema.TableDef{
+		Name:        stmt.TableName,
+		Columns:     stmt.Columns,
+		ForeignKeys: stmt.ForeignKeys,
+		Indexes:     stmt.Indexes,
+	}
+	if !reflect.DeepEqual(got, def) {
+		t.Errorf("Round trip mismatch for %s\n got: %+v\nwant: %+v", ddl, got, def)
+	}
+
+	// Expressions quote keyword and unusual names so they parse back
+	for _, sql := range []string{
+		`SELECT "limit", "select"."key" AS "from", SUM("my col") FROM "select" WHERE "limit" > 0`,
+		`SELECT * FROM t WHERE NOT ("order" = 'x') AND n IN (SELECT "limit" FROM "group")`,
+	} {
+		sel := parse(t, sql).(*SelectStmt)
+		again := parse(t, sel.String()).(*SelectStmt)
+		if sel.String() != again.String() {
+			t.Errorf("%s: rendered as %s, which renders as %s", sql, sel.String(), again.String())
+		}
+	}
+}
+
+func TestParseGroupByHaving(t *testing.T) {
+	sel := parse(t, "SELECT user_id, SUM(amount) FROM orders WHERE amount > 0 GROUP BY user_id HAVING SUM(amount) > 100 LIMIT 5").(*SelectStmt)
+	if len(sel.GroupBy) != 1 || sel.GroupBy[0].Name != "user_id" {
+		t.Fatalf("Expected GROUP BY user_id, got %v", sel.GroupBy)
+	}
+	cmp, ok := sel.Having.(*ComparisonExpression)
+	if !ok || cmp.Left == nil || cmp.Left.String() != "SUM(amount)" {
+		t.Fatalf("Expected HAVING on SUM(amount), got %#v", sel.Having)
+	}
+	if sel.Limit != 5 {
+		t.Errorf("Expected LIMIT 5 after HAVING, got %d", sel.Limit)
+	}
+	want := "SELECT user_id, SUM(amount) FROM orders WHERE amount > 0 GROUP BY user_id HAVING SUM(amount) > 100 LIMIT 5"
+	if got := sel.String(); got != want {
+		t.Errorf("Unexpected rendering:\n got %s\nwant %s", got, want)
+	}
+	if _, err := NewParser(NewTokenizer("SELECT a FROM t GROUP a")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for GROUP without BY")
+	}
+}
+
+func TestParseShowStats(t *testing.T) {
+	if stmt := parse(t, "show stats orders").(*ShowStatsStmt); stmt.TableName != "orders" {
+		t.Errorf("Expected table orders, got %q", stmt.TableName)
+	}
+	if _, ok := parse(t, "SHOW STATUS").(*ShowStatusStmt); !ok {
+		t.Errorf("Expected SHOW STATUS to still parse")
+	}
+	if _, err := NewParser(NewTokenizer("SHOW STATS")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for SHOW STATS without a table")
+	}
+}
+
+func TestParseInsertOnConflict(t *testing.T) {
+	stmt := parse(t, "INSERT INTO users VALUES (1, 'ann', 30) ON CONFLICT (id) DO UPDATE SET name = 'ann', age = 31").(*InsertStmt)
+	if len(stmt.Values) != 3 {
+		t.Fatalf("Expected 3 values, got %d", len(stmt.Values))
+	}
+	c := stmt.OnConflict
+	if c == nil || c.Column != "id" {
+		t.Fatalf("Expected conflict on id, got %+v", c)
+	}
+	if len(c.Set) != 2 || c.Set["age"].Val != 31 || c.Set["name"].Val != "ann" {
+		t.Errorf("Unexpected SET: %v", c.Set)
+	}
+
+	if parse(t, "INSERT INTO users VALUES (1)").(*InsertStmt).OnConflict != nil {
+		t.Errorf("Expected no conflict clause on a plain INSERT")
+	}
+	for _, sql := range []string{
+		"INSERT INTO users VALUES (1) ON CONFLICT DO UPDATE SET age = 1",
+		"INSERT INTO users VALUES (1) ON CONFLICT (id) UPDATE SET age = 1",
+		"INSERT INTO users VALUES (1) ON CONFLICT (id) DO UPDATE SET age = 1, age = 2",
+	} {
+		if _, err := NewParser(NewTokenizer(sql)).ParseStatement(); err == nil {
+			t.Errorf("Expected error for %q", sql)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	create := parse(t, "CREATE TABLE orders (id INT PRIMARY KEY, created_at DATE)").(*CreateTableStmt)
+	if create.Columns[1].Type != types.TypeDate {
+		t.Errorf("Expected DATE column, got %s", create.Columns[1].Type)
+	}
+
+	insert := parse(t, "INSERT INTO orders VALUES (1, DATE '2024-01-02')").(*InsertStmt)
+	if v := insert.Values[1].(*Literal).Value; v.Type != types.TypeDate || v.String() != "2024-01-02" {
+		t.Errorf("Expected DATE '2024-01-02', got %#v", v)
+	}
+
+	sel := parse(t, "SELECT id FROM orders WHERE created_at >= DATE '2024-01-01' AND created_at < DATE '2024-02-01'").(*SelectStmt)
+	want := "SELECT id FROM orders WHERE created_at >= DATE '2024-01-01' AND created_at < DATE '2024-02-01'"
+	if got := sel.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	for _, sql := range []string{
+		"INSERT INTO orders VALUES (1, DATE '2024-13-01')",
+		"INSERT INTO orders VALUES (1, DATE 20240101)",
+	} {
+		if _, err := NewParser(NewTokenizer(sql)).ParseStatement(); err == nil {
+			t.Errorf("Expected error for %q", sql)
+		}
+	}
+}
+
+func TestParseNow(t *testing.T) {
+	stmt := parse(t, "INSERT INTO orders VALUES (1, NOW(), 'x', CURRENT_TIMESTAMP, current_timestamp())").(*InsertStmt)
+	for _, i := range []int{1, 3, 4} {
+		if got := stmt.Values[i].String(); got != "NOW()" {
+			t.Errorf("Value %d: expected NOW(), got %s", i, got)
+		}
+	}
+
+	// CURRENT_TIMESTAMP is an expression anywhere NOW() is
+	sel := parse(t, "SELECT CURRENT_TIMESTAMP, NOW() FROM orders WHERE created < CURRENT_TIMESTAMP AND NOW() > created").(*SelectStmt)
+	want := "SELECT NOW(), NOW() FROM orders WHERE created < NOW() AND NOW() > created"
+	if got := sel.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if _, err := NewParser(NewTokenizer("SELECT CURRENT_TIMESTAMP(1)")).ParseStatement(); err == nil {
+		t.Errorf("Expected error for CURRENT_TIMESTAMP(1)")
+	}
+}
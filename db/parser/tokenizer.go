@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"mini-rdbms/db/schema"
 	"strings"
 	"unicode"
 )
@@ -49,15 +50,56 @@ const (
 	TokenIf
 	TokenNot
 	TokenExists
+	TokenCollate
+	TokenTablesample
+	TokenPercent
+	TokenAutoIncrement
+	TokenNull
+	TokenIndex
+	TokenCheck
+	TokenLT  // <
+	TokenGT  // >
+	TokenLTE // <=
+	TokenGTE // >=
+	TokenDrop
+	TokenDatabase
+	TokenParam // ? placeholder
+	TokenCurrentTimestamp
+	TokenLike
+	TokenPlus  // +
+	TokenMinus // -
+	TokenSlash // /
+	TokenInsertionOrder
+	TokenForeign
+	TokenReferences
+	TokenVerify
+	TokenAs
+	TokenBetween
+	TokenExplain
+	TokenIn
+	TokenOr
+	TokenShow
+	TokenDistinct
+	TokenVacuum
+	TokenGroup
+	TokenBy
+	TokenHaving
+	TokenNotEqual // != or <>
+	TokenConflict
+	TokenDo
+	TokenDate
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	// Line and Column locate the token's first character, both from 1.
+	// Columns count characters, not bytes.
+	Line, Column int
 }
 
 func (t Token) String() string {
-	return fmt.Sprintf("Token(%d, %q)", t.Type, t.Literal)
+	return fmt.Sprintf("Token(%s, %q)", t.Type, t.Literal)
 }
 
 // Tokenizer scans a SQL string.
@@ -66,33 +108,129 @@ type Tokenizer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line, column int // Of ch
 }
 
 func NewTokenizer(input string) *Tokenizer {
-	t := &Tokenizer{input: input}
+	t := &Tokenizer{input: input, line: 1}
 	t.readChar()
 	return t
 }
 
 func (t *Tokenizer) readChar() {
+	if t.readPosition > len(t.input) {
+		return // already at the end
+	}
+	if t.ch == '\n' {
+		t.line++
+		t.column = 0
+	}
 	if t.readPosition >= len(t.input) {
 		t.ch = 0
 	} else {
 		t.ch = t.input[t.readPosition]
 	}
+	if t.ch&0xC0 != 0x80 { // not inside a UTF-8 sequence
+		t.column++
+	}
 	t.position = t.readPosition
 	t.readPosition++
 }
 
+// String names a token type the way it is written in SQL: the keyword
+// for keywords, the symbol for punctuation.
+func (t TokenType) String() string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("token %d", int(t))
+}
+
+// tokenNames holds the names of the token types that are not keywords;
+// keyword names are added from keywords in init.
+var tokenNames = map[TokenType]string{
+	TokenIllegal:  "illegal character",
+	TokenEOF:      "end of input",
+	TokenWS:       "whitespace",
+	TokenIdent:    "identifier",
+	TokenString:   "string",
+	TokenNumber:   "number",
+	TokenAsterisk: "*",
+	TokenComma:    ",",
+	TokenLParen:   "(",
+	TokenRParen:   ")",
+	TokenEqual:    "=",
+	TokenLT:       "<",
+	TokenGT:       ">",
+	TokenLTE:      "<=",
+	TokenGTE:      ">=",
+	TokenNotEqual: "!=",
+	TokenParam:    "?",
+	TokenPlus:     "+",
+	TokenMinus:    "-",
+	TokenSlash:    "/",
+}
+
+func init() {
+	schema.IsKeyword = func(name string) bool { return LookupIdent(name) != TokenIdent }
+
+	// Types with several spellings (INT, INTEGER) go by the shortest
+	for word, tok := range keywords {
+		name, ok := tokenNames[tok]
+		if !ok || len(word) < len(name) || len(word) == len(name) && word < name {
+			tokenNames[tok] = word
+		}
+	}
+}
+
 func (t *Tokenizer) skipWhitespace() {
 	for unicode.IsSpace(rune(t.ch)) {
 		t.readChar()
 	}
 }
 
+func (t *Tokenizer) peekChar() byte {
+	if t.readPosition >= len(t.input) {
+		return 0
+	}
+	return t.input[t.readPosition]
+}
+
+// skipWhitespaceAndComments skips spaces, `-- line` comments and `/* block */` comments.
+func (t *Tokenizer) skipWhitespaceAndComments() {
+	for {
+		t.skipWhitespace()
+		switch {
+		case t.ch == '-' && t.peekChar() == '-':
+			for t.ch != '\n' && t.ch != 0 {
+				t.readChar()
+			}
+		case t.ch == '/' && t.peekChar() == '*':
+			t.readChar()
+			t.readChar()
+			for !(t.ch == '*' && t.peekChar() == '/') && t.ch != 0 {
+				t.readChar()
+			}
+			if t.ch != 0 {
+				t.readChar() // *
+				t.readChar() // /
+			}
+		default:
+			return
+		}
+	}
+}
+
+// NextToken returns the next token, with its position.
 func (t *Tokenizer) NextToken() Token {
-	t.skipWhitespace()
+	t.skipWhitespaceAndComments()
+	line, column := t.line, t.column
+	tok := t.readToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
 
+func (t *Tokenizer) readToken() Token {
 	var tok Token
 
 	switch t.ch {
@@ -109,6 +247,42 @@ func (t *Tokenizer) NextToken() Token {
 		tok = newToken(TokenRParen, t.ch)
 	case '=':
 		tok = newToken(TokenEqual, t.ch)
+	case '?':
+		tok = newToken(TokenParam, t.ch)
+	case '+':
+		tok = newToken(TokenPlus, t.ch)
+	case '-':
+		tok = newToken(TokenMinus, t.ch)
+	case '/':
+		tok = newToken(TokenSlash, t.ch)
+	case '<':
+		if t.peekChar() == '=' {
+			t.readChar()
+			tok = Token{Type: TokenLTE, Literal: "<="}
+		} else if t.peekChar() == '>' {
+			t.readChar()
+			tok = Token{Type: TokenNotEqual, Literal: "<>"}
+		} else {
+			tok = newToken(TokenLT, t.ch)
+		}
+	case '>':
+		if t.peekChar() == '=' {
+			t.readChar()
+			tok = Token{Type: TokenGTE, Literal: ">="}
+		} else {
+			tok = newToken(TokenGT, t.ch)
+		}
+	case '!':
+		if t.peekChar() == '=' {
+			t.readChar()
+			tok = Token{Type: TokenNotEqual, Literal: "!="}
+		} else {
+			tok = newToken(TokenIllegal, t.ch)
+		}
+	case '"', '`':
+		// Quoted identifier; never a keyword
+		name, _ := t.readName()
+		return Token{Type: TokenIdent, Literal: name}
 	case '\'':
 		// String literal
 		tok.Type = TokenString
@@ -116,8 +290,11 @@ func (t *Tokenizer) NextToken() Token {
 		return tok // readString advances past quotes
 	default:
 		if isLetter(t.ch) {
-			tok.Literal = t.readIdentifier()
-			tok.Type = LookupIdent(tok.Literal)
+			name, quoted := t.readName()
+			tok.Literal, tok.Type = name, TokenIdent
+			if !quoted {
+				tok.Type = LookupIdent(name)
+			}
 			return tok
 		} else if isDigit(t.ch) {
 			tok.Type = TokenNumber
@@ -160,6 +337,49 @@ func (t *Tokenizer) readIdentifier() string {
 	return t.input[position:t.position]
 }
 
+// readName reads an identifier made of bare and quoted parts, such as
+// users."order", and returns it with the quotes removed. quoted reports
+// whether any part was quoted, in which case the name is never a keyword.
+func (t *Tokenizer) readName() (name string, quoted bool) {
+	var b strings.Builder
+	for {
+		if t.ch == '"' || t.ch == '`' {
+			b.WriteString(t.readQuoted(t.ch))
+			quoted = true
+			if t.ch != '.' {
+				return b.String(), quoted
+			}
+			continue
+		}
+		part := t.readIdentifier()
+		b.WriteString(part)
+		if !strings.HasSuffix(part, ".") || (t.ch != '"' && t.ch != '`') {
+			return b.String(), quoted
+		}
+	}
+}
+
+// readQuoted reads an identifier quoted with q. A doubled q inside it
+// stands for one q character.
+func (t *Tokenizer) readQuoted(q byte) string {
+	t.readChar() // opening quote
+	var b strings.Builder
+	for t.ch != 0 {
+		if t.ch == q {
+			if t.peekChar() != q {
+				break
+			}
+			t.readChar()
+		}
+		b.WriteByte(t.ch)
+		t.readChar()
+	}
+	if t.ch == q {
+		t.readChar() // closing quote
+	}
+	return b.String()
+}
+
 func (t *Tokenizer) readNumber() string {
 	position := t.position
 	for isDigit(t.ch) {
@@ -177,29 +397,61 @@ func isDigit(ch byte) bool {
 }
 
 var keywords = map[string]TokenType{
-	"SELECT":  TokenSelect,
-	"FROM":    TokenFrom,
-	"WHERE":   TokenWhere,
-	"INSERT":  TokenInsert,
-	"INTO":    TokenInto,
-	"VALUES":  TokenValues,
-	"UPDATE":  TokenUpdate,
-	"SET":     TokenSet,
-	"DELETE":  TokenDelete,
-	"CREATE":  TokenCreate,
-	"TABLE":   TokenTable,
-	"PRIMARY": TokenPrimary,
-	"KEY":     TokenKey,
-	"UNIQUE":  TokenUnique,
-	"JOIN":    TokenJoin,
-	"ON":      TokenOn,
-	"INT":     TokenIntType,
-	"TEXT":    TokenTextType,
-	"AND":     TokenAnd,
-	"LIMIT":   TokenLimit,
-	"IF":      TokenIf,
-	"NOT":     TokenNot,
-	"EXISTS":  TokenExists,
+	"SELECT":            TokenSelect,
+	"FROM":              TokenFrom,
+	"WHERE":             TokenWhere,
+	"INSERT":            TokenInsert,
+	"INTO":              TokenInto,
+	"VALUES":            TokenValues,
+	"UPDATE":            TokenUpdate,
+	"SET":               TokenSet,
+	"DELETE":            TokenDelete,
+	"CREATE":            TokenCreate,
+	"TABLE":             TokenTable,
+	"PRIMARY":           TokenPrimary,
+	"KEY":               TokenKey,
+	"UNIQUE":            TokenUnique,
+	"JOIN":              TokenJoin,
+	"ON":                TokenOn,
+	"INT":               TokenIntType,
+	"TEXT":              TokenTextType,
+	"INTEGER":           TokenIntType,
+	"VARCHAR":           TokenTextType,
+	"STRING":            TokenTextType,
+	"AND":               TokenAnd,
+	"LIMIT":             TokenLimit,
+	"IF":                TokenIf,
+	"NOT":               TokenNot,
+	"EXISTS":            TokenExists,
+	"COLLATE":           TokenCollate,
+	"TABLESAMPLE":       TokenTablesample,
+	"PERCENT":           TokenPercent,
+	"AUTO_INCREMENT":    TokenAutoIncrement,
+	"NULL":              TokenNull,
+	"INDEX":             TokenIndex,
+	"CHECK":             TokenCheck,
+	"DROP":              TokenDrop,
+	"DATABASE":          TokenDatabase,
+	"CURRENT_TIMESTAMP": TokenCurrentTimestamp,
+	"LIKE":              TokenLike,
+	"INSERTION_ORDER":   TokenInsertionOrder,
+	"FOREIGN":           TokenForeign,
+	"REFERENCES":        TokenReferences,
+	"VERIFY":            TokenVerify,
+	"AS":                TokenAs,
+	"BETWEEN":           TokenBetween,
+	"EXPLAIN":           TokenExplain,
+	"IN":                TokenIn,
+	"OR":                TokenOr,
+	"SHOW":              TokenShow,
+	"DISTINCT":          TokenDistinct,
+	"VACUUM":            TokenVacuum,
+	"GROUP":             TokenGroup,
+	"BY":                TokenBy,
+	"HAVING":            TokenHaving,
+	"CONFLICT":          TokenConflict,
+	"DO":                TokenDo,
+	"DATE":              TokenDate,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizerSkipsComments(t *testing.T) {
+	input := `-- leading comment
+SELECT /* inline */ id -- trailing
+FROM users /* multi
+line */`
+	want := []TokenType{TokenSelect, TokenIdent, TokenFrom, TokenIdent, TokenEOF}
+
+	tok := NewTokenizer(input)
+	for i, w := range want {
+		got := tok.NextToken()
+		if got.Type != w {
+			t.Fatalf("Token %d: expected type %d, got %v", i, w, got)
+		}
+	}
+}
+
+func TestParseEmptyStatement(t *testing.T) {
+	for _, input := range []string{"", "   \n\t ", "-- just a comment", "/* block */ -- and line"} {
+		p := NewParser(NewTokenizer(input))
+		if _, err := p.ParseStatement(); err != ErrEmptyStatement {
+			t.Errorf("%q: expected ErrEmptyStatement, got %v", input, err)
+		}
+	}
+}
+
+func TestTokenizePlaceholder(t *testing.T) {
+	tok := NewTokenizer("id = ? AND name = '?'")
+	want := []TokenType{TokenIdent, TokenEqual, TokenParam, TokenAnd, TokenIdent, TokenEqual, TokenString, TokenEOF}
+	for i, w := range want {
+		if got := tok.NextToken(); got.Type != w {
+			t.Errorf("Token %d: expected %d, got %d (%q)", i, w, got.Type, got.Literal)
+		}
+	}
+}
+
+func TestTokenizeNotEqual(t *testing.T) {
+	tok := NewTokenizer("a != 1 <> b < c ! d")
+	want := []struct {
+		Type    TokenType
+		Literal string
+	}{
+		{TokenIdent, "a"}, {TokenNotEqual, "!="}, {TokenNumber, "1"}, {TokenNotEqual, "<>"},
+		{TokenIdent, "b"}, {TokenLT, "<"}, {TokenIdent, "c"}, {TokenIllegal, "!"}, {TokenIdent, "d"},
+	}
+	for i, w := range want {
+		if got := tok.NextToken(); got.Type != w.Type || got.Literal != w.Literal {
+			t.Errorf("Token %d: expected %v, got %v", i, w, got)
+		}
+	}
+
+	for _, sql := range []string{"SELECT id FROM t WHERE status != 'done'", "SELECT id FROM t WHERE status <> 'done'"} {
+		sel := parse(t, sql).(*SelectStmt)
+		if cmp := sel.Where.Expr.(*ComparisonExpression); cmp.Operator != "!=" {
+			t.Errorf("%s: expected operator !=, got %q", sql, cmp.Operator)
+		}
+	}
+}
+
+func TestTokenizeQuotedIdentifiers(t *testing.T) {
+	tok := NewTokenizer("SELECT \"order\", `select`, t.\"from\", \"t\".*, \"say \"\"hi\"\"\" FROM \"t\"")
+	want := []struct {
+		Type    TokenType
+		Literal string
+	}{
+		{TokenSelect, "SELECT"}, {TokenIdent, "order"}, {TokenComma, ","}, {TokenIdent, "select"}, {TokenComma, ","},
+		{TokenIdent, "t.from"}, {TokenComma, ","}, {TokenIdent, "t."}, {TokenAsterisk, "*"}, {TokenComma, ","},
+		{TokenIdent, `say "hi"`}, {TokenFrom, "FROM"}, {TokenIdent, "t"}, {TokenEOF, ""},
+	}
+	for i, w := range want {
+		if got := tok.NextToken(); got.Type != w.Type || got.Literal != w.Literal {
+			t.Errorf("Token %d: expected %v, got %v", i, w, got)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	tok := NewTokenizer("SELECT id,\n  'naïve', x -- note\nFROM users")
+	want := []struct {
+		literal      string
+		line, column int
+	}{
+		{"SELECT", 1, 1}, {"id", 1, 8}, {",", 1, 10}, {"naïve", 2, 3}, {",", 2, 10}, {"x", 2, 12}, {"FROM", 3, 1}, {"users", 3, 6}, {"", 3, 11}, {"", 3, 11},
+	}
+	for i, w := range want {
+		got := tok.NextToken()
+		if got.Literal != w.literal || got.Line != w.line || got.Column != w.column {
+			t.Errorf("Token %d: expected %q at %d:%d, got %q at %d:%d", i, w.literal, w.line, w.column, got.Literal, got.Line, got.Column)
+		}
+	}
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"INSERT users VALUES (1)", "line 1, column 8: expected INTO, got identifier 'users'"},
+		{"SELECT id\nFROM users\nJOIN orders id = 1", "line 3, column 13: expected ON, got identifier 'id'"},
+		{"SHOW STATS", "line 1, column 11: expected identifier, got end of input"},
+	}
+	for _, tt := range tests {
+		_, err := NewParser(NewTokenizer(tt.sql)).ParseStatement()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.sql, tt.want, err)
+		}
+	}
+}
+
+func TestTokenTypeNames(t *testing.T) {
+	tests := []struct {
+		typ  TokenType
+		want string
+	}{
+		{TokenSelect, "SELECT"}, {TokenIntType, "INT"}, {TokenTextType, "TEXT"}, {TokenCurrentTimestamp, "CURRENT_TIMESTAMP"},
+		{TokenIdent, "identifier"}, {TokenEOF, "end of input"}, {TokenLParen, "("}, {TokenNotEqual, "!="},
+	}
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.want {
+			t.Errorf("TokenType %d: expected %q, got %q", int(tt.typ), tt.want, got)
+		}
+	}
+
+	for sql, want := range map[string]string{
+		"SELECT id users":                    "line 1, column 11: expected FROM, got identifier 'users'",
+		"CREATE TABLE t (id INT PRIMARY KEY": "expected , or ), got end of input",
+		"UPDATE users SET age = 1 LIMIT 1":   "unexpected LIMIT after UPDATE SET",
+		"SELECT id FROM t WHERE id IN (1)":   "expected SELECT after IN (, got number '1'",
+	} {
+		_, err := NewParser(NewTokenizer(sql)).ParseStatement()
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: expected error %q, got %v", sql, want, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsKeyword reports whether a bare name would be read as a keyword rather
+// than a name. The parser, which owns the keyword list, sets it.
+var IsKeyword = func(name string) bool { return false }
+
+// QuoteIdent returns name as it must be written in SQL: bare if it is a
+// plain identifier and not a keyword, else in double quotes.
+func QuoteIdent(name string) string {
+	plain := name != "" && !IsKeyword(name)
+	for i, ch := range name {
+		letter := 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+		if !letter && (i == 0 || ch < '0' || ch > '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// DDL reconstructs the CREATE TABLE statement for the definition. Parsing
+// the result yields an equal TableDef.
+func (t *TableDef) DDL() string {
+	var parts []string
+	for _, c := range t.Columns {
+		parts = append(parts, c.ddl())
+	}
+	for _, col := range t.Indexes {
+		parts = append(parts, fmt.Sprintf("INDEX (%s)", QuoteIdent(col)))
+	}
+	for _, fk := range t.ForeignKeys {
+		parts = append(parts, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", QuoteIdent(fk.Column), QuoteIdent(fk.RefTable), QuoteIdent(fk.RefColumn)))
+	}
+
+	ddl := fmt.Sprintf("CREATE TABLE %s (%s)", QuoteIdent(t.Name), strings.Join(parts, ", "))
+	if t.InsertionOrder {
+		ddl += " INSERTION_ORDER"
+	}
+	return ddl
+}
+
+// ddl renders a column definition, with options in the order the parser
+// accepts them.
+func (c ColumnDef) ddl() string {
+	def := QuoteIdent(c.Name) + " " + string(c.Type)
+	if c.MaxLength > 0 {
+		def = fmt.Sprintf("%s VARCHAR(%d)", QuoteIdent(c.Name), c.MaxLength)
+	}
+	if c.IsPrimary {
+		def += " PRIMARY KEY"
+	} else if c.IsUnique {
+		def += " UNIQUE"
+	}
+	if c.AutoIncrement {
+		def += " AUTO_INCREMENT"
+	}
+	if c.OnUpdateNow {
+		def += " ON UPDATE CURRENT_TIMESTAMP"
+	}
+	if c.Check != "" {
+		def += " CHECK (" + c.Check + ")"
+	}
+	if c.Collation != "" {
+		def += " COLLATE " + string(c.Collation)
+	}
+	return def
+}
@@ -1,6 +1,9 @@
 package schema
 
-import "mini-rdbms/db/types"
+import (
+	"fmt"
+	"mini-rdbms/db/types"
+)
 
 // ColumnDef defines a single column in a table.
 type ColumnDef struct {
@@ -8,6 +11,19 @@ type ColumnDef struct {
 	Type      types.DataType
 	IsPrimary bool
 	IsUnique  bool
+	Collation types.Collation `json:",omitempty"` // TEXT ordering; empty means binary
+	// MaxLength bounds TEXT values in characters, from VARCHAR(n). 0 means unbounded.
+	MaxLength int `json:",omitempty"`
+	// AutoIncrement assigns the next integer when an INT primary key is omitted or NULL.
+	AutoIncrement bool `json:",omitempty"`
+	// OnUpdateNow sets the column to the current time on every UPDATE that
+	// does not assign it (ON UPDATE CURRENT_TIMESTAMP).
+	OnUpdateNow bool `json:",omitempty"`
+	// Check is a CHECK constraint as SQL text, e.g. "amount > 0".
+	Check string `json:",omitempty"`
+	// Table is the source table of the column in a derived (joined) schema.
+	// Empty means the column belongs to the TableDef it is in. Not persisted.
+	Table string `json:"-"`
 }
 
 // ForeignKeyDef defines a foreign key constraint.
@@ -23,6 +39,10 @@ type TableDef struct {
 	Name        string
 	Columns     []ColumnDef
 	ForeignKeys []ForeignKeyDef // FK constraints for this table
+	Indexes     []string        // Columns with a non-unique INDEX
+	// InsertionOrder makes full scans return rows in the order they were
+	// inserted instead of primary-key order.
+	InsertionOrder bool
 }
 
 // GetColumn finds a column definition by name.
@@ -64,3 +84,38 @@ func (t *TableDef) GetForeignKey(column string) (ForeignKeyDef, bool) {
 	}
 	return ForeignKeyDef{}, false
 }
+
+// ResolveColumn finds the position of a column reference. A non-empty table
+// matches only columns from that table; a bare name must be unambiguous.
+func (t *TableDef) ResolveColumn(table, name string) (int, error) {
+	ref := name
+	if table != "" {
+		ref = table + "." + name
+	}
+
+	found := -1
+	for i, c := range t.Columns {
+		if c.Name != name {
+			continue
+		}
+		if table != "" && t.sourceTable(c) != table {
+			continue
+		}
+		if found != -1 {
+			return -1, fmt.Errorf("ambiguous column reference: %s", ref)
+		}
+		found = i
+	}
+	if found == -1 {
+		return -1, fmt.Errorf("unknown column: %s", ref)
+	}
+	return found, nil
+}
+
+// sourceTable returns the table a column came from.
+func (t *TableDef) sourceTable(c ColumnDef) string {
+	if c.Table != "" {
+		return c.Table
+	}
+	return t.Name
+}
@@ -1,59 +1,107 @@
 package storage
 
 import (
-	"encoding/json"
 	"fmt"
 	"mini-rdbms/db/schema"
 	"mini-rdbms/db/types"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
-// storageDir usually would be configured. We'll use "data".
-const DataDir = "data"
+// DefaultDataDir is the directory used when none is configured.
+const DefaultDataDir = "data"
 
 // SerializableTable is a helper struct for JSON encoding.
 type SerializableTable struct {
-	Name    string
-	Columns []schema.ColumnDef
+	Name          string
+	Columns       []schema.ColumnDef
+	Indexes       []string               `json:",omitempty"` // Secondary INDEX columns
+	ForeignKeys   []schema.ForeignKeyDef `json:",omitempty"`
+	AutoIncrement int                    `json:",omitempty"` // Last AUTO_INCREMENT id handed out
+	// InsertionOrder tables save Rows oldest first so loading restores the order
+	InsertionOrder bool `json:",omitempty"`
+	// Compact files are written without indentation, once vacuumed
+	Compact bool  `json:",omitempty"`
 	Rows    []Row // We convert map to slice for saving
 }
 
+// tableCache remembers the files LoadTable has parsed, keyed by file path.
+// It holds the decoded contents, not a Table: each load builds its own
+// Table, so engines sharing a directory never share in-memory rows. An
+// entry is only reused while the file's modification time and size are
+// unchanged; SaveTable and DeleteAllTables drop entries they make stale.
+var tableCache = struct {
+	sync.Mutex
+	entries map[string]cachedTable
+}{entries: make(map[string]cachedTable)}
+
+type cachedTable struct {
+	modTime time.Time
+	size    int64
+	format  Format
+	decoded *SerializableTable
+}
+
+func invalidateCached(filename string) {
+	tableCache.Lock()
+	delete(tableCache.entries, filename)
+	tableCache.Unlock()
+}
+
 // EnsureDataDir makes sure the data directory exists.
-func EnsureDataDir() error {
-	if _, err := os.Stat(DataDir); os.IsNotExist(err) {
-		return os.Mkdir(DataDir, 0755)
+func EnsureDataDir(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return os.MkdirAll(dir, 0755)
 	}
 	return nil
 }
 
-// SaveTable persists the table to disk atomically.
-func SaveTable(t *Table) error {
-	if err := EnsureDataDir(); err != nil {
+// SaveTable persists the table to disk atomically inside dir.
+func SaveTable(dir string, t *Table) error {
+	if err := EnsureDataDir(dir); err != nil {
 		return err
 	}
 
 	// Get a snapshot of data to write while holding the lock
-	rows := t.GetSnapshot()
+	rows := t.ScanSnapshot()
+
+	t.mu.RLock()
+	lastAutoID, compact, format := t.lastAutoID, t.compact, t.format
+	t.mu.RUnlock()
+	if format == "" {
+		format = FormatJSON
+	}
+	codec, err := codecFor(format)
+	if err != nil {
+		return err
+	}
 
 	sTable := SerializableTable{
-		Name:    t.Def.Name,
-		Columns: t.Def.Columns,
-		Rows:    rows,
+		Name:           t.Def.Name,
+		Columns:        t.Def.Columns,
+		Indexes:        t.Def.Indexes,
+		ForeignKeys:    t.Def.ForeignKeys,
+		AutoIncrement:  lastAutoID,
+		InsertionOrder: t.Def.InsertionOrder,
+		Compact:        compact,
+		Rows:           rows,
 	}
 
-	finalFilename := filepath.Join(DataDir, t.Def.Name+".json")
+	finalFilename := filepath.Join(dir, t.Def.Name+format.ext())
+	defer invalidateCached(finalFilename)
 	// Write to temp file first
-	tempFile, err := os.CreateTemp(DataDir, "tmp-*.json")
+	tempFile, err := os.CreateTemp(dir, "tmp-*"+format.ext())
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tempName := tempFile.Name()
 	defer os.Remove(tempName) // Cleanup if we fail
 
-	encoder := json.NewEncoder(tempFile)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(sTable); err != nil {
+	if err := codec.encode(tempFile, &sTable); err != nil {
 		tempFile.Close()
 		return fmt.Errorf("failed to encode table: %w", err)
 	}
@@ -65,12 +113,107 @@ func SaveTable(t *Table) error {
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
+	// Drop the file of a format the table was saved in before
+	for _, f := range formats {
+		if f == format {
+			continue
+		}
+		stale := filepath.Join(dir, t.Def.Name+f.ext())
+		invalidateCached(stale)
+		if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// LoadTable reads a table from disk.
-func LoadTable(tableName string) (*Table, error) {
-	filename := filepath.Join(DataDir, tableName+".json")
+// VacuumTable rewrites t's file in dir from the in-memory rows, without
+// indentation, and keeps it compact on later saves. The table is compacted
+// in memory first (see Table.Compact). It returns the file's size before
+// (0 if there was no file) and after.
+func VacuumTable(dir string, t *Table) (before, after int64, err error) {
+	_, _, info, err := findTableFile(dir, t.Def.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+	if info != nil {
+		before = info.Size()
+	}
+
+	t.Compact()
+	t.mu.Lock()
+	t.compact = true
+	t.mu.Unlock()
+	if err := SaveTable(dir, t); err != nil {
+		return before, 0, err
+	}
+
+	_, _, info, err = findTableFile(dir, t.Def.Name)
+	if err != nil {
+		return before, 0, err
+	}
+	if info == nil {
+		return before, 0, fmt.Errorf("table not found: %s", t.Def.Name)
+	}
+	return before, info.Size(), nil
+}
+
+// LoadTable reads a table from dir. A file that has not changed since it was
+// last loaded is not parsed again, but the table returned is always new.
+func LoadTable(dir, tableName string) (*Table, error) {
+	filename, format, info, err := findTableFile(dir, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+
+	tableCache.Lock()
+	cached, ok := tableCache.entries[filename]
+	tableCache.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return buildTable(cached.decoded, tableName, cached.format)
+	}
+
+	st, err := decodeTable(filename, tableName, format)
+	if err != nil {
+		return nil, err
+	}
+	t, err := buildTable(st, tableName, format)
+	if err != nil {
+		return nil, err
+	}
+	tableCache.Lock()
+	tableCache.entries[filename] = cachedTable{modTime: info.ModTime(), size: info.Size(), format: format, decoded: st}
+	tableCache.Unlock()
+	return t, nil
+}
+
+// ReadTable reads a table from dir, always parsing the file rather than
+// using what LoadTable cached.
+func ReadTable(dir, tableName string) (*Table, error) {
+	filename, format, info, err := findTableFile(dir, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+	st, err := decodeTable(filename, tableName, format)
+	if err != nil {
+		return nil, err
+	}
+	return buildTable(st, tableName, format)
+}
+
+// decodeTable parses a table file written in format.
+func decodeTable(filename, tableName string, format Format) (*SerializableTable, error) {
+	codec, err := codecFor(format)
+	if err != nil {
+		return nil, err
+	}
 	file, err := os.Open(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -81,51 +224,104 @@ func LoadTable(tableName string) (*Table, error) {
 	defer file.Close()
 
 	var sTable SerializableTable
-	if err := json.NewDecoder(file).Decode(&sTable); err != nil {
+	if err := codec.decode(file, &sTable); err != nil {
 		return nil, err
 	}
+	return &sTable, nil
+}
 
-	// Reconstruct Table
-	def := schema.TableDef{Name: sTable.Name, Columns: sTable.Columns}
+// buildTable makes a Table, with its own rows and indices, from decoded
+// file contents, which are left unchanged for the next build.
+func buildTable(sTable *SerializableTable, tableName string, format Format) (*Table, error) {
+	def := schema.TableDef{
+		Name:           sTable.Name,
+		Columns:        append([]schema.ColumnDef(nil), sTable.Columns...),
+		Indexes:        append([]string(nil), sTable.Indexes...),
+		ForeignKeys:    append([]schema.ForeignKeyDef(nil), sTable.ForeignKeys...),
+		InsertionOrder: sTable.InsertionOrder,
+	}
 	t := NewTable(def)
+	t.lastAutoID = sTable.AutoIncrement
+	t.compact = sTable.Compact
+	t.format = format
+	pkCol, _ := def.GetPrimaryKey()
 
-	// Since JSON unmarshalling of interface{} converts numbers to float64,
-	// we need to fix the types based on schema.
+	// Values decode into their declared Go types (gob keeps them, and see
+	// types.Value.UnmarshalJSON), so rows can be used as-is.
 	for _, row := range sTable.Rows {
-		// Convert values
-		fixedValues := make([]types.Value, len(row.Values))
-		for i, val := range row.Values {
-			colType := def.Columns[i].Type
-			fixedValues[i] = types.Value{Type: colType, Val: val.Val}
-
-			// Fix float64 to int if necessary
-			if colType == types.TypeInt {
-				if f, ok := val.Val.(float64); ok {
-					fixedValues[i].Val = int(f)
-				} else if iVal, ok := val.Val.(int); ok {
-					fixedValues[i].Val = iVal
-				}
-			}
+		if len(row.Values) != len(def.Columns) {
+			return nil, fmt.Errorf("corrupt row in %s: expected %d values, got %d", tableName, len(def.Columns), len(row.Values))
 		}
 
-		// Insert directly (bypassing redundant checks optionally, but safer to use Insert or manual set)
-		// Manual set to avoid re-checking constraints if trusted valid data,
-		// but we do need to rebuild indices.
-
-		// Let's use internal logic to populate Rows and Indices
-
-		pkCol, _ := def.GetPrimaryKey()
+		// Populate Rows directly; data from disk skips the type checks but
+		// not the key checks, so a file with duplicate keys fails to load
+		// instead of leaving the indices out of step with the rows.
 		pkIdx := def.GetColumnIndex(pkCol.Name)
-		pk := fixedValues[pkIdx].Val
-
-		t.Rows[pk] = Row{Values: fixedValues}
+		pk := row.Values[pkIdx].Val
+		if err := t.checkUnique(row.Values, nil); err != nil {
+			return nil, fmt.Errorf("corrupt row in %s: %w", tableName, err)
+		}
 
-		// Rebuild indices
-		for idxName, idx := range t.Indices {
-			colIdx := def.GetColumnIndex(idxName)
-			idx.Set(fixedValues[colIdx], pk)
+		values := append([]types.Value(nil), row.Values...)
+		t.Rows[pk] = &Row{Values: values}
+		t.indexRow(values, pk)
+		if def.InsertionOrder {
+			t.order = append(t.order, pk)
+		}
+		if pkCol.AutoIncrement {
+			// Never hand out an id that is already on disk
+			t.noteAutoID(pk)
 		}
 	}
 
 	return t, nil
 }
+
+// ListTables returns the names of the tables saved in dir, in any format,
+// sorted. Leftover tmp-* files from interrupted saves are skipped.
+func ListTables(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name, ok := tableFileName(entry.Name())
+		if entry.IsDir() || !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeleteAllTables removes every table file (and leftover tmp-* temp file),
+// in any format, from dir. Anything that is not a .json or .gob file is left
+// alone.
+func DeleteAllTables(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, FormatJSON.ext()) && !strings.HasSuffix(name, FormatGob.ext())) {
+			continue
+		}
+		filename := filepath.Join(dir, name)
+		invalidateCached(filename)
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
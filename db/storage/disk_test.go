@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveLoadPreservesValueTypes(t *testing.T) {
+	dir := t.TempDir()
+	def := schema.TableDef{
+		Name: "things",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "label", Type: types.TypeText, IsUnique: true},
+			{Name: "qty", Type: types.TypeInt},
+		},
+	}
+	tbl := NewTable(def)
+	rows := [][]types.Value{
+		{{Type: types.TypeInt, Val: 1}, {Type: types.TypeText, Val: "one"}, {Type: types.TypeInt, Val: 9007199254740993}},
+		{{Type: types.TypeInt, Val: -2}, {Type: types.TypeText, Val: "two \"quoted\""}, {Type: types.TypeInt, Val: 0}},
+	}
+	for _, r := range rows {
+		if err := tbl.Insert(r); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	if err := SaveTable(dir, tbl); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	loaded, err := LoadTable(dir, "things")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	for _, want := range rows {
+		pk := want[0].Val
+		got, ok := loaded.GetRow(pk)
+		if !ok {
+			t.Fatalf("Row %v missing after reload", pk)
+		}
+		for i, v := range got.Values {
+			switch v.Type {
+			case types.TypeInt:
+				if _, ok := v.Val.(int); !ok {
+					t.Errorf("Column %d: expected int, got %T", i, v.Val)
+				}
+			case types.TypeText:
+				if _, ok := v.Val.(string); !ok {
+					t.Errorf("Column %d: expected string, got %T", i, v.Val)
+				}
+			}
+			if v != want[i] {
+				t.Errorf("Column %d: expected %#v, got %#v", i, want[i], v)
+			}
+		}
+	}
+
+	// The unique index must be keyed by the decoded string, not a raw JSON value.
+	if pk, ok := loaded.IndexLookup("label", types.Value{Type: types.TypeText, Val: "one"}); !ok || pk != 1 {
+		t.Errorf("Expected index lookup to find pk 1, got %v (found=%v)", pk, ok)
+	}
+}
+
+func TestLoadTableRejectsMismatchedValue(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"Name":"bad","Columns":[{"Name":"id","Type":"INT","IsPrimary":true,"IsUnique":false}],
+"Rows":[{"Values":[{"Type":"INT","Val":"not a number"}]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadTable(dir, "bad")
+	if err == nil || !strings.Contains(err.Error(), "invalid INT value") {
+		t.Errorf("Expected invalid INT error, got %v", err)
+	}
+}
+
+func TestLoadTableCachesUnchangedFile(t *testing.T) {
+	dir := t.TempDir()
+	def := schema.TableDef{
+		Name: "things",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "label", Type: types.TypeText},
+		},
+	}
+	tbl := NewTable(def)
+	if err := tbl.Insert([]types.Value{types.NewInt(1), types.NewText("one")}); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := SaveTable(dir, tbl); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	filename := filepath.Join(dir, "things.json")
+	decoded := func() *SerializableTable {
+		tableCache.Lock()
+		defer tableCache.Unlock()
+		return tableCache.entries[filename].decoded
+	}
+
+	first, err := LoadTable(dir, "things")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	cached := decoded()
+	second, err := LoadTable(dir, "things")
+	if err != nil {
+		t.Fatalf("Failed to load again: %v", err)
+	}
+	if cached == nil || decoded() != cached {
+		t.Fatal("expected the unchanged file not to be parsed again")
+	}
+
+	// Each load is its own table: a change to one is not seen by the other
+	if first == second {
+		t.Fatal("expected each load to build a new table")
+	}
+	if err := first.Update(types.NewInt(1), []types.Value{types.NewInt(1), types.NewText("changed")}); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if row, _ := second.GetRow(1); row.Values[1].Val != "one" {
+		t.Fatalf("second load sees the first's update: %v", row.Values)
+	}
+
+	// Touching the file invalidates the cached copy.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filename, later, later); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	third, err := LoadTable(dir, "things")
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if decoded() == cached {
+		t.Fatal("expected a modtime change to reparse the file")
+	}
+
+	// Saving drops the cache entry as well.
+	if err := tbl.Insert([]types.Value{types.NewInt(2), types.NewText("two")}); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := SaveTable(dir, tbl); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	fourth, err := LoadTable(dir, "things")
+	if err != nil {
+		t.Fatalf("Failed to load after save: %v", err)
+	}
+	if fourth == third || fourth.RowCount() != 2 {
+		t.Fatalf("expected a fresh load with 2 rows after save, got %d rows", fourth.RowCount())
+	}
+}
+
+func TestSaveTableWritesRowsInKeyOrder(t *testing.T) {
+	dir := t.TempDir()
+	tbl := NewTable(schema.TableDef{
+		Name:    "nums",
+		Columns: []schema.ColumnDef{{Name: "id", Type: types.TypeInt, IsPrimary: true}},
+	})
+	for _, id := range []int{30, 2, 100, 7} {
+		if err := tbl.Insert([]types.Value{types.NewInt(id)}); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+	if err := SaveTable(dir, tbl); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "nums.json"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	var saved SerializableTable
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Failed to decode file: %v", err)
+	}
+	var ids []interface{}
+	for _, row := range saved.Rows {
+		ids = append(ids, row.Values[0].Val)
+	}
+	if got := fmt.Sprint(ids); got != "[2 7 30 100]" {
+		t.Errorf("Expected rows saved in key order, got %s", got)
+	}
+}
+
+func TestLoadTableRejectsDuplicateKeys(t *testing.T) {
+	dir := t.TempDir()
+	columns := `"Columns":[{"Name":"id","Type":"INT","IsPrimary":true},{"Name":"email","Type":"TEXT","IsUnique":true}]`
+	files := map[string]string{
+		"dup_pk":    `{"Name":"dup_pk",` + columns + `,"Rows":[{"Values":[{"Type":"INT","Val":1},{"Type":"TEXT","Val":"a"}]},{"Values":[{"Type":"INT","Val":1},{"Type":"TEXT","Val":"b"}]}]}`,
+		"dup_email": `{"Name":"dup_email",` + columns + `,"Rows":[{"Values":[{"Type":"INT","Val":1},{"Type":"TEXT","Val":"a"}]},{"Values":[{"Type":"INT","Val":2},{"Type":"TEXT","Val":"a"}]}]}`,
+	}
+	want := map[string]string{
+		"dup_pk":    "corrupt row in dup_pk: duplicate primary key: 1",
+		"dup_email": "corrupt row in dup_email: duplicate unique value for column email: a",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadTable(dir, name)
+		if err == nil || err.Error() != want[name] {
+			t.Errorf("%s: expected %q, got %v", name, want[name], err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is the encoding of a table file.
+type Format string
+
+const (
+	// FormatJSON writes human-readable .json files. It is the default.
+	FormatJSON Format = "json"
+	// FormatGob writes binary .gob files, which are smaller and decode
+	// values straight into their Go types.
+	FormatGob Format = "gob"
+)
+
+// formats lists every Format; LoadTable looks for a file in each.
+var formats = []Format{FormatJSON, FormatGob}
+
+// ParseFormat returns the Format named by s ("json" or "gob", any case).
+// An empty s means FormatJSON.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatGob:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown storage format: %s (want json or gob)", s)
+}
+
+// ext is the file name extension, with the dot.
+func (f Format) ext() string { return "." + string(f) }
+
+func init() {
+	// DATE values travel in the interface-typed Value.Val
+	gob.Register(time.Time{})
+}
+
+// SetFormat makes later saves of t write f. The table's file in the old
+// format is removed by the next save. A loaded table keeps the format it
+// was read in until this is called.
+func (t *Table) SetFormat(f Format) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.format = f
+}
+
+// tableCodec encodes and decodes a SerializableTable in one Format.
+type tableCodec interface {
+	encode(w io.Writer, st *SerializableTable) error
+	decode(r io.Reader, st *SerializableTable) error
+}
+
+func codecFor(f Format) (tableCodec, error) {
+	switch f {
+	case FormatJSON, "":
+		return jsonCodec{}, nil
+	case FormatGob:
+		return gobCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown storage format: %s", f)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) encode(w io.Writer, st *SerializableTable) error {
+	encoder := json.NewEncoder(w)
+	if !st.Compact {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(st)
+}
+
+func (jsonCodec) decode(r io.Reader, st *SerializableTable) error {
+	return json.NewDecoder(r).Decode(st)
+}
+
+// gobCodec keeps INT values as int and DATEs as time.Time on the way back,
+// with no fixups by declared column type.
+type gobCodec struct{}
+
+func (gobCodec) encode(w io.Writer, st *SerializableTable) error {
+	return gob.NewEncoder(w).Encode(st)
+}
+
+func (gobCodec) decode(r io.Reader, st *SerializableTable) error {
+	return gob.NewDecoder(r).Decode(st)
+}
+
+// findTableFile returns the file holding tableName in dir and its format.
+// If a crash left the table in more than one format, the newest file wins.
+// info is nil if there is no file.
+func findTableFile(dir, tableName string) (string, Format, os.FileInfo, error) {
+	var (
+		found  string
+		format Format
+		latest os.FileInfo
+	)
+	for _, f := range formats {
+		filename := filepath.Join(dir, tableName+f.ext())
+		info, err := os.Stat(filename)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", "", nil, err
+		}
+		if latest == nil || info.ModTime().After(latest.ModTime()) {
+			found, format, latest = filename, f, info
+		}
+	}
+	return found, format, latest, nil
+}
+
+// tableFileName reports whether name is a table file, not a leftover
+// tmp-* file, and returns the table's name.
+func tableFileName(name string) (string, bool) {
+	if strings.HasPrefix(name, "tmp-") {
+		return "", false
+	}
+	for _, f := range formats {
+		if strings.HasSuffix(name, f.ext()) {
+			return strings.TrimSuffix(name, f.ext()), true
+		}
+	}
+	return "", false
+}
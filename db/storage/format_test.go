@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/types"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGobRoundTripKeepsExactValues(t *testing.T) {
+	dir := t.TempDir()
+	tbl := NewTable(schema.TableDef{
+		Name: "things",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true, AutoIncrement: true},
+			{Name: "label", Type: types.TypeText, IsUnique: true},
+			{Name: "qty", Type: types.TypeInt},
+			{Name: "day", Type: types.TypeDate},
+		},
+		Indexes: []string{"qty"},
+	})
+	day := types.NewDate(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	rows := [][]types.Value{
+		{types.NewInt(1), types.NewText("one"), types.NewInt(9007199254740993), day},
+		{types.NewInt(-2), types.NewText("two \"quoted\"\n"), types.NewInt(0), day},
+		{types.NewInt(40), types.NewText(""), types.NewInt(-9007199254740993), day},
+	}
+	for _, r := range rows {
+		if err := tbl.Insert(r); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+	tbl.SetFormat(FormatGob)
+	if err := SaveTable(dir, tbl); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "things.gob")); err != nil {
+		t.Fatalf("Expected things.gob: %v", err)
+	}
+
+	loaded, err := ReadTable(dir, "things")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if loaded.RowCount() != len(rows) {
+		t.Fatalf("Expected %d rows, got %d", len(rows), loaded.RowCount())
+	}
+	for _, want := range rows {
+		got, ok := loaded.GetRow(want[0].Val)
+		if !ok {
+			t.Fatalf("Row %v missing after reload", want[0].Val)
+		}
+		for i, v := range got.Values {
+			if v.Type != want[i].Type {
+				t.Errorf("Row %v column %d: expected type %s, got %s", want[0].Val, i, want[i].Type, v.Type)
+			}
+			switch v.Type {
+			case types.TypeInt:
+				if n, ok := v.Val.(int); !ok || n != want[i].Val {
+					t.Errorf("Row %v column %d: expected int %v, got %T %v", want[0].Val, i, want[i].Val, v.Val, v.Val)
+				}
+			case types.TypeText:
+				if s, ok := v.Val.(string); !ok || s != want[i].Val {
+					t.Errorf("Row %v column %d: expected string %q, got %T %v", want[0].Val, i, want[i].Val, v.Val, v.Val)
+				}
+			case types.TypeDate:
+				if v.Hash() != want[i].Hash() {
+					t.Errorf("Row %v column %d: expected %v, got %v", want[0].Val, i, want[i], v)
+				}
+			}
+		}
+	}
+	if pk, ok := loaded.IndexLookup("label", types.NewText("one")); !ok || pk != 1 {
+		t.Errorf("Expected index lookup to find pk 1, got %v (found=%v)", pk, ok)
+	}
+	if key, err := loaded.InsertKey([]types.Value{types.NewText("next"), types.NewInt(1), day}); err != nil || key.Val != 41 {
+		t.Errorf("Expected AUTO_INCREMENT to resume at 41, got %v (%v)", key, err)
+	}
+
+	// Saving as JSON again replaces the gob file
+	loaded.SetFormat(FormatJSON)
+	if err := SaveTable(dir, loaded); err != nil {
+		t.Fatalf("Failed to save as JSON: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "things.gob")); !os.IsNotExist(err) {
+		t.Errorf("Expected things.gob to be removed, got %v", err)
+	}
+	names, err := ListTables(dir)
+	if err != nil || len(names) != 1 || names[0] != "things" {
+		t.Errorf("Expected [things], got %v (%v)", names, err)
+	}
+	again, err := LoadTable(dir, "things")
+	if err != nil || again.RowCount() != len(rows)+1 {
+		t.Errorf("Expected %d rows from JSON, got %v", len(rows)+1, err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatJSON, "json": FormatJSON, "GOB": FormatGob} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("Expected an error for xml")
+	}
+}
@@ -1,9 +1,52 @@
 package storage
 
-import "mini-rdbms/db/types"
+import (
+	"fmt"
+	"mini-rdbms/db/types"
+)
 
 // Row represents a single record in the table.
 // We use a slice of values corresponding to the column order in the schema.
 type Row struct {
 	Values []types.Value
 }
+
+// Scan copies the row's values into dest, one pointer per column, in the
+// style of database/sql's Rows.Scan. Supported destinations are *int,
+// *int64, *string, *types.Value and *interface{}.
+func (r Row) Scan(dest ...any) error {
+	if len(dest) != len(r.Values) {
+		return fmt.Errorf("scan: expected %d destinations, got %d", len(r.Values), len(dest))
+	}
+
+	for i, d := range dest {
+		v := r.Values[i]
+		switch p := d.(type) {
+		case *types.Value:
+			*p = v
+		case *interface{}:
+			*p = v.Val
+		case *int:
+			n, err := v.AsInt()
+			if err != nil {
+				return fmt.Errorf("scan column %d: cannot assign %s into *int", i, v.Type)
+			}
+			*p = n
+		case *int64:
+			n, err := v.AsInt()
+			if err != nil {
+				return fmt.Errorf("scan column %d: cannot assign %s into *int64", i, v.Type)
+			}
+			*p = int64(n)
+		case *string:
+			s, err := v.AsText()
+			if err != nil {
+				return fmt.Errorf("scan column %d: cannot assign %s into *string", i, v.Type)
+			}
+			*p = s
+		default:
+			return fmt.Errorf("scan column %d: unsupported destination type %T", i, d)
+		}
+	}
+	return nil
+}
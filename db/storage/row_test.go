@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"mini-rdbms/db/types"
+	"strings"
+	"testing"
+)
+
+func TestRowScan(t *testing.T) {
+	row := Row{Values: []types.Value{
+		{Type: types.TypeInt, Val: 7},
+		{Type: types.TypeText, Val: "Alice"},
+		{Type: types.TypeInt, Val: 250},
+		{Type: types.TypeText, Val: "note"},
+		{Type: types.TypeInt, Val: 1},
+	}}
+
+	var (
+		id     int
+		name   string
+		amount int64
+		raw    types.Value
+		other  interface{}
+	)
+	if err := row.Scan(&id, &name, &amount, &raw, &other); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if id != 7 || name != "Alice" || amount != 250 {
+		t.Errorf("Unexpected scan result: id=%d name=%q amount=%d", id, name, amount)
+	}
+	if raw.Type != types.TypeText || raw.Val != "note" {
+		t.Errorf("Unexpected raw value: %#v", raw)
+	}
+	if other != 1 {
+		t.Errorf("Expected interface{} dest to hold 1, got %#v", other)
+	}
+}
+
+func TestRowScanErrors(t *testing.T) {
+	row := Row{Values: []types.Value{
+		{Type: types.TypeInt, Val: 1},
+		{Type: types.TypeText, Val: "x"},
+	}}
+
+	var id int
+	var wrong int
+	if err := row.Scan(&id); err == nil || !strings.Contains(err.Error(), "expected 2 destinations") {
+		t.Errorf("Expected count mismatch error, got %v", err)
+	}
+	if err := row.Scan(&id, &wrong); err == nil || !strings.Contains(err.Error(), "cannot assign TEXT into *int") {
+		t.Errorf("Expected type mismatch error, got %v", err)
+	}
+	var f float64
+	if err := row.Scan(&id, &f); err == nil || !strings.Contains(err.Error(), "unsupported destination") {
+		t.Errorf("Expected unsupported destination error, got %v", err)
+	}
+}
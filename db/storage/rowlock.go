@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// rowLockStripes is how many locks EnableRowLocks spreads rows across.
+const rowLockStripes = 64
+
+// rowLocks stripes per-row locks over a fixed set of mutexes; two rows
+// share a lock only when their keys hash to the same stripe.
+type rowLocks []sync.RWMutex
+
+// of returns the lock guarding the row with primary key pk.
+func (l rowLocks) of(pk interface{}) *sync.RWMutex {
+	var h uint32
+	switch k := pk.(type) {
+	case int:
+		h = uint32(k)
+	default:
+		f := fnv.New32a()
+		fmt.Fprint(f, k)
+		h = f.Sum32()
+	}
+	return &l[h%uint32(len(l))]
+}
+
+// EnableRowLocks lets Update change a row's unindexed columns while holding
+// a lock on that row instead of the whole table, so point updates no longer
+// wait for a long scan to finish. Inserts, deletes and updates that change
+// an indexed column still lock the whole table. Once enabled, row locks
+// stay on for the life of the table.
+func (t *Table) EnableRowLocks() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rowLocks == nil {
+		t.rowLocks = make(rowLocks, rowLockStripes)
+	}
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/types"
+	"sync"
+	"testing"
+)
+
+func newCountersTable(tb testing.TB, rows int, rowLocks bool) *Table {
+	tb.Helper()
+	tbl := NewTable(schema.TableDef{
+		Name: "counters",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "label", Type: types.TypeText, IsUnique: true},
+			{Name: "hits", Type: types.TypeInt},
+		},
+	})
+	if rowLocks {
+		tbl.EnableRowLocks()
+	}
+	for i := 0; i < rows; i++ {
+		vals := []types.Value{types.NewInt(i), types.NewText(fmt.Sprintf("c%d", i)), types.NewInt(0)}
+		if err := tbl.Insert(vals); err != nil {
+			tb.Fatalf("Failed to insert: %v", err)
+		}
+	}
+	return tbl
+}
+
+// Run with -race: point updates under row locks must not race with scans,
+// point reads or updates that fall back to the table lock.
+func TestRowLocksConcurrentUpdatesAndScans(t *testing.T) {
+	const rows, workers, rounds = 32, 8, 50
+	tbl := newCountersTable(t, rows, true)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds)
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		// Each worker owns the rows id % workers == w, so its counts are exact.
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				for id := w; id < rows; id += workers {
+					row, ok := tbl.GetRow(id)
+					if !ok {
+						errs <- fmt.Errorf("row %d missing", id)
+						return
+					}
+					vals := append([]types.Value{}, row.Values...)
+					vals[2] = types.NewInt(vals[2].Val.(int) + 1)
+					if r == rounds-1 {
+						// Renaming changes a unique column, so it takes the table lock
+						vals[1] = types.NewText(fmt.Sprintf("done%d", id))
+					}
+					if err := tbl.Update(types.NewInt(id), vals); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				n := 0
+				tbl.Scan(func(pk interface{}, row Row) bool {
+					n++
+					return true
+				})
+				if n != rows {
+					errs <- fmt.Errorf("scan saw %d rows, want %d", n, rows)
+				}
+				tbl.GetSnapshot()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for id := 0; id < rows; id++ {
+		row, _ := tbl.GetRow(id)
+		if got := row.Values[2].Val; got != rounds {
+			t.Errorf("Row %d: expected %d hits, got %v", id, rounds, got)
+		}
+		label := types.NewText(fmt.Sprintf("done%d", id))
+		if pk, ok := tbl.IndexLookup("label", label); !ok || pk != id {
+			t.Errorf("Row %d: expected %s indexed, got %v (found=%v)", id, label.Val, pk, ok)
+		}
+	}
+}
+
+func TestRowLocksKeepConstraints(t *testing.T) {
+	tbl := newCountersTable(t, 2, true)
+	err := tbl.Update(types.NewInt(0), []types.Value{types.NewInt(0), types.NewText("c1"), types.NewInt(1)})
+	if err == nil {
+		t.Errorf("Expected a unique violation through the row-lock path")
+	}
+	err = tbl.Update(types.NewInt(0), []types.Value{types.NewInt(1), types.NewText("c0"), types.NewInt(1)})
+	if err == nil {
+		t.Errorf("Expected a primary key collision to be rejected")
+	}
+	// A free key takes the table-lock path and moves the row
+	if err := tbl.Update(types.NewInt(0), []types.Value{types.NewInt(5), types.NewText("c0"), types.NewInt(1)}); err != nil {
+		t.Errorf("Failed to change primary key: %v", err)
+	}
+	if _, ok := tbl.GetRow(5); !ok {
+		t.Errorf("Expected row under its new key")
+	}
+	if err := tbl.Update(types.NewInt(9), []types.Value{types.NewInt(9), types.NewText("c9"), types.NewInt(1)}); err == nil {
+		t.Errorf("Expected missing row error")
+	}
+}
+
+// BenchmarkPointUpdatesDuringScan measures point updates while another
+// goroutine scans the table over and over, with and without row locks.
+func BenchmarkPointUpdatesDuringScan(b *testing.B) {
+	const rows = 10000
+	for _, rowLocks := range []bool{false, true} {
+		name := "TableLock"
+		if rowLocks {
+			name = "RowLocks"
+		}
+		b.Run(name, func(b *testing.B) {
+			tbl := newCountersTable(b, rows, rowLocks)
+			stop := make(chan struct{})
+			var scans sync.WaitGroup
+			scans.Add(1)
+			go func() {
+				defer scans.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					tbl.Scan(func(pk interface{}, row Row) bool { return true })
+				}
+			}()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					id := i % rows
+					i++
+					vals := []types.Value{types.NewInt(id), types.NewText(fmt.Sprintf("c%d", id)), types.NewInt(i)}
+					if err := tbl.Update(types.NewInt(id), vals); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			close(stop)
+			scans.Wait()
+		})
+	}
+}
@@ -3,10 +3,11 @@ package storage
 import (
 	"mini-rdbms/db/types"
 	"sort"
+	"time"
 )
 
 // sortPrimaryKeys sorts a slice of primary keys based on their type.
-// Supports INT and TEXT types for deterministic ordering.
+// Supports INT, TEXT and DATE types for deterministic ordering.
 func sortPrimaryKeys(pks []interface{}, pkType types.DataType) {
 	sort.Slice(pks, func(i, j int) bool {
 		if pkType == types.TypeInt {
@@ -25,6 +26,13 @@ func sortPrimaryKeys(pks []interface{}, pkType types.DataType) {
 				return a < b
 			}
 			return false
+		} else if pkType == types.TypeDate {
+			a, aOk := pks[i].(time.Time)
+			b, bOk := pks[j].(time.Time)
+			if aOk && bOk {
+				return a.Before(b)
+			}
+			return false
 		}
 		return false
 	})
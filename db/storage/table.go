@@ -6,6 +6,7 @@ import (
 	"mini-rdbms/db/schema"
 	"mini-rdbms/db/types"
 	"sync"
+	"unicode/utf8"
 )
 
 // Table represents a database table in memory.
@@ -13,16 +14,32 @@ import (
 type Table struct {
 	mu      sync.RWMutex
 	Def     schema.TableDef
-	Rows    map[interface{}]Row         // PK -> Row
+	Rows    map[interface{}]*Row        // PK -> Row
 	Indices map[string]*index.HashIndex // Column Name -> Index
+	// SecondaryIndices are non-unique indices declared with INDEX (col)
+	SecondaryIndices map[string]*index.MultiIndex
+
+	lastAutoID int // Highest id seen for an AUTO_INCREMENT primary key
+
+	// compact saves the file without indentation; set by VacuumTable
+	compact bool
+	// format is the encoding SaveTable writes; empty means FormatJSON
+	format Format
+
+	// order lists PKs oldest first; only kept when Def.InsertionOrder is set
+	order []interface{}
+
+	// rowLocks guard each row's Values when set; see EnableRowLocks
+	rowLocks rowLocks
 }
 
 // NewTable creates a new empty table.
 func NewTable(def schema.TableDef) *Table {
 	t := &Table{
-		Def:     def,
-		Rows:    make(map[interface{}]Row),
-		Indices: make(map[string]*index.HashIndex),
+		Def:              def,
+		Rows:             make(map[interface{}]*Row),
+		Indices:          make(map[string]*index.HashIndex),
+		SecondaryIndices: make(map[string]*index.MultiIndex),
 	}
 
 	// Create indices for Primary Key and Unique columns
@@ -31,71 +48,202 @@ func NewTable(def schema.TableDef) *Table {
 			t.Indices[col.Name] = index.NewHashIndex()
 		}
 	}
+
+	// Secondary indices; a unique index already covers its column
+	for _, colName := range def.Indexes {
+		if _, ok := t.Indices[colName]; !ok {
+			t.SecondaryIndices[colName] = index.NewMultiIndex()
+		}
+	}
 	return t
 }
 
 // Insert adds a row to the table. Enforces constraints.
 func (t *Table) Insert(values []types.Value) error {
+	_, err := t.InsertKey(values)
+	return err
+}
+
+// InsertKey is Insert that also returns the new row's primary key, which
+// differs from the value given when an AUTO_INCREMENT key was assigned.
+func (t *Table) InsertKey(values []types.Value) (types.Value, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	return t.insert(values)
+}
 
-	if len(values) != len(t.Def.Columns) {
-		return fmt.Errorf("column count mismatch: expected %d, got %d", len(t.Def.Columns), len(values))
+// InsertBatch inserts rows under a single lock, with the same checks as
+// Insert. A row that fails is skipped and the rest are still inserted; errs
+// holds each row's error (nil on success) and keys each inserted row's
+// primary key, both parallel to rows.
+func (t *Table) InsertBatch(rows [][]types.Value) (keys []types.Value, errs []error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys = make([]types.Value, len(rows))
+	errs = make([]error, len(rows))
+	for i, values := range rows {
+		keys[i], errs[i] = t.insert(values)
 	}
+	return keys, errs
+}
 
-	// Validate types
-	for i, val := range values {
-		if val.Type != t.Def.Columns[i].Type {
-			return fmt.Errorf("type mismatch for column %s: expected %s, got %s", t.Def.Columns[i].Name, t.Def.Columns[i].Type, val.Type)
-		}
+// insert validates and adds one row. Caller must hold t.mu.
+func (t *Table) insert(values []types.Value) (types.Value, error) {
+	values = t.fillAutoIncrement(values)
+
+	if len(values) != len(t.Def.Columns) {
+		return types.Value{}, fmt.Errorf("column count mismatch: expected %d, got %d", len(t.Def.Columns), len(values))
 	}
 
-	// Check constraints and gather keys
-	var pk interface{}
+	if err := t.checkValues(values); err != nil {
+		return types.Value{}, err
+	}
 
-	// 1. Check Primary Key
 	pkCol, ok := t.Def.GetPrimaryKey()
 	if !ok {
-		return fmt.Errorf("table %s has no primary key", t.Def.Name)
+		return types.Value{}, fmt.Errorf("table %s has no primary key", t.Def.Name)
 	}
 	pkIdx := t.Def.GetColumnIndex(pkCol.Name)
-	pk = values[pkIdx].Val
+	pk := values[pkIdx].Val
 
-	if _, exists := t.Rows[pk]; exists {
-		return fmt.Errorf("duplicate primary key: %v", pk)
+	if err := t.checkUnique(values, nil); err != nil {
+		return types.Value{}, err
 	}
 
-	// 2. Check Unique Constraints
-	for _, col := range t.Def.Columns {
-		if col.IsUnique && !col.IsPrimary {
-			colIdx := t.Def.GetColumnIndex(col.Name)
-			val := values[colIdx]
-			idx, hasIdx := t.Indices[col.Name]
-			if hasIdx {
-				if _, exists := idx.Get(val); exists {
-					return fmt.Errorf("duplicate unique value for column %s: %v", col.Name, val.Val)
-				}
-			}
-		}
+	// Do Insert
+	t.Rows[pk] = &Row{Values: values}
+	if t.Def.InsertionOrder {
+		t.order = append(t.order, pk)
+	}
+	if pkCol.AutoIncrement {
+		t.noteAutoID(pk)
 	}
 
-	// 3. Do Insert
-	t.Rows[pk] = Row{Values: values}
+	t.indexRow(values, pk)
+	return values[pkIdx], nil
+}
 
-	// 4. Update Indices
+// indexRow adds a new row's values to every index. The caller holds the
+// lock and has already checked the row with checkUnique.
+func (t *Table) indexRow(values []types.Value, pk interface{}) {
 	for _, col := range t.Def.Columns {
 		if col.IsPrimary || col.IsUnique {
 			idx, hasIdx := t.Indices[col.Name]
 			if hasIdx {
 				colIdx := t.Def.GetColumnIndex(col.Name)
-				idx.Set(values[colIdx], pk)
+				idx.Set(t.uniqueKey(colIdx, values[colIdx]), pk)
 			}
 		}
 	}
+	for colName, idx := range t.SecondaryIndices {
+		idx.Add(values[t.Def.GetColumnIndex(colName)], pk)
+	}
+}
+
+// checkUnique is the one check for the primary key and every UNIQUE
+// column, shared by Insert and Update. It fails if a value in values
+// already belongs to a row other than self; Insert passes a nil self.
+// The caller holds the lock.
+func (t *Table) checkUnique(values []types.Value, self interface{}) error {
+	for i, col := range t.Def.Columns {
+		idx, ok := t.Indices[col.Name]
+		if !ok {
+			continue
+		}
+		owner, exists := idx.Get(t.uniqueKey(i, values[i]))
+		if !exists || (self != nil && owner == self) {
+			continue
+		}
+		if col.IsPrimary {
+			return fmt.Errorf("duplicate primary key: %v", values[i].Val)
+		}
+		return fmt.Errorf("duplicate unique value for column %s: %v", col.Name, values[i].Val)
+	}
+	return nil
+}
+
+// uniqueKey is the key of v, a value of column col, in the column's unique
+// index. Under a non-binary collation, TEXT values that compare equal, such
+// as 'Alice' and 'alice' under NOCASE, share a key, so UNIQUE and PRIMARY KEY
+// treat them as duplicates.
+func (t *Table) uniqueKey(col int, v types.Value) types.Value {
+	return v.Fold(t.Def.Columns[col].Collation)
+}
+
+// checkValues is the per-column check shared by Insert and Update: no
+// column may hold NULL, and each value must have its column's type and fit
+// its VARCHAR(n) bound.
+func (t *Table) checkValues(values []types.Value) error {
+	for i, col := range t.Def.Columns {
+		val := values[i]
+		if val.IsNull() {
+			return fmt.Errorf("NULL not allowed for column %s", col.Name)
+		}
+		if val.Type != col.Type {
+			return fmt.Errorf("type mismatch for column %s: expected %s, got %s", col.Name, col.Type, val.Type)
+		}
+		if err := val.Check(); err != nil {
+			return fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		if err := checkLength(col, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// checkLength enforces a column's VARCHAR(n) bound, counted in characters.
+func checkLength(col schema.ColumnDef, val types.Value) error {
+	s, ok := val.Val.(string)
+	if col.MaxLength > 0 && ok && utf8.RuneCountInString(s) > col.MaxLength {
+		return fmt.Errorf("value too long for column %s: max %d characters", col.Name, col.MaxLength)
+	}
 	return nil
 }
 
+// PadAutoIncrement returns values with a NULL placeholder in the primary key
+// position when the table has an AUTO_INCREMENT key and it was omitted
+// (one value short). Insert replaces the placeholder with the next id.
+func (t *Table) PadAutoIncrement(values []types.Value) []types.Value {
+	pkCol, ok := t.Def.GetPrimaryKey()
+	if !ok || !pkCol.AutoIncrement || len(values) != len(t.Def.Columns)-1 {
+		return values
+	}
+	pkIdx := t.Def.GetColumnIndex(pkCol.Name)
+
+	full := make([]types.Value, 0, len(t.Def.Columns))
+	full = append(full, values[:pkIdx]...)
+	full = append(full, types.NewNull(""))
+	return append(full, values[pkIdx:]...)
+}
+
+// fillAutoIncrement assigns the next id when an AUTO_INCREMENT primary key is
+// omitted or NULL. Caller must hold t.mu.
+func (t *Table) fillAutoIncrement(values []types.Value) []types.Value {
+	values = t.PadAutoIncrement(values)
+
+	pkCol, ok := t.Def.GetPrimaryKey()
+	if !ok || !pkCol.AutoIncrement || len(values) != len(t.Def.Columns) {
+		return values
+	}
+	pkIdx := t.Def.GetColumnIndex(pkCol.Name)
+	if !values[pkIdx].IsNull() {
+		return values
+	}
+
+	// Copy so the caller's slice is left untouched
+	values = append([]types.Value(nil), values...)
+	values[pkIdx] = types.NewInt(t.lastAutoID + 1)
+	return values
+}
+
+// noteAutoID advances the AUTO_INCREMENT counter past pk. Caller must hold t.mu.
+func (t *Table) noteAutoID(pk interface{}) {
+	if id, ok := pk.(int); ok && id > t.lastAutoID {
+		t.lastAutoID = id
+	}
+}
+
 // Delete removes a row by Primary Key.
 func (t *Table) Delete(pk types.Value) error {
 	t.mu.Lock()
@@ -112,21 +260,41 @@ func (t *Table) Delete(pk types.Value) error {
 			idx, hasIdx := t.Indices[col.Name]
 			if hasIdx {
 				colIdx := t.Def.GetColumnIndex(col.Name)
-				idx.Delete(row.Values[colIdx])
+				idx.Delete(t.uniqueKey(colIdx, row.Values[colIdx]))
 			}
 		}
 	}
+	for colName, idx := range t.SecondaryIndices {
+		idx.Remove(row.Values[t.Def.GetColumnIndex(colName)], pk.Val)
+	}
 
 	// Remove from rows
 	delete(t.Rows, pk.Val)
+	if t.Def.InsertionOrder {
+		for i, p := range t.order {
+			if p == pk.Val {
+				t.order = append(t.order[:i], t.order[i+1:]...)
+				break
+			}
+		}
+	}
 	return nil
 }
 
-// Update modifies a row. Limitation: Updating PK is not supported.
+// Update modifies a row. A new primary key value moves the row to that
+// key, unless another row already has it.
 func (t *Table) Update(pk types.Value, newValues []types.Value) error {
+	if done, err := t.updateRow(pk, newValues); done {
+		return err
+	}
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	return t.update(pk, newValues)
+}
 
+// update is Update under the table lock, which the caller holds.
+func (t *Table) update(pk types.Value, newValues []types.Value) error {
 	// Check if row exists
 	oldRow, exists := t.Rows[pk.Val]
 	if !exists {
@@ -138,65 +306,183 @@ func (t *Table) Update(pk types.Value, newValues []types.Value) error {
 		return fmt.Errorf("column count mismatch")
 	}
 
-	// Check if PK is changing
+	if err := t.checkValues(newValues); err != nil {
+		return err
+	}
+
+	// A new primary key re-keys the row; checkUnique below rejects one
+	// that belongs to another row
 	pkCol, _ := t.Def.GetPrimaryKey()
 	pkIdx := t.Def.GetColumnIndex(pkCol.Name)
-	if newValues[pkIdx].Val != oldRow.Values[pkIdx].Val {
-		return fmt.Errorf("updating primary key is not supported")
+	newPK := newValues[pkIdx].Val
+
+	if err := t.checkUnique(newValues, pk.Val); err != nil {
+		return err
 	}
 
-	// Check Unique Constraints for changed values
+	// Update Indices (Remove old, Add new). Every entry points at the
+	// primary key, so a re-keyed row moves in all of them.
+	rekey := newPK != pk.Val
 	for i, col := range t.Def.Columns {
-		if col.IsUnique && !col.IsPrimary {
-			newVal := newValues[i]
-			oldVal := oldRow.Values[i]
-			if newVal.Val != oldVal.Val {
-				idx := t.Indices[col.Name]
-				if _, exists := idx.Get(newVal); exists {
-					return fmt.Errorf("duplicate unique value for %s", col.Name)
-				}
+		idx, ok := t.Indices[col.Name]
+		if ok && (rekey || newValues[i].Val != oldRow.Values[i].Val) {
+			idx.Delete(t.uniqueKey(i, oldRow.Values[i]))
+			idx.Set(t.uniqueKey(i, newValues[i]), newPK)
+		}
+	}
+	for colName, idx := range t.SecondaryIndices {
+		colIdx := t.Def.GetColumnIndex(colName)
+		if rekey || newValues[colIdx].Val != oldRow.Values[colIdx].Val {
+			idx.Remove(oldRow.Values[colIdx], pk.Val)
+			idx.Add(newValues[colIdx], newPK)
+		}
+	}
+
+	// Update Row
+	oldRow.Values = newValues
+	if rekey {
+		delete(t.Rows, pk.Val)
+		t.Rows[newPK] = oldRow
+		for i, p := range t.order {
+			if p == pk.Val {
+				t.order[i] = newPK
+				break
 			}
 		}
+		if pkCol.AutoIncrement {
+			t.noteAutoID(newPK)
+		}
 	}
+	return nil
+}
+
+// Upsert inserts values unless another row already holds the same value in
+// conflictCol, a primary key or UNIQUE column; then that row is replaced by
+// update's result instead. The lookup and the write happen under one lock,
+// so no other write can come between them. update must not use the table.
+// key is the inserted or updated row's primary key.
+func (t *Table) Upsert(values []types.Value, conflictCol string, update func(Row) ([]types.Value, error)) (key types.Value, inserted bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	// Update Indices (Remove old, Add new)
+	idx, ok := t.Indices[conflictCol]
+	if !ok {
+		return types.Value{}, false, fmt.Errorf("ON CONFLICT column %s is not a primary key or unique column", conflictCol)
+	}
+	colIdx := t.Def.GetColumnIndex(conflictCol)
+	if len(values) != len(t.Def.Columns) {
+		return types.Value{}, false, fmt.Errorf("column count mismatch: expected %d, got %d", len(t.Def.Columns), len(values))
+	}
+
+	owner, exists := idx.Get(t.uniqueKey(colIdx, values[colIdx]))
+	if !exists || values[colIdx].IsNull() {
+		key, err = t.insert(values)
+		return key, err == nil, err
+	}
+	newValues, err := update(Row{Values: append([]types.Value{}, t.Rows[owner].Values...)})
+	if err != nil {
+		return types.Value{}, false, err
+	}
+	pkCol, _ := t.Def.GetPrimaryKey()
+	if err := t.update(types.Value{Type: pkCol.Type, Val: owner}, newValues); err != nil {
+		return types.Value{}, false, err
+	}
+	return newValues[t.Def.GetColumnIndex(pkCol.Name)], false, nil
+}
+
+// updateRow is Update's fast path when row locks are on. A change that
+// leaves every indexed column alone touches nothing but its own row, so it
+// runs under the table's read lock plus that row's lock, alongside scans
+// and other point updates. done is false if Update must take the table
+// lock instead.
+func (t *Table) updateRow(pk types.Value, newValues []types.Value) (done bool, err error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.rowLocks == nil {
+		return false, nil
+	}
+	lock := t.rowLocks.of(pk.Val)
+	lock.Lock()
+	defer lock.Unlock()
+
+	row, exists := t.Rows[pk.Val]
+	if !exists {
+		return true, fmt.Errorf("row not found")
+	}
+	if len(newValues) != len(t.Def.Columns) {
+		return true, fmt.Errorf("column count mismatch")
+	}
 	for i, col := range t.Def.Columns {
-		if col.IsUnique && !col.IsPrimary {
-			newVal := newValues[i]
-			oldVal := oldRow.Values[i]
-			if newVal.Val != oldVal.Val {
-				idx := t.Indices[col.Name]
-				idx.Delete(oldVal)
-				idx.Set(newVal, pk.Val)
-			}
+		_, unique := t.Indices[col.Name]
+		_, secondary := t.SecondaryIndices[col.Name]
+		if (unique || secondary) && newValues[i].Val != row.Values[i].Val {
+			return false, nil
 		}
 	}
+	if err := t.checkValues(newValues); err != nil {
+		return true, err
+	}
+	row.Values = newValues
+	return true, nil
+}
 
-	// Update Row
-	t.Rows[pk.Val] = Row{Values: newValues}
-	return nil
+// rowAt returns the row stored under pk. The caller holds t.mu; with row
+// locks on, the row's own lock is taken too, since updateRow may be
+// replacing its values under a shared t.mu.
+func (t *Table) rowAt(pk interface{}) (Row, bool) {
+	r, ok := t.Rows[pk]
+	if !ok {
+		return Row{}, false
+	}
+	if t.rowLocks != nil {
+		lock := t.rowLocks.of(pk)
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+	return *r, true
 }
 
 // GetRow returns a copy of the row for the given PK. Safe for concurrency.
 func (t *Table) GetRow(pk interface{}) (Row, bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	r, ok := t.Rows[pk]
-	return r, ok
+	return t.rowAt(pk)
 }
 
-// Scan iterates over all rows safely. Stops if yield returns false.
+// GetRows returns copies of the rows for the given PKs, in the same order,
+// under a single read lock. Keys with no row are skipped.
+func (t *Table) GetRows(pks []interface{}) []Row {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rows := make([]Row, 0, len(pks))
+	for _, pk := range pks {
+		r, ok := t.rowAt(pk)
+		if !ok {
+			continue
+		}
+		values := make([]types.Value, len(r.Values))
+		copy(values, r.Values)
+		rows = append(rows, Row{Values: values})
+	}
+	return rows
+}
+
+// Scan iterates over all rows safely, in primary-key order. Stops if yield
+// returns false. The table is read-locked until Scan returns, so yield must
+// not modify it.
 func (t *Table) Scan(yield func(pk interface{}, row Row) bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	for k, v := range t.Rows {
-		if !yield(k, v) {
+	for _, pk := range t.sortedPKs() {
+		row, _ := t.rowAt(pk)
+		if !yield(pk, row) {
 			break
 		}
 	}
 }
 
-// IndexLookup returns PK for a given indexed value.
+// IndexLookup returns PK for a given indexed value, matched under the
+// column's collation.
 func (t *Table) IndexLookup(colName string, val types.Value) (interface{}, bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -204,29 +490,319 @@ func (t *Table) IndexLookup(colName string, val types.Value) (interface{}, bool)
 	if !ok {
 		return nil, false
 	}
-	return idx.Get(val)
+	return idx.Get(t.uniqueKey(t.Def.GetColumnIndex(colName), val))
+}
+
+// IndexLookupAll returns the PKs of every row whose indexed column equals val,
+// sorted by PK. It uses the unique index if there is one, which matches under
+// the column's collation, else the secondary index. ok is false if colName is
+// not indexed at all.
+func (t *Table) IndexLookupAll(colName string, val types.Value) (pks []interface{}, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if idx, ok := t.Indices[colName]; ok {
+		if pk, found := idx.Get(t.uniqueKey(t.Def.GetColumnIndex(colName), val)); found {
+			return []interface{}{pk}, true
+		}
+		return nil, true
+	}
+	if idx, ok := t.SecondaryIndices[colName]; ok {
+		pks := idx.Get(val)
+		pkCol, _ := t.Def.GetPrimaryKey()
+		sortPrimaryKeys(pks, pkCol.Type)
+		return pks, true
+	}
+	return nil, false
+}
+
+// IndexRange returns the PKs of every row whose indexed column lies in
+// [lo, hi), sorted by PK. A NULL lo or hi leaves that end of the range open.
+// ok is false if colName is not indexed.
+func (t *Table) IndexRange(colName string, lo, hi types.Value) (pks []interface{}, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	idx, ok := t.rangeIndex(colName)
+	if !ok {
+		return nil, false
+	}
+	pks = idx.Range(lo, hi)
+	pkCol, _ := t.Def.GetPrimaryKey()
+	sortPrimaryKeys(pks, pkCol.Type)
+	return pks, true
+}
+
+// IndexBounds returns the smallest and largest values of an indexed column,
+// read from the ends of its ordered index rather than by scanning. Both are
+// NULL for an empty table. ok is false if colName is not indexed.
+func (t *Table) IndexBounds(colName string) (lo, hi types.Value, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	idx, ok := t.rangeIndex(colName)
+	if !ok {
+		return types.Value{}, types.Value{}, false
+	}
+	lo, hi, found := idx.Bounds()
+	if !found {
+		col, _ := t.Def.GetColumn(colName)
+		return types.NewNull(col.Type), types.NewNull(col.Type), true
+	}
+	return lo, hi, true
+}
+
+// rangeIndex returns the unique or, failing that, secondary index on colName.
+// A unique index under a non-binary collation holds folded keys, not the
+// column's values, so it is not used for ranges.
+func (t *Table) rangeIndex(colName string) (index.RangeIndex, bool) {
+	if idx, ok := t.Indices[colName]; ok {
+		if col, _ := t.Def.GetColumn(colName); !col.Collation.IsBinary() {
+			return nil, false
+		}
+		return idx, true
+	}
+	if idx, ok := t.SecondaryIndices[colName]; ok {
+		return idx, true
+	}
+	return nil, false
+}
+
+// IndexCollation returns the collation under which IndexLookupAll matches
+// values of colName: the column's own for a unique index, whose keys are
+// folded by it, and binary for a secondary index. ok is false if colName
+// is not indexed.
+func (t *Table) IndexCollation(colName string) (coll types.Collation, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if _, ok := t.Indices[colName]; ok {
+		col, _ := t.Def.GetColumn(colName)
+		if col.Collation.IsBinary() {
+			return types.CollationBinary, true
+		}
+		return col.Collation, true
+	}
+	if _, ok := t.SecondaryIndices[colName]; ok {
+		return types.CollationBinary, true
+	}
+	return "", false
+}
+
+// HasRangeIndex reports whether IndexRange and IndexBounds can use an index
+// on colName. Its keys are in byte order.
+func (t *Table) HasRangeIndex(colName string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.rangeIndex(colName)
+	return ok
+}
+
+// HasIndex reports whether colName has a unique or secondary index.
+func (t *Table) HasIndex(colName string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, unique := t.Indices[colName]
+	_, secondary := t.SecondaryIndices[colName]
+	return unique || secondary
+}
+
+// RowCount returns the number of rows in the table. The row map tracks its
+// own length, so the count stays current across inserts and deletes without
+// a scan.
+func (t *Table) RowCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.Rows)
+}
+
+// Stats summarises a table for the planner's cost estimates.
+type Stats struct {
+	RowCount int
+	// Distinct is the number of distinct values in each indexed column.
+	Distinct map[string]int
+}
+
+// Stats returns the table's current statistics. The indices keep one entry
+// per distinct value up to date on every write, so this needs no scan.
+func (t *Table) Stats() Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	stats := Stats{RowCount: len(t.Rows), Distinct: make(map[string]int)}
+	for col, idx := range t.Indices {
+		stats.Distinct[col] = len(idx.Data)
+	}
+	for col, idx := range t.SecondaryIndices {
+		stats.Distinct[col] = len(idx.Data)
+	}
+	return stats
+}
+
+// ColumnStats describes the values stored in one column.
+type ColumnStats struct {
+	Name     string
+	Min, Max types.Value // NULL when the table is empty
+	Distinct int
+}
+
+// ColumnStats computes the min, max and distinct count of every column from
+// the current rows, in column order. Unlike Stats it scans the whole table.
+// TEXT min, max and distinct values follow the column's collation.
+func (t *Table) ColumnStats() []ColumnStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	stats := make([]ColumnStats, len(t.Def.Columns))
+	seen := make([]map[string]bool, len(t.Def.Columns))
+	for i, col := range t.Def.Columns {
+		stats[i] = ColumnStats{Name: col.Name, Min: types.NewNull(col.Type), Max: types.NewNull(col.Type)}
+		seen[i] = make(map[string]bool)
+	}
+	for pk := range t.Rows {
+		row, _ := t.rowAt(pk)
+		for i, v := range row.Values {
+			collation := t.Def.Columns[i].Collation
+			if key := v.Fold(collation).Hash(); !seen[i][key] {
+				seen[i][key] = true
+				stats[i].Distinct++
+			}
+			if c, err := v.CompareCollated(stats[i].Min, collation); stats[i].Min.IsNull() || (err == nil && c < 0) {
+				stats[i].Min = v
+			}
+			if c, err := v.CompareCollated(stats[i].Max, collation); stats[i].Max.IsNull() || (err == nil && c > 0) {
+				stats[i].Max = v
+			}
+		}
+	}
+	return stats
 }
 
 // GetSnapshot returns all rows sorted by primary key for deterministic results.
 func (t *Table) GetSnapshot() []Row {
+	_, rows := t.SortedSnapshot()
+	return rows
+}
+
+// SortedSnapshot returns primary keys and their rows in primary-key order.
+// pks[i] is the key of rows[i]; both are taken under a single read lock.
+func (t *Table) SortedSnapshot() ([]interface{}, []Row) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
-	// Collect all primary keys
+	// Build result in sorted order
+	pks := t.sortedPKs()
+	rows := make([]Row, 0, len(pks))
+	for _, pk := range pks {
+		row, _ := t.rowAt(pk)
+		rows = append(rows, row)
+	}
+	return pks, rows
+}
+
+// sortedPKs returns every primary key in order (numeric for INT keys,
+// byte-wise for TEXT). Caller must hold t.mu.
+func (t *Table) sortedPKs() []interface{} {
 	pks := make([]interface{}, 0, len(t.Rows))
 	for pk := range t.Rows {
 		pks = append(pks, pk)
 	}
-
-	// Sort primary keys for deterministic ordering
-	// We need to handle both INT and TEXT types
 	pkCol, _ := t.Def.GetPrimaryKey()
 	sortPrimaryKeys(pks, pkCol.Type)
+	return pks
+}
 
-	// Build result in sorted order
-	rows := make([]Row, 0, len(pks))
-	for _, pk := range pks {
-		rows = append(rows, t.Rows[pk])
+// InsertionSnapshot returns primary keys and their rows in the order the
+// rows were inserted. ok is false if the table does not track insertion
+// order (see schema.TableDef.InsertionOrder).
+func (t *Table) InsertionSnapshot() (pks []interface{}, rows []Row, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.Def.InsertionOrder {
+		return nil, nil, false
 	}
-	return rows
+	pks = make([]interface{}, len(t.order))
+	copy(pks, t.order)
+	rows = make([]Row, len(pks))
+	for i, pk := range pks {
+		rows[i], _ = t.rowAt(pk)
+	}
+	return pks, rows, true
+}
+
+// ScanSnapshot returns every row in the order a full scan visits them:
+// insertion order for tables that track it, otherwise primary-key order.
+func (t *Table) ScanSnapshot() []Row {
+	if _, rows, ok := t.InsertionSnapshot(); ok {
+		return rows
+	}
+	return t.GetSnapshot()
+}
+
+// RebuildIndex clears the index on colName and repopulates it from Rows.
+func (t *Table) RebuildIndex(colName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rebuildIndexLocked(colName)
+}
+
+// RebuildAllIndices repopulates every index from Rows. Used after bulk loads.
+func (t *Table) RebuildAllIndices() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for colName := range t.Indices {
+		if err := t.rebuildIndexLocked(colName); err != nil {
+			return err
+		}
+	}
+	for colName := range t.SecondaryIndices {
+		if err := t.rebuildIndexLocked(colName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compact rebuilds the row map, the insertion order and every index at
+// their current size. A Go map keeps the buckets it grew to after its
+// entries are deleted, so a table that once held many more rows goes on
+// holding their memory until it is compacted. Rows themselves are kept, not
+// copied.
+func (t *Table) Compact() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rows := make(map[interface{}]*Row, len(t.Rows))
+	for pk, row := range t.Rows {
+		rows[pk] = row
+	}
+	t.Rows = rows
+	if t.order != nil {
+		t.order = append(make([]interface{}, 0, len(t.order)), t.order...)
+	}
+	// Clear gives each index fresh maps, so refilling them is a rebuild
+	for colName := range t.Indices {
+		t.rebuildIndexLocked(colName)
+	}
+	for colName := range t.SecondaryIndices {
+		t.rebuildIndexLocked(colName)
+	}
+}
+
+// rebuildIndexLocked does the work for RebuildIndex. Caller must hold t.mu.
+func (t *Table) rebuildIndexLocked(colName string) error {
+	colIdx := t.Def.GetColumnIndex(colName)
+	if colIdx == -1 {
+		return fmt.Errorf("column not found: %s", colName)
+	}
+
+	if idx, ok := t.Indices[colName]; ok {
+		idx.Clear()
+		for pk, row := range t.Rows {
+			idx.Set(t.uniqueKey(colIdx, row.Values[colIdx]), pk)
+		}
+		return nil
+	}
+	if idx, ok := t.SecondaryIndices[colName]; ok {
+		idx.Clear()
+		for pk, row := range t.Rows {
+			idx.Add(row.Values[colIdx], pk)
+		}
+		return nil
+	}
+	return fmt.Errorf("no index on column %s", colName)
 }
@@ -0,0 +1,567 @@
+package storage
+
+import (
+	"fmt"
+	"mini-rdbms/db/schema"
+	"mini-rdbms/db/types"
+	"testing"
+)
+
+func newUsersTable(t *testing.T) *Table {
+	t.Helper()
+	tbl := NewTable(schema.TableDef{
+		Name: "users",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "email", Type: types.TypeText, IsUnique: true},
+		},
+	})
+	for i, email := range []string{"a@x.com", "b@x.com", "c@x.com"} {
+		vals := []types.Value{{Type: types.TypeInt, Val: i + 1}, {Type: types.TypeText, Val: email}}
+		if err := tbl.Insert(vals); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+	return tbl
+}
+
+func TestRebuildIndexRepairsDrift(t *testing.T) {
+	tbl := newUsersTable(t)
+	email := func(s string) types.Value { return types.Value{Type: types.TypeText, Val: s} }
+
+	// Corrupt the index: drop one entry and point another at the wrong row.
+	emailIdx := tbl.Indices["email"]
+	emailIdx.Delete(email("a@x.com"))
+	emailIdx.Set(email("b@x.com"), 3)
+	emailIdx.Set(email("ghost@x.com"), 99)
+
+	if _, ok := tbl.IndexLookup("email", email("a@x.com")); ok {
+		t.Fatalf("Expected corrupted index to miss a@x.com")
+	}
+
+	if err := tbl.RebuildIndex("email"); err != nil {
+		t.Fatalf("Failed to rebuild index: %v", err)
+	}
+
+	want := map[string]int{"a@x.com": 1, "b@x.com": 2, "c@x.com": 3}
+	for e, wantPK := range want {
+		pk, ok := tbl.IndexLookup("email", email(e))
+		if !ok || pk != wantPK {
+			t.Errorf("Lookup %s: expected pk %d, got %v (found=%v)", e, wantPK, pk, ok)
+		}
+	}
+	if _, ok := tbl.IndexLookup("email", email("ghost@x.com")); ok {
+		t.Errorf("Stale entry survived rebuild")
+	}
+
+	if err := tbl.RebuildIndex("missing"); err == nil {
+		t.Errorf("Expected error rebuilding a non-existent index")
+	}
+}
+
+func TestRebuildAllIndices(t *testing.T) {
+	tbl := newUsersTable(t)
+	for _, idx := range tbl.Indices {
+		idx.Clear()
+	}
+
+	if err := tbl.RebuildAllIndices(); err != nil {
+		t.Fatalf("Failed to rebuild indices: %v", err)
+	}
+	for name, idx := range tbl.Indices {
+		if len(idx.Data) != 3 {
+			t.Errorf("Index %s: expected 3 entries, got %d", name, len(idx.Data))
+		}
+	}
+}
+
+func TestSortedSnapshotPairsKeysAndRows(t *testing.T) {
+	tbl := NewTable(schema.TableDef{
+		Name: "nums",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "label", Type: types.TypeText},
+		},
+	})
+	for _, id := range []int{42, 7, 19, 3, 100, 1} {
+		vals := []types.Value{{Type: types.TypeInt, Val: id}, {Type: types.TypeText, Val: "row"}}
+		if err := tbl.Insert(vals); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	pks, rows := tbl.SortedSnapshot()
+	if len(pks) != 6 || len(rows) != 6 {
+		t.Fatalf("Expected 6 keys and rows, got %d and %d", len(pks), len(rows))
+	}
+	want := []int{1, 3, 7, 19, 42, 100}
+	for i, w := range want {
+		if pks[i] != w {
+			t.Errorf("Position %d: expected pk %d, got %v", i, w, pks[i])
+		}
+		if rows[i].Values[0].Val != pks[i] {
+			t.Errorf("Position %d: row pk %v does not match key %v", i, rows[i].Values[0].Val, pks[i])
+		}
+	}
+}
+
+func TestSnapshotAndScanSortTextKeys(t *testing.T) {
+	tbl := NewTable(schema.TableDef{
+		Name: "codes",
+		Columns: []schema.ColumnDef{
+			{Name: "code", Type: types.TypeText, IsPrimary: true},
+		},
+	})
+	for _, code := range []string{"m", "B", "a", "zz", "b", "Z", "ab"} {
+		if err := tbl.Insert([]types.Value{types.NewText(code)}); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+	// Byte order: upper case before lower case
+	want := "[B Z a ab b m zz]"
+
+	var scanned []interface{}
+	tbl.Scan(func(pk interface{}, row Row) bool {
+		if row.Values[0].Val != pk {
+			t.Errorf("Scan paired key %v with row %v", pk, row.Values)
+		}
+		scanned = append(scanned, pk)
+		return true
+	})
+	if got := fmt.Sprint(scanned); got != want {
+		t.Errorf("Scan order: expected %s, got %s", want, got)
+	}
+
+	var snapshot []interface{}
+	for _, row := range tbl.GetSnapshot() {
+		snapshot = append(snapshot, row.Values[0].Val)
+	}
+	if got := fmt.Sprint(snapshot); got != want {
+		t.Errorf("Snapshot order: expected %s, got %s", want, got)
+	}
+
+	// Scan stops as soon as yield says so
+	n := 0
+	tbl.Scan(func(interface{}, Row) bool { n++; return n < 3 })
+	if n != 3 {
+		t.Errorf("Expected Scan to stop after 3 rows, got %d", n)
+	}
+}
+
+func TestGetRows(t *testing.T) {
+	tbl := newUsersTable(t)
+
+	rows := tbl.GetRows([]interface{}{3, 99, 1, "x"})
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].Values[1].Val != "c@x.com" || rows[1].Values[1].Val != "a@x.com" {
+		t.Errorf("Expected rows 3 and 1 in request order, got %v", rows)
+	}
+
+	// The rows are copies; changing them leaves the table alone
+	rows[0].Values[1] = types.Value{Type: types.TypeText, Val: "changed"}
+	if row, _ := tbl.GetRow(3); row.Values[1].Val != "c@x.com" {
+		t.Errorf("Expected stored row to be unchanged, got %v", row.Values[1])
+	}
+
+	if rows := tbl.GetRows(nil); len(rows) != 0 {
+		t.Errorf("Expected no rows for no keys, got %d", len(rows))
+	}
+}
+
+func TestInsertBatch(t *testing.T) {
+	tbl := newUsersTable(t)
+	row := func(id int, email string) []types.Value {
+		return []types.Value{types.NewInt(id), types.NewText(email)}
+	}
+
+	keys, errs := tbl.InsertBatch([][]types.Value{
+		row(4, "d@x.com"),
+		row(2, "new@x.com"), // duplicate primary key
+		row(5, "a@x.com"),   // duplicate unique email
+		row(6, "d@x.com"),   // duplicates a row earlier in the batch
+		{types.NewInt(7)},   // too few values
+		row(8, "e@x.com"),
+	})
+	if len(keys) != 6 || len(errs) != 6 {
+		t.Fatalf("Expected results for 6 rows, got %d keys and %d errors", len(keys), len(errs))
+	}
+	for i, wantErr := range []bool{false, true, true, true, true, false} {
+		if (errs[i] != nil) != wantErr {
+			t.Errorf("Row %d: error = %v, want error %v", i, errs[i], wantErr)
+		}
+	}
+	if keys[0].Val != 4 || keys[5].Val != 8 {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+
+	// Good rows are in and indexed; failed ones left nothing behind
+	if got := tbl.RowCount(); got != 5 {
+		t.Errorf("Expected 5 rows, got %d", got)
+	}
+	if pk, ok := tbl.IndexLookup("email", types.NewText("e@x.com")); !ok || pk != 8 {
+		t.Errorf("Expected e@x.com indexed to 8, got %v, %v", pk, ok)
+	}
+	if _, ok := tbl.GetRow(6); ok {
+		t.Errorf("Expected row 6 to be rejected")
+	}
+}
+
+func TestStatsTrackDistinctValues(t *testing.T) {
+	tbl := newUsersTable(t)
+	if err := tbl.Delete(types.NewInt(2)); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	stats := tbl.Stats()
+	if stats.RowCount != 2 || stats.Distinct["id"] != 2 || stats.Distinct["email"] != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if _, ok := stats.Distinct["missing"]; ok {
+		t.Errorf("Expected no entry for an unindexed column")
+	}
+}
+
+func TestRowCountTracksMutations(t *testing.T) {
+	tbl := newUsersTable(t)
+	if got := tbl.RowCount(); got != 3 {
+		t.Fatalf("Expected 3 rows, got %d", got)
+	}
+
+	id := func(i int) types.Value { return types.Value{Type: types.TypeInt, Val: i} }
+	if err := tbl.Delete(id(2)); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if err := tbl.Insert([]types.Value{id(4), {Type: types.TypeText, Val: "d@x.com"}}); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := tbl.Insert([]types.Value{id(5), {Type: types.TypeText, Val: "e@x.com"}}); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := tbl.Delete(id(1)); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	// Failed mutations leave the count alone.
+	if err := tbl.Insert([]types.Value{id(3), {Type: types.TypeText, Val: "dup@x.com"}}); err == nil {
+		t.Fatalf("Expected duplicate key error")
+	}
+	if err := tbl.Delete(id(99)); err == nil {
+		t.Fatalf("Expected missing row error")
+	}
+
+	if got := tbl.RowCount(); got != 3 {
+		t.Errorf("Expected 3 rows after mixed mutations, got %d", got)
+	}
+}
+
+func TestVarcharMaxLength(t *testing.T) {
+	tbl := NewTable(schema.TableDef{
+		Name: "codes",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "code", Type: types.TypeText, MaxLength: 3},
+		},
+	})
+	id := types.Value{Type: types.TypeInt, Val: 1}
+	text := func(s string) types.Value { return types.Value{Type: types.TypeText, Val: s} }
+
+	// The bound counts characters, not bytes.
+	if err := tbl.Insert([]types.Value{id, text("äöü")}); err != nil {
+		t.Fatalf("Failed to insert value at the limit: %v", err)
+	}
+	if err := tbl.Insert([]types.Value{{Type: types.TypeInt, Val: 2}, text("abcd")}); err == nil {
+		t.Errorf("Expected insert over the limit to fail")
+	}
+	if err := tbl.Update(id, []types.Value{id, text("abcd")}); err == nil {
+		t.Errorf("Expected update over the limit to fail")
+	}
+}
+
+func TestIndexLookupMatchesFloatDecodedInt(t *testing.T) {
+	tbl := NewTable(schema.TableDef{
+		Name: "orders",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "user_id", Type: types.TypeInt},
+		},
+		Indexes: []string{"user_id"},
+	})
+	for _, id := range []int{1, 2, 3} {
+		if err := tbl.Insert([]types.Value{types.NewInt(id), types.NewInt(id % 2)}); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	// A lookup value that came through a generic JSON decode finds the
+	// same entries as the int it stands for
+	asFloat := func(f float64) types.Value { return types.Value{Type: types.TypeInt, Val: f} }
+	if pk, ok := tbl.IndexLookup("id", asFloat(2)); !ok || pk != 2 {
+		t.Errorf("Expected id 2.0 to find row 2, got %v, %v", pk, ok)
+	}
+	if pks, _ := tbl.IndexLookupAll("user_id", asFloat(1)); fmt.Sprint(pks) != "[1 3]" {
+		t.Errorf("Expected user_id 1.0 to find rows 1 and 3, got %v", pks)
+	}
+	// Other types and fractions stay distinct
+	if _, ok := tbl.IndexLookup("id", types.NewText("2")); ok {
+		t.Errorf("Expected TEXT '2' not to match INT 2")
+	}
+	if _, ok := tbl.IndexLookup("id", asFloat(2.5)); ok {
+		t.Errorf("Expected 2.5 not to match 2")
+	}
+}
+
+func TestInsertRejectsMismatchedGoType(t *testing.T) {
+	tbl := newUsersTable(t)
+	// Type says INT but Val is a float, as a careless JSON decode would give.
+	vals := []types.Value{{Type: types.TypeInt, Val: 4.0}, types.NewText("d@x.com")}
+	if err := tbl.Insert(vals); err == nil {
+		t.Errorf("Expected insert with a float INT value to fail")
+	}
+}
+
+func TestInsertionOrderSnapshot(t *testing.T) {
+	def := schema.TableDef{
+		Name: "logs",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "msg", Type: types.TypeText},
+		},
+		InsertionOrder: true,
+	}
+	tbl := NewTable(def)
+	for _, id := range []int{30, 10, 20, 5} {
+		if err := tbl.Insert([]types.Value{types.NewInt(id), types.NewText("m")}); err != nil {
+			t.Fatalf("Failed to insert %d: %v", id, err)
+		}
+	}
+	if err := tbl.Delete(types.NewInt(10)); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if err := tbl.Insert([]types.Value{types.NewInt(1), types.NewText("m")}); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	want := []int{30, 20, 5, 1}
+	check := func(label string, tbl *Table) {
+		t.Helper()
+		pks, rows, ok := tbl.InsertionSnapshot()
+		if !ok {
+			t.Fatalf("%s: expected insertion order to be tracked", label)
+		}
+		if len(pks) != len(want) {
+			t.Fatalf("%s: expected %d rows, got %v", label, len(want), pks)
+		}
+		for i, id := range want {
+			if pks[i] != id || rows[i].Values[0].Val != id {
+				t.Errorf("%s: position %d: expected %d, got pk %v row %v", label, i, id, pks[i], rows[i].Values[0].Val)
+			}
+		}
+	}
+	check("in memory", tbl)
+
+	dir := t.TempDir()
+	if err := SaveTable(dir, tbl); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	loaded, err := LoadTable(dir, "logs")
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	check("after reload", loaded)
+
+	// Tables without the option keep no order list.
+	def.InsertionOrder = false
+	if _, _, ok := NewTable(def).InsertionSnapshot(); ok {
+		t.Errorf("Expected no insertion order without the table option")
+	}
+}
+
+func TestUniqueViolationsMatchOnInsertAndUpdate(t *testing.T) {
+	tbl := newUsersTable(t)
+	row := func(id int, email string) []types.Value {
+		return []types.Value{types.NewInt(id), types.NewText(email)}
+	}
+
+	// Each case collides with an existing row the same way on both paths.
+	cases := []struct {
+		name   string
+		insert []types.Value
+		update []types.Value // applied to row 3
+		want   string
+	}{
+		{"unique column", row(9, "a@x.com"), row(3, "a@x.com"), "duplicate unique value for column email: a@x.com"},
+		{"primary key", row(1, "new@x.com"), nil, "duplicate primary key: 1"},
+	}
+	for _, c := range cases {
+		err := tbl.Insert(c.insert)
+		if err == nil || err.Error() != c.want {
+			t.Errorf("%s: insert error = %v, want %q", c.name, err, c.want)
+		}
+		if c.update == nil {
+			continue
+		}
+		err = tbl.Update(types.NewInt(3), c.update)
+		if err == nil || err.Error() != c.want {
+			t.Errorf("%s: update error = %v, want %q", c.name, err, c.want)
+		}
+	}
+
+	// A row keeping its own unique value is not a conflict, and a freed
+	// value can be taken by another row.
+	if err := tbl.Update(types.NewInt(3), row(3, "c@x.com")); err != nil {
+		t.Fatalf("Update keeping own value: %v", err)
+	}
+	if err := tbl.Update(types.NewInt(3), row(3, "d@x.com")); err != nil {
+		t.Fatalf("Update to a free value: %v", err)
+	}
+	if err := tbl.Insert(row(4, "c@x.com")); err != nil {
+		t.Fatalf("Insert of a freed value: %v", err)
+	}
+	if pk, ok := tbl.IndexLookup("email", types.NewText("d@x.com")); !ok || pk != 3 {
+		t.Errorf("Expected d@x.com to index row 3, got %v (found=%v)", pk, ok)
+	}
+}
+
+func TestColumnStats(t *testing.T) {
+	tbl := NewTable(schema.TableDef{
+		Name: "scores",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "points", Type: types.TypeInt},
+			{Name: "name", Type: types.TypeText, Collation: types.CollationNoCase},
+		},
+	})
+	for _, s := range [][]interface{}{{1, 40, "bob"}, {2, -5, "Al"}, {3, 40, "cy"}, {4, 12, "BOB"}} {
+		vals := []types.Value{types.NewInt(s[0].(int)), types.NewInt(s[1].(int)), types.NewText(s[2].(string))}
+		if err := tbl.Insert(vals); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	stats := tbl.ColumnStats()
+	points := stats[1]
+	if points.Name != "points" || points.Min != types.NewInt(-5) || points.Max != types.NewInt(40) || points.Distinct != 3 {
+		t.Errorf("Unexpected points stats: %+v", points)
+	}
+	// NOCASE orders Al < bob < cy, and bob and BOB are one value
+	if name := stats[2]; name.Min.Val != "Al" || name.Max.Val != "cy" || name.Distinct != 3 {
+		t.Errorf("Unexpected name stats: %+v", name)
+	}
+
+	empty := NewTable(tbl.Def).ColumnStats()
+	if !empty[1].Min.IsNull() || !empty[1].Max.IsNull() || empty[1].Distinct != 0 {
+		t.Errorf("Expected NULL min/max for an empty table, got %+v", empty[1])
+	}
+}
+
+func TestUpdateRekeysRow(t *testing.T) {
+	def := schema.TableDef{
+		Name: "logs",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "code", Type: types.TypeText, IsUnique: true},
+			{Name: "level", Type: types.TypeText},
+		},
+		Indexes:        []string{"level"},
+		InsertionOrder: true,
+	}
+	tbl := NewTable(def)
+	for i, id := range []int{3, 1, 2} {
+		if err := tbl.Insert([]types.Value{types.NewInt(id), types.NewText(fmt.Sprintf("c%d", id)), types.NewText("info")}); err != nil {
+			t.Fatalf("Failed to insert row %d: %v", i, err)
+		}
+	}
+
+	if err := tbl.Update(types.NewInt(1), []types.Value{types.NewInt(9), types.NewText("c1"), types.NewText("warn")}); err != nil {
+		t.Fatalf("Failed to re-key: %v", err)
+	}
+	if _, ok := tbl.GetRow(1); ok {
+		t.Errorf("Expected no row under the old key")
+	}
+	if row, ok := tbl.GetRow(9); !ok || row.Values[2].Val != "warn" {
+		t.Errorf("Expected the updated row under key 9, got %v (found=%v)", row, ok)
+	}
+	if pk, ok := tbl.IndexLookup("code", types.NewText("c1")); !ok || pk != 9 {
+		t.Errorf("Expected code c1 to point at 9, got %v", pk)
+	}
+	if pk, ok := tbl.IndexLookup("id", types.NewInt(9)); !ok || pk != 9 {
+		t.Errorf("Expected primary key index entry for 9, got %v", pk)
+	}
+	if pks := tbl.SecondaryIndices["level"].Get(types.NewText("warn")); len(pks) != 1 || pks[0] != 9 {
+		t.Errorf("Expected level warn to point at 9, got %v", pks)
+	}
+	if pks, _, _ := tbl.InsertionSnapshot(); fmt.Sprint(pks) != "[3 9 2]" {
+		t.Errorf("Expected the row to keep its insertion position, got %v", pks)
+	}
+
+	if err := tbl.Update(types.NewInt(9), []types.Value{types.NewInt(2), types.NewText("c1"), types.NewText("warn")}); err == nil {
+		t.Errorf("Expected a collision with key 2 to be rejected")
+	}
+	if err := tbl.Update(types.NewInt(9), []types.Value{types.NewNull(types.TypeInt), types.NewText("c1"), types.NewText("warn")}); err == nil {
+		t.Errorf("Expected a NULL key to be rejected")
+	}
+	if _, ok := tbl.GetRow(9); !ok || tbl.RowCount() != 3 {
+		t.Errorf("Expected failed re-keys to leave the table alone")
+	}
+}
+
+func TestCompactKeepsData(t *testing.T) {
+	tbl := NewTable(schema.TableDef{
+		Name: "logs",
+		Columns: []schema.ColumnDef{
+			{Name: "id", Type: types.TypeInt, IsPrimary: true},
+			{Name: "code", Type: types.TypeText, IsUnique: true},
+			{Name: "level", Type: types.TypeText},
+		},
+		Indexes:        []string{"level"},
+		InsertionOrder: true,
+	})
+	for id := 1000; id >= 1; id-- {
+		level := "info"
+		if id%2 == 0 {
+			level = "warn"
+		}
+		if err := tbl.Insert([]types.Value{types.NewInt(id), types.NewText(fmt.Sprintf("c%d", id)), types.NewText(level)}); err != nil {
+			t.Fatalf("Failed to insert %d: %v", id, err)
+		}
+	}
+	for id := 1; id <= 1000; id++ {
+		if id%100 == 0 || id == 7 {
+			continue
+		}
+		if err := tbl.Delete(types.NewInt(id)); err != nil {
+			t.Fatalf("Failed to delete %d: %v", id, err)
+		}
+	}
+	wantPKs, wantRows, _ := tbl.InsertionSnapshot()
+
+	tbl.Compact()
+
+	pks, rows, _ := tbl.InsertionSnapshot()
+	if fmt.Sprint(pks) != fmt.Sprint(wantPKs) || fmt.Sprint(rows) != fmt.Sprint(wantRows) {
+		t.Fatalf("Expected rows %v in insertion order %v, got %v in %v", wantRows, wantPKs, rows, pks)
+	}
+	if tbl.RowCount() != 11 {
+		t.Errorf("Expected 11 rows, got %d", tbl.RowCount())
+	}
+	if pk, ok := tbl.IndexLookup("code", types.NewText("c700")); !ok || pk != 700 {
+		t.Errorf("Expected code c700 to point at 700, got %v (found=%v)", pk, ok)
+	}
+	if _, ok := tbl.IndexLookup("code", types.NewText("c8")); ok {
+		t.Errorf("Expected no index entry for deleted code c8")
+	}
+	if pks, _ := tbl.IndexLookupAll("level", types.NewText("info")); fmt.Sprint(pks) != "[7]" {
+		t.Errorf("Expected level info to point at [7], got %v", pks)
+	}
+	if pks, _ := tbl.IndexRange("id", types.NewInt(150), types.NewInt(450)); fmt.Sprint(pks) != "[200 300 400]" {
+		t.Errorf("Expected ids [200 300 400] in range, got %v", pks)
+	}
+
+	// The table stays usable: keys freed by deletes can be reused, taken ones cannot
+	if err := tbl.Insert([]types.Value{types.NewInt(8), types.NewText("c8"), types.NewText("info")}); err != nil {
+		t.Errorf("Failed to insert after compaction: %v", err)
+	}
+	if err := tbl.Insert([]types.Value{types.NewInt(9), types.NewText("c700"), types.NewText("info")}); err == nil {
+		t.Errorf("Expected a duplicate code to be rejected after compaction")
+	}
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Collation controls how TEXT values are ordered and compared.
+type Collation string
+
+const (
+	// CollationBinary compares strings byte by byte. This is the default.
+	CollationBinary Collation = "BINARY"
+	// CollationNoCase compares strings after lowercasing both sides.
+	CollationNoCase Collation = "NOCASE"
+	// CollationUnicode uses the Unicode Collation Algorithm (root locale),
+	// so accented letters sort next to their base letter.
+	CollationUnicode Collation = "UNICODE"
+)
+
+// unicodeCollator is shared; a collate.Collator is not safe for concurrent use.
+var (
+	unicodeMu       sync.Mutex
+	unicodeCollator = collate.New(language.Und)
+)
+
+// ParseCollation resolves a collation name (case-insensitive).
+func ParseCollation(name string) (Collation, error) {
+	switch c := Collation(strings.ToUpper(name)); c {
+	case CollationBinary, CollationNoCase, CollationUnicode:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown collation: %s", name)
+}
+
+// IsBinary reports whether c compares raw bytes. The empty collation is binary.
+func (c Collation) IsBinary() bool {
+	return c == "" || c == CollationBinary
+}
+
+// CompareStrings returns -1, 0 or 1 ordering a and b under the collation.
+func (c Collation) CompareStrings(a, b string) int {
+	switch c {
+	case CollationNoCase:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	case CollationUnicode:
+		unicodeMu.Lock()
+		defer unicodeMu.Unlock()
+		return unicodeCollator.CompareString(a, b)
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// Key returns a string that is the same for a and b exactly when
+// CompareStrings(a, b) is 0, so values equal under c can share a map key.
+func (c Collation) Key(s string) string {
+	switch c {
+	case CollationNoCase:
+		return strings.ToLower(s)
+	case CollationUnicode:
+		unicodeMu.Lock()
+		defer unicodeMu.Unlock()
+		return string(unicodeCollator.KeyFromString(&collate.Buffer{}, s))
+	default:
+		return s
+	}
+}
+
+// Fold returns v with a TEXT value replaced by its Key under c, so values
+// equal under c have equal Hashes. Other values are returned unchanged.
+func (v Value) Fold(c Collation) Value {
+	s, ok := v.Val.(string)
+	if c.IsBinary() || !ok {
+		return v
+	}
+	return NewText(c.Key(s))
+}
@@ -0,0 +1,62 @@
+package types
+
+import "testing"
+
+func TestCompareCollated(t *testing.T) {
+	text := func(s string) Value { return Value{Type: TypeText, Val: s} }
+
+	tests := []struct {
+		a, b string
+		coll Collation
+		want int
+	}{
+		// Binary: uppercase sorts before lowercase, accents after ASCII.
+		{"Zebra", "apple", CollationBinary, -1},
+		{"alice", "Alice", CollationBinary, 1},
+		{"été", "ete", CollationBinary, 1},
+		{"été", "f", CollationBinary, 1},
+		// NOCASE: case folded, accents still distinct.
+		{"Zebra", "apple", CollationNoCase, 1},
+		{"alice", "ALICE", CollationNoCase, 0},
+		// UNICODE: accented letters sort with their base letter.
+		{"Zebra", "apple", CollationUnicode, 1},
+		{"été", "f", CollationUnicode, -1},
+		{"Émile", "Eric", CollationUnicode, -1},
+	}
+
+	for _, tt := range tests {
+		got, err := text(tt.a).CompareCollated(text(tt.b), tt.coll)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.coll, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: compare(%q, %q) = %d, want %d", tt.coll, tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// Compare stays byte-wise.
+	if got, _ := text("alice").Compare(text("Alice")); got != 1 {
+		t.Errorf("Compare should remain byte-wise, got %d", got)
+	}
+}
+
+func TestParseCollation(t *testing.T) {
+	if c, err := ParseCollation("nocase"); err != nil || c != CollationNoCase {
+		t.Errorf("Expected NOCASE, got %q (%v)", c, err)
+	}
+	if _, err := ParseCollation("klingon"); err == nil {
+		t.Errorf("Expected error for unknown collation")
+	}
+}
+
+func TestCollationKey(t *testing.T) {
+	pairs := [][2]string{{"alice", "ALICE"}, {"Zebra", "apple"}, {"été", "ete"}, {"Émile", "émile"}}
+	for _, coll := range []Collation{CollationBinary, CollationNoCase, CollationUnicode} {
+		for _, p := range pairs {
+			same := coll.Key(p[0]) == coll.Key(p[1])
+			if want := coll.CompareStrings(p[0], p[1]) == 0; same != want {
+				t.Errorf("%s: keys of %q and %q equal = %v, want %v", coll, p[0], p[1], same, want)
+			}
+		}
+	}
+}
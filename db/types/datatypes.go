@@ -1,7 +1,13 @@
 package types
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
 )
 
 // DataType represents the supported SQL types.
@@ -10,16 +16,134 @@ type DataType string
 const (
 	TypeInt  DataType = "INT"
 	TypeText DataType = "TEXT"
+	// TypeDate is a calendar day, held as a time.Time at midnight UTC.
+	TypeDate DataType = "DATE"
+	// TypeNumeric is an integer of any size, held as a *big.Int. Columns
+	// cannot be declared NUMERIC; it is what INT arithmetic and SUM produce
+	// when overflow is set to promote rather than fail.
+	TypeNumeric DataType = "NUMERIC"
 )
 
+// DateLayout is how DATE values are written in SQL literals and on disk.
+const DateLayout = "2006-01-02"
+
 // Value holds the dynamic data for a cell.
-// In a real DB we might use a custom tagging/serialization,
-// but for this mini-RDBMS `interface{}` is sufficient and idiomatic enough for the scope.
+// Val is an `interface{}`; MarshalJSON/UnmarshalJSON keep the Type tag next to
+// it so the concrete Go type survives a round-trip through disk.
 type Value struct {
 	Type DataType
 	Val  interface{}
 }
 
+// NewInt returns an INT value.
+func NewInt(i int) Value {
+	return Value{Type: TypeInt, Val: i}
+}
+
+// NewText returns a TEXT value.
+func NewText(s string) Value {
+	return Value{Type: TypeText, Val: s}
+}
+
+// NewDate returns a DATE value for the calendar day of t in t's location.
+func NewDate(t time.Time) Value {
+	return Value{Type: TypeDate, Val: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// NewNumeric returns a NUMERIC value. n is not copied.
+func NewNumeric(n *big.Int) Value {
+	return Value{Type: TypeNumeric, Val: n}
+}
+
+// ParseDate reads a YYYY-MM-DD date as a DATE value.
+func ParseDate(s string) (Value, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Value{}, fmt.Errorf("invalid DATE %q: expected YYYY-MM-DD", s)
+	}
+	return NewDate(t), nil
+}
+
+// NewNull returns a NULL of type t. An empty t is an untyped NULL, as the
+// parser produces for the NULL literal.
+func NewNull(t DataType) Value {
+	return Value{Type: t}
+}
+
+// NewValue builds a value of type t from a Go value, so Val always holds the
+// Go type Check expects. Other integer types and integral floats (as JSON
+// decoding produces) are normalized to int; anything that cannot be
+// represented exactly is rejected. nil yields NULL.
+func NewValue(t DataType, v interface{}) (Value, error) {
+	if v == nil {
+		return NewNull(t), nil
+	}
+	switch t {
+	case TypeInt:
+		switch n := v.(type) {
+		case int:
+			return NewInt(n), nil
+		case int8:
+			return NewInt(int(n)), nil
+		case int16:
+			return NewInt(int(n)), nil
+		case int32:
+			return NewInt(int(n)), nil
+		case int64:
+			if int64(int(n)) != n {
+				return Value{}, fmt.Errorf("INT value out of range: %d", n)
+			}
+			return NewInt(int(n)), nil
+		case uint8:
+			return NewInt(int(n)), nil
+		case uint16:
+			return NewInt(int(n)), nil
+		case uint32:
+			return NewValue(t, int64(n))
+		case uint:
+			return NewValue(t, uint64(n))
+		case uint64:
+			if n > math.MaxInt {
+				return Value{}, fmt.Errorf("INT value out of range: %d", n)
+			}
+			return NewInt(int(n)), nil
+		case float32:
+			return NewValue(t, float64(n))
+		case float64:
+			if n != math.Trunc(n) || n < math.MinInt || n >= math.MaxInt {
+				return Value{}, fmt.Errorf("INT value must be a whole number in range, got %v", n)
+			}
+			return NewInt(int(n)), nil
+		}
+		return Value{}, fmt.Errorf("cannot use %T as INT", v)
+	case TypeText:
+		switch s := v.(type) {
+		case string:
+			return NewText(s), nil
+		case []byte:
+			return NewText(string(s)), nil
+		}
+		return Value{}, fmt.Errorf("cannot use %T as TEXT", v)
+	case TypeDate:
+		switch d := v.(type) {
+		case time.Time:
+			return NewDate(d), nil
+		case string:
+			return ParseDate(d)
+		}
+		return Value{}, fmt.Errorf("cannot use %T as DATE", v)
+	case TypeNumeric:
+		switch n := v.(type) {
+		case *big.Int:
+			return NewNumeric(n), nil
+		case int:
+			return NewNumeric(big.NewInt(int64(n))), nil
+		}
+		return Value{}, fmt.Errorf("cannot use %T as NUMERIC", v)
+	}
+	return Value{}, fmt.Errorf("unknown type: %s", t)
+}
+
 // Check verifies if the internal Val matches the Type.
 func (v Value) Check() error {
 	switch v.Type {
@@ -33,12 +157,25 @@ func (v Value) Check() error {
 		if _, ok := v.Val.(string); !ok {
 			return fmt.Errorf("expected TEXT, got type %T", v.Val)
 		}
+	case TypeDate:
+		if _, ok := v.Val.(time.Time); !ok {
+			return fmt.Errorf("expected DATE, got type %T", v.Val)
+		}
+	case TypeNumeric:
+		if _, ok := v.Val.(*big.Int); !ok {
+			return fmt.Errorf("expected NUMERIC, got type %T", v.Val)
+		}
 	default:
 		return fmt.Errorf("unknown type: %s", v.Type)
 	}
 	return nil
 }
 
+// IsNull reports whether the value is SQL NULL.
+func (v Value) IsNull() bool {
+	return v.Val == nil
+}
+
 // String returns a string representation of the value.
 func (v Value) String() string {
 	if v.Val == nil {
@@ -49,10 +186,42 @@ func (v Value) String() string {
 		return fmt.Sprintf("%d", v.Val)
 	case TypeText:
 		return fmt.Sprintf("%s", v.Val)
+	case TypeDate:
+		if d, ok := v.Val.(time.Time); ok {
+			return d.Format(DateLayout)
+		}
 	}
 	return fmt.Sprintf("%v", v.Val)
 }
 
+// Hash returns a canonical key for v, for use in maps. Values that are equal
+// under binary comparison get the same key and values of different types
+// never do, except that a NUMERIC hashes like the INT it equals. An INT
+// held as a whole float64, as a generic JSON decode leaves it, hashes like
+// the int on purpose; a fractional one is kept distinct.
+// Every NULL hashes to "NULL", which no non-NULL value produces.
+func (v Value) Hash() string {
+	if v.IsNull() {
+		return "NULL"
+	}
+	switch val := v.Val.(type) {
+	case int:
+		return string(v.Type) + ":" + strconv.Itoa(val)
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return string(v.Type) + ":" + strconv.Itoa(int(val))
+		}
+		return string(v.Type) + ":" + strconv.FormatFloat(val, 'g', -1, 64)
+	case string:
+		return string(v.Type) + ":" + val
+	case time.Time:
+		return string(v.Type) + ":" + val.Format(DateLayout)
+	case *big.Int:
+		return string(TypeInt) + ":" + val.String()
+	}
+	return fmt.Sprintf("%s:%T:%v", v.Type, v.Val, v.Val)
+}
+
 // AsInt attempts to return the value as int.
 func (v Value) AsInt() (int, error) {
 	if v.Type != TypeInt {
@@ -81,11 +250,61 @@ func (v Value) AsText() (string, error) {
 	return s, nil
 }
 
+// AsDate returns the value as a time.Time at midnight UTC.
+func (v Value) AsDate() (time.Time, error) {
+	if v.Type != TypeDate {
+		return time.Time{}, fmt.Errorf("not a DATE")
+	}
+	d, ok := v.Val.(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("val is not time: %v", v.Val)
+	}
+	return d, nil
+}
+
+// AsNumeric returns an INT or NUMERIC value as a *big.Int, which the caller
+// must not modify.
+func (v Value) AsNumeric() (*big.Int, error) {
+	switch v.Type {
+	case TypeInt:
+		i, err := v.AsInt()
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(int64(i)), nil
+	case TypeNumeric:
+		n, ok := v.Val.(*big.Int)
+		if !ok {
+			return nil, fmt.Errorf("val is not *big.Int: %v", v.Val)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("not a number")
+}
+
 // Compare returns -1 if v < other, 0 if v == other, 1 if v > other.
+// TEXT values are compared byte-wise and DATE values chronologically.
+// An INT and a NUMERIC compare by value.
 func (v Value) Compare(other Value) (int, error) {
-	if v.Type != other.Type {
+	return v.CompareCollated(other, CollationBinary)
+}
+
+// CompareCollated is like Compare but orders TEXT values using the given collation.
+func (v Value) CompareCollated(other Value, c Collation) (int, error) {
+	if v.Type != other.Type && !(isInteger(v.Type) && isInteger(other.Type)) {
 		return 0, fmt.Errorf("type mismatch: %s vs %s", v.Type, other.Type)
 	}
+	if v.Type == TypeNumeric || other.Type == TypeNumeric {
+		a, err := v.AsNumeric()
+		if err != nil {
+			return 0, err
+		}
+		b, err := other.AsNumeric()
+		if err != nil {
+			return 0, err
+		}
+		return a.Cmp(b), nil
+	}
 	switch v.Type {
 	case TypeInt:
 		i1, _ := v.AsInt()
@@ -100,13 +319,85 @@ func (v Value) Compare(other Value) (int, error) {
 	case TypeText:
 		s1, _ := v.AsText()
 		s2, _ := other.AsText()
-		if s1 < s2 {
-			return -1, nil
+		return c.CompareStrings(s1, s2), nil
+	case TypeDate:
+		d1, _ := v.AsDate()
+		d2, _ := other.AsDate()
+		return d1.Compare(d2), nil
+	}
+	return 0, fmt.Errorf("unsupported comparison type: %s", v.Type)
+}
+
+// isInteger reports whether t holds whole numbers.
+func isInteger(t DataType) bool {
+	return t == TypeInt || t == TypeNumeric
+}
+
+// jsonValue is the on-disk shape of a Value. Val is kept raw so it can be
+// decoded according to Type instead of as a generic interface{}.
+type jsonValue struct {
+	Type DataType
+	Val  json.RawMessage
+}
+
+// MarshalJSON encodes the value together with its type tag. A DATE is
+// written as YYYY-MM-DD text.
+func (v Value) MarshalJSON() ([]byte, error) {
+	val := v.Val
+	if d, ok := val.(time.Time); ok && v.Type == TypeDate {
+		val = d.Format(DateLayout)
+	}
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue{Type: v.Type, Val: raw})
+}
+
+// UnmarshalJSON decodes Val straight into the Go type declared by Type.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var jv jsonValue
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return err
+	}
+	v.Type = jv.Type
+	v.Val = nil
+
+	if len(jv.Val) == 0 || bytes.Equal(jv.Val, []byte("null")) {
+		return nil
+	}
+
+	switch jv.Type {
+	case TypeInt:
+		var i int
+		if err := json.Unmarshal(jv.Val, &i); err != nil {
+			return fmt.Errorf("invalid INT value %s: %w", jv.Val, err)
 		}
-		if s1 > s2 {
-			return 1, nil
+		v.Val = i
+	case TypeText:
+		var s string
+		if err := json.Unmarshal(jv.Val, &s); err != nil {
+			return fmt.Errorf("invalid TEXT value %s: %w", jv.Val, err)
 		}
-		return 0, nil
+		v.Val = s
+	case TypeDate:
+		var s string
+		if err := json.Unmarshal(jv.Val, &s); err != nil {
+			return fmt.Errorf("invalid DATE value %s: %w", jv.Val, err)
+		}
+		d, err := ParseDate(s)
+		if err != nil {
+			return err
+		}
+		v.Val = d.Val
+	case TypeNumeric:
+		n := new(big.Int)
+		if err := json.Unmarshal(jv.Val, n); err != nil {
+			return fmt.Errorf("invalid NUMERIC value %s: %w", jv.Val, err)
+		}
+		v.Val = n
+	default:
+		return fmt.Errorf("unknown type: %s", jv.Type)
 	}
-	return 0, fmt.Errorf("unsupported comparison type: %s", v.Type)
+	return nil
 }
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestValueConstructors(t *testing.T) {
+	if v := NewInt(7); v.Type != TypeInt || v.Val != 7 || v.Check() != nil {
+		t.Errorf("NewInt: unexpected %#v", v)
+	}
+	if v := NewText("a'b"); v.Type != TypeText || v.Val != "a'b" || v.Check() != nil {
+		t.Errorf("NewText: unexpected %#v", v)
+	}
+	if v := NewNull(TypeInt); v.Type != TypeInt || !v.IsNull() {
+		t.Errorf("NewNull: unexpected %#v", v)
+	}
+}
+
+func TestNewValueNormalizes(t *testing.T) {
+	tests := []struct {
+		typ  DataType
+		in   interface{}
+		want Value
+	}{
+		{TypeInt, 42, NewInt(42)},
+		{TypeInt, int64(-3), NewInt(-3)},
+		{TypeInt, uint8(9), NewInt(9)},
+		{TypeInt, float64(12), NewInt(12)}, // JSON numbers decode as float64
+		{TypeInt, float32(-5), NewInt(-5)},
+		{TypeText, "hi", NewText("hi")},
+		{TypeText, []byte("raw"), NewText("raw")},
+		{TypeText, nil, NewNull(TypeText)},
+		{TypeDate, "2024-01-02", NewDate(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))},
+		{TypeDate, time.Date(2024, 1, 2, 23, 59, 0, 0, time.FixedZone("X", -5*3600)), NewDate(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))},
+	}
+	for _, tt := range tests {
+		got, err := NewValue(tt.typ, tt.in)
+		if err != nil {
+			t.Errorf("NewValue(%s, %#v): unexpected error %v", tt.typ, tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NewValue(%s, %#v) = %#v, want %#v", tt.typ, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewValueRejects(t *testing.T) {
+	tests := []struct {
+		typ DataType
+		in  interface{}
+	}{
+		{TypeInt, 1.5},
+		{TypeInt, math.Inf(1)},
+		{TypeInt, uint64(math.MaxUint64)},
+		{TypeInt, "12"},
+		{TypeText, 12},
+		{TypeDate, "2024-02-30"},
+		{TypeDate, "01/02/2024"},
+		{TypeDate, 20240102},
+		{DataType("BLOB"), "x"},
+	}
+	for _, tt := range tests {
+		if v, err := NewValue(tt.typ, tt.in); err == nil {
+			t.Errorf("NewValue(%s, %#v): expected error, got %#v", tt.typ, tt.in, v)
+		}
+	}
+}
+
+func TestValueHash(t *testing.T) {
+	floatInt := func(f float64) Value { return Value{Type: TypeInt, Val: f} }
+
+	same := [][2]Value{
+		{NewInt(4), floatInt(4)}, // a generic JSON decode; matches on purpose
+		{NewInt(-7), floatInt(-7)},
+		{NewText("abc"), NewText("abc")},
+		{NewNull(TypeInt), NewNull(TypeText)},
+	}
+	for _, p := range same {
+		if p[0].Hash() != p[1].Hash() {
+			t.Errorf("Expected %#v and %#v to hash alike, got %q and %q", p[0], p[1], p[0].Hash(), p[1].Hash())
+		}
+	}
+
+	distinct := [][2]Value{
+		{NewInt(4), NewText("4")},
+		{NewInt(4), floatInt(4.5)}, // not truncated to 4
+		{NewText("NULL"), NewNull(TypeText)},
+		{NewText("INT:1"), NewInt(1)},
+		{NewText("a"), NewText("A")},
+		{NewInt(0), NewNull(TypeInt)},
+	}
+	for _, p := range distinct {
+		if p[0].Hash() == p[1].Hash() {
+			t.Errorf("Expected %#v and %#v to hash differently, both got %q", p[0], p[1], p[0].Hash())
+		}
+	}
+}
+
+func TestDateValues(t *testing.T) {
+	d, err := ParseDate("2024-03-09")
+	if err != nil {
+		t.Fatalf("ParseDate: %v", err)
+	}
+	if d.Type != TypeDate || d.String() != "2024-03-09" || d.Check() != nil {
+		t.Errorf("Unexpected date %#v", d)
+	}
+
+	later, _ := ParseDate("2024-10-01")
+	if c, err := d.Compare(later); err != nil || c != -1 {
+		t.Errorf("Expected 2024-03-09 < 2024-10-01, got %d (%v)", c, err)
+	}
+	if c, err := later.Compare(d); err != nil || c != 1 {
+		t.Errorf("Expected 2024-10-01 > 2024-03-09, got %d (%v)", c, err)
+	}
+	same := NewDate(time.Date(2024, 3, 9, 15, 0, 0, 0, time.UTC))
+	if c, err := d.Compare(same); err != nil || c != 0 || d.Hash() != same.Hash() || d.Val != same.Val {
+		t.Errorf("Expected the same day to be equal, got %d (%v)", c, err)
+	}
+	if _, err := d.Compare(NewText("2024-03-09")); err == nil {
+		t.Errorf("Expected DATE vs TEXT comparison to fail")
+	}
+	if d.Hash() == NewText("2024-03-09").Hash() {
+		t.Errorf("Expected DATE and TEXT to hash differently")
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `{"Type":"DATE","Val":"2024-03-09"}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	var back Value
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if back != d {
+		t.Errorf("Round trip: got %#v, want %#v", back, d)
+	}
+	if err := json.Unmarshal([]byte(`{"Type":"DATE","Val":"March 9"}`), &back); err == nil {
+		t.Errorf("Expected a malformed DATE to fail to decode")
+	}
+}